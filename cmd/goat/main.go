@@ -11,14 +11,20 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"text/template"
 
 	"github.com/podhmo/goat/internal/analyzer"
 	"github.com/podhmo/goat/internal/codegen"
+	"github.com/podhmo/goat/internal/completiongen"
+	"github.com/podhmo/goat/internal/docgen"
 	helpgen "github.com/podhmo/goat/internal/helpgen"
 	"github.com/podhmo/goat/internal/interpreter"
 	"github.com/podhmo/goat/internal/loader"
+	"github.com/podhmo/goat/internal/mangen"
 	"github.com/podhmo/goat/internal/metadata"
+	"github.com/podhmo/goat/internal/scanjson"
+	"github.com/podhmo/goat/internal/schemagen"
 )
 
 // Options holds the configuration for the goat tool itself.
@@ -27,6 +33,9 @@ type Options struct {
 	OptionsInitializerName string
 	TargetFile             string
 	LocatorName            string
+	DotEnv                 bool   // Load the .env file in the generated main()
+	EnvPrefix              string // Prefix of the environment variables derived for the options without an `env` tag
+	PrintConfig            bool   // Add the --print-config flag to the generated main()
 }
 
 func main() {
@@ -36,7 +45,7 @@ func main() {
 
 	if len(os.Args) < 2 {
 		fmt.Fprintln(os.Stderr, "Usage: goat <subcommand> [options]")
-		fmt.Fprintln(os.Stderr, "Available subcommands: init, emit, help-message, scan")
+		fmt.Fprintln(os.Stderr, "Available subcommands: init, emit, help-message, scan, completion, man, docs")
 		os.Exit(1)
 	}
 
@@ -55,54 +64,18 @@ func main() {
 		slog.InfoContext(ctx, "Goat: init command finished successfully.") // Updated
 	case "emit":
 		ctx := context.Background()
-		emitCmd := flag.NewFlagSet("emit", flag.ExitOnError)
-		var runFuncName, optionsInitializerName, locatorName string
-		emitCmd.StringVar(&runFuncName, "run", "run", "Name of the function to be treated as the entrypoint")
-		emitCmd.StringVar(&optionsInitializerName, "initializer", "", "Name of the function that initializes the options struct")
-		emitCmd.StringVar(&locatorName, "locator", "golist", "Locator to use for package discovery (gomod or golist)")
-		emitCmd.Usage = func() {
-			fmt.Fprintf(os.Stderr, "Usage: goat emit [options] <target_gofile.go>\n\nOptions:\n")
-			emitCmd.PrintDefaults()
-		}
-		emitCmd.Parse(os.Args[2:])
-		if emitCmd.NArg() < 1 {
-			fmt.Fprintln(os.Stderr, "Error: Target Go file must be specified for emit.")
-			emitCmd.Usage()
-			os.Exit(1)
-		}
-		opts := &Options{
-			RunFuncName:            runFuncName,
-			OptionsInitializerName: optionsInitializerName,
-			TargetFile:             emitCmd.Arg(0),
-			LocatorName:            locatorName,
-		}
+		opts := &Options{}
+		emitCmd := newTargetFlagSet("emit", "goat emit [options] <target_gofile.go>", opts)
+		parseTargetFlags(emitCmd, os.Args[2:], opts)
 		if err := runGoat(ctx, opts); err != nil {
 			slog.ErrorContext(ctx, "Error running goat (emit)", "error", err)
 			os.Exit(1)
 		}
 	case "help-message":
 		ctx := context.Background()
-		helpMessageCmd := flag.NewFlagSet("help-message", flag.ExitOnError)
-		var runFuncName, optionsInitializerName, locatorName string
-		helpMessageCmd.StringVar(&runFuncName, "run", "run", "Name of the function to be treated as the entrypoint")
-		helpMessageCmd.StringVar(&optionsInitializerName, "initializer", "", "Name of the function that initializes the options struct")
-		helpMessageCmd.StringVar(&locatorName, "locator", "golist", "Locator to use for package discovery (gomod or golist)")
-		helpMessageCmd.Usage = func() {
-			fmt.Fprintf(os.Stderr, "Usage: goat help-message [options] <target_gofile.go>\n\nOptions:\n")
-			helpMessageCmd.PrintDefaults()
-		}
-		helpMessageCmd.Parse(os.Args[2:])
-		if helpMessageCmd.NArg() < 1 {
-			fmt.Fprintln(os.Stderr, "Error: Target Go file must be specified for help-message.")
-			helpMessageCmd.Usage()
-			os.Exit(1)
-		}
-		opts := &Options{
-			RunFuncName:            runFuncName,
-			OptionsInitializerName: optionsInitializerName,
-			TargetFile:             helpMessageCmd.Arg(0),
-			LocatorName:            locatorName,
-		}
+		opts := &Options{}
+		helpMessageCmd := newTargetFlagSet("help-message", "goat help-message [options] <target_gofile.go>", opts)
+		parseTargetFlags(helpMessageCmd, os.Args[2:], opts)
 		fset := token.NewFileSet()
 		cmdMetadata, _, err := scanMain(ctx, fset, opts)
 		if err != nil {
@@ -113,45 +86,127 @@ func main() {
 		fmt.Print(helpMsg)
 	case "scan":
 		ctx := context.Background()
-		scanCmd := flag.NewFlagSet("scan", flag.ExitOnError)
-		var runFuncName, optionsInitializerName, locatorName string
-		scanCmd.StringVar(&runFuncName, "run", "run", "Name of the function to be treated as the entrypoint")
-		scanCmd.StringVar(&optionsInitializerName, "initializer", "", "Name of the function that initializes the options struct")
-		scanCmd.StringVar(&locatorName, "locator", "golist", "Locator to use for package discovery (gomod or golist)")
-		scanCmd.Usage = func() {
-			fmt.Fprintf(os.Stderr, "Usage: goat scan [options] <target_gofile.go>\n\nOptions:\n")
-			scanCmd.PrintDefaults()
-		}
-		scanCmd.Parse(os.Args[2:])
-		if scanCmd.NArg() < 1 {
-			fmt.Fprintln(os.Stderr, "Error: Target Go file must be specified for scan.")
-			scanCmd.Usage()
+		opts := &Options{}
+		scanCmd := newTargetFlagSet("scan", "goat scan [options] <target_gofile.go>", opts)
+		var format string
+		scanCmd.StringVar(&format, "format", "json", "Format of the output: json (the versioned command metadata) or jsonschema (a JSON Schema of the config file of the options)")
+		parseTargetFlags(scanCmd, os.Args[2:], opts)
+		if format != "json" && format != "jsonschema" {
+			fmt.Fprintf(os.Stderr, "Error: Unsupported format %q for scan (must be json or jsonschema).\n", format)
 			os.Exit(1)
 		}
-		opts := &Options{
-			RunFuncName:            runFuncName,
-			OptionsInitializerName: optionsInitializerName,
-			TargetFile:             scanCmd.Arg(0),
-			LocatorName:            locatorName,
-		}
 		fset := token.NewFileSet()
 		cmdMetadata, _, err := scanMain(ctx, fset, opts)
 		if err != nil {
 			slog.ErrorContext(ctx, "Error scanning main for scan", "error", err)
 			os.Exit(1)
 		}
-		jsonData, err := json.MarshalIndent(cmdMetadata, "", "  ")
+		var output any
+		if format == "jsonschema" {
+			output = schemagen.GenerateJSONSchema(cmdMetadata)
+		} else {
+			wd, _ := os.Getwd() // The positions are absolute if the working directory is unknown
+			output = scanjson.FromMetadata(cmdMetadata, wd)
+		}
+		jsonData, err := json.MarshalIndent(output, "", "  ")
 		if err != nil {
 			slog.ErrorContext(ctx, "Error marshalling metadata to JSON for scan", "error", err)
 			os.Exit(1)
 		}
 		fmt.Println(string(jsonData))
+	case "completion":
+		ctx := context.Background()
+		opts := &Options{}
+		completionCmd := newTargetFlagSet("completion", "goat completion -shell bash|zsh|fish [options] <target_gofile.go>", opts)
+		var shell string
+		completionCmd.StringVar(&shell, "shell", "", "Shell of the completion script (bash, zsh or fish)")
+		parseTargetFlags(completionCmd, os.Args[2:], opts)
+		fset := token.NewFileSet()
+		cmdMetadata, _, err := scanMain(ctx, fset, opts)
+		if err != nil {
+			slog.ErrorContext(ctx, "Error scanning main for completion", "error", err)
+			os.Exit(1)
+		}
+		script, err := completiongen.Generate(cmdMetadata, shell)
+		if err != nil {
+			slog.ErrorContext(ctx, "Error generating the completion script", "error", err)
+			os.Exit(1)
+		}
+		fmt.Print(script)
+	case "man":
+		ctx := context.Background()
+		opts := &Options{}
+		manCmd := newTargetFlagSet("man", "goat man [options] <target_gofile.go>", opts)
+		parseTargetFlags(manCmd, os.Args[2:], opts)
+		fset := token.NewFileSet()
+		cmdMetadata, _, err := scanMain(ctx, fset, opts)
+		if err != nil {
+			slog.ErrorContext(ctx, "Error scanning main for man", "error", err)
+			os.Exit(1)
+		}
+		fmt.Print(mangen.GenerateMan(cmdMetadata))
+	case "docs":
+		ctx := context.Background()
+		opts := &Options{}
+		docsCmd := newTargetFlagSet("docs", "goat docs [options] <target_gofile.go>", opts)
+		var format, updateFile string
+		docsCmd.StringVar(&format, "format", "markdown", "Format of the reference docs (markdown)")
+		docsCmd.StringVar(&updateFile, "update", "", "Markdown file (e.g. README.md) whose region between <!-- goat:docs:start --> and <!-- goat:docs:end --> is updated in place, instead of printing the docs")
+		parseTargetFlags(docsCmd, os.Args[2:], opts)
+		if format != "markdown" {
+			fmt.Fprintf(os.Stderr, "Error: Unsupported format %q for docs (must be markdown).\n", format)
+			os.Exit(1)
+		}
+		fset := token.NewFileSet()
+		cmdMetadata, _, err := scanMain(ctx, fset, opts)
+		if err != nil {
+			slog.ErrorContext(ctx, "Error scanning main for docs", "error", err)
+			os.Exit(1)
+		}
+		if updateFile == "" {
+			fmt.Print(docgen.GenerateMarkdown(cmdMetadata))
+			return
+		}
+		if err := updateDocs(updateFile, docgen.GenerateMarkdownSection(cmdMetadata)); err != nil {
+			slog.ErrorContext(ctx, "Error updating the docs", "error", err, "file", updateFile)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stdout, "Goat: Updated the docs in %s.\n", updateFile)
 	default:
 		// ctx is not created for default case, as it's not used.
 		fmt.Fprintf(os.Stderr, "Error: Unknown subcommand '%s'\n", os.Args[1])
-		fmt.Fprintln(os.Stderr, "Available subcommands: init, emit, help-message, scan")
+		fmt.Fprintln(os.Stderr, "Available subcommands: init, emit, help-message, scan, completion, man, docs")
+		os.Exit(1)
+	}
+}
+
+// newTargetFlagSet returns the flag set of a subcommand reading a target Go file (e.g. emit, scan),
+// with the flags shared by these subcommands. The values of the flags are stored in opts.
+func newTargetFlagSet(name string, usage string, opts *Options) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.StringVar(&opts.RunFuncName, "run", "run", "Name of the function to be treated as the entrypoint (comma-separated names generate git-style subcommands)")
+	fs.StringVar(&opts.OptionsInitializerName, "initializer", "", "Name of the function that initializes the options struct (comma-separated names for each of the -run functions)")
+	fs.StringVar(&opts.LocatorName, "locator", "golist", "Locator to use for package discovery (gomod or golist)")
+	fs.BoolVar(&opts.DotEnv, "dotenv", false, "Load the .env file (or the file given by --env-file) in the generated main()")
+	fs.StringVar(&opts.EnvPrefix, "env-prefix", "", "Prefix of the environment variables derived for the options without an env tag (e.g. APP for APP_USER_NAME)")
+	fs.BoolVar(&opts.PrintConfig, "print-config", false, "Add the --print-config flag, printing the effective options and their sources, to the generated main()")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s\n\nOptions:\n", usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseTargetFlags parses the arguments of a subcommand created by newTargetFlagSet,
+// and sets the target Go file to opts. It exits if the target file is not given.
+func parseTargetFlags(fs *flag.FlagSet, args []string, opts *Options) {
+	fs.Parse(args)
+	if fs.NArg() < 1 {
+		fmt.Fprintf(os.Stderr, "Error: Target Go file must be specified for %s.\n", fs.Name())
+		fs.Usage()
 		os.Exit(1)
 	}
+	opts.TargetFile = fs.Arg(0)
 }
 
 func runGoat(ctx context.Context, opts *Options) error {
@@ -161,7 +216,11 @@ func runGoat(ctx context.Context, opts *Options) error {
 		return fmt.Errorf("failed to scan main: %w", err)
 	}
 	helpMsg := helpgen.GenerateHelp(cmdMetadata)
-	newMainContent, err := codegen.GenerateMain(cmdMetadata, helpMsg, false)
+	subcommandHelpMsgs := make(map[string]string, len(cmdMetadata.Subcommands))
+	for _, sub := range cmdMetadata.Subcommands {
+		subcommandHelpMsgs[sub.Name] = helpgen.GenerateSubcommandHelp(cmdMetadata, sub)
+	}
+	newMainContent, err := codegen.GenerateSubcommandsMain(cmdMetadata, helpMsg, subcommandHelpMsgs, false)
 	if err != nil {
 		return fmt.Errorf("failed to generate new main.go content: %w", err)
 	}
@@ -173,6 +232,29 @@ func runGoat(ctx context.Context, opts *Options) error {
 	return nil
 }
 
+// updateDocs replaces the region between the marker comments of the Markdown file with docs, keeping the file mode.
+func updateDocs(filename string, docs string) error {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", filename, err)
+	}
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+	updated, err := docgen.UpdateRegion(string(content), docs)
+	if err != nil {
+		return fmt.Errorf("failed to update %s: %w", filename, err)
+	}
+	if updated == string(content) {
+		return nil
+	}
+	if err := os.WriteFile(filename, []byte(updated), info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+	return nil
+}
+
 const mainGoTemplate = `package main
 
 import (
@@ -311,29 +393,124 @@ func scanMain(ctx context.Context, fset *token.FileSet, opts *Options) (*metadat
 	}
 	slog.DebugContext(ctx, "Determined module root", "moduleRootPath", moduleRootPath)
 
+	// Several run functions (e.g. "runServe,runMigrate") are treated as git-style subcommands.
+	runFuncNames := strings.Split(opts.RunFuncName, ",")
+	if len(runFuncNames) > 1 {
+		cmdMetadata := &metadata.CommandMetadata{
+			Name: targetPackageID,
+		}
+		if targetFileAst.Doc != nil {
+			cmdMetadata.Description = strings.TrimSpace(targetFileAst.Doc.Text())
+		}
+		// The initializers (if given) are a matching list (e.g. "NewServeOptions,"), and an empty name means
+		// the conventional initializer of the subcommand (New<OptionsType>), if any.
+		initializerNames := make([]string, len(runFuncNames))
+		if opts.OptionsInitializerName != "" {
+			initializerNames = strings.Split(opts.OptionsInitializerName, ",")
+			if len(initializerNames) != len(runFuncNames) {
+				return nil, targetFileAst, fmt.Errorf("-initializer %q must have one name (or an empty one) for each of the %d run functions in -run %q", opts.OptionsInitializerName, len(runFuncNames), opts.RunFuncName)
+			}
+		}
+		for i, runFuncName := range runFuncNames {
+			runFuncName = strings.TrimSpace(runFuncName)
+			subMetadata, err := analyzeCommand(ctx, fset, targetFileAst, runFuncName, strings.TrimSpace(initializerNames[i]), true, opts.EnvPrefix, targetPackageID, moduleRootPath, l)
+			if err != nil {
+				return nil, targetFileAst, err
+			}
+			subMetadata.Name = analyzer.SubcommandName(runFuncName)
+
+			// Global options are parsed before dispatching, so all subcommands must share the same type.
+			globalType := subMetadata.RunFunc.GlobalOptionsArgTypeNameStripped
+			if len(cmdMetadata.Subcommands) == 0 {
+				cmdMetadata.GlobalOptions = subMetadata.GlobalOptions
+				cmdMetadata.GlobalOptionGroups = subMetadata.GlobalOptionGroups
+			} else if firstType := cmdMetadata.Subcommands[0].RunFunc.GlobalOptionsArgTypeNameStripped; globalType != firstType {
+				return nil, targetFileAst, fmt.Errorf("run function %s has global options %q, but %s has %q: all subcommands must share the same global options", runFuncName, globalType, cmdMetadata.Subcommands[0].RunFunc.Name, firstType)
+			}
+			subMetadata.GlobalOptions = nil
+			subMetadata.GlobalOptionGroups = nil
+
+			if cmdMetadata.MainFuncPosition == nil {
+				cmdMetadata.MainFuncPosition = subMetadata.MainFuncPosition
+			}
+			subMetadata.MainFuncPosition = nil
+			subMetadata.DotEnv = opts.DotEnv // The .env file is loaded before dispatching, and --env-file is accepted by each subcommand.
+			subMetadata.PrintConfig = opts.PrintConfig
+			cmdMetadata.Subcommands = append(cmdMetadata.Subcommands, subMetadata)
+		}
+		cmdMetadata.DotEnv = opts.DotEnv
+		cmdMetadata.PrintConfig = opts.PrintConfig
+		return cmdMetadata, targetFileAst, nil
+	}
+
+	cmdMetadata, err := analyzeCommand(ctx, fset, targetFileAst, opts.RunFuncName, opts.OptionsInitializerName, false, opts.EnvPrefix, targetPackageID, moduleRootPath, l)
+	if err != nil {
+		return nil, targetFileAst, err
+	}
+	cmdMetadata.DotEnv = opts.DotEnv
+	cmdMetadata.PrintConfig = opts.PrintConfig
+	return cmdMetadata, targetFileAst, nil
+}
+
+// analyzeCommand extracts the metadata of the command whose entrypoint is runFuncName,
+// and interprets its options initializer.
+// The initializer is interpreted if initializerName is given, or if useConventionalInitializer is true
+// and the analyzer found a conventional one. If envPrefix is given, the environment variables of the options without an `env` tag are derived from it.
+func analyzeCommand(ctx context.Context, fset *token.FileSet, targetFileAst *ast.File, runFuncName string, initializerName string, useConventionalInitializer bool, envPrefix string, targetPackageID string, moduleRootPath string, l *loader.Loader) (*metadata.CommandMetadata, error) {
 	// The files for analysis is now just the single parsed target file.
 	// However, analyzer.Analyze expects a slice.
 	filesForAnalysis := []*ast.File{targetFileAst}
 
-	cmdMetadata, returnedOptionsStructName, err := analyzer.Analyze(ctx, fset, filesForAnalysis, opts.RunFuncName, opts.OptionsInitializerName, targetPackageID, moduleRootPath, l)
+	cmdMetadata, returnedOptionsStructName, err := analyzer.Analyze(ctx, fset, filesForAnalysis, runFuncName, initializerName, targetPackageID, moduleRootPath, l)
 	if err != nil {
-		return nil, targetFileAst, fmt.Errorf("failed to analyze AST (targetPkgID: %s, modRoot: %s): %w", targetPackageID, moduleRootPath, err)
+		return nil, fmt.Errorf("failed to analyze AST (targetPkgID: %s, modRoot: %s): %w", targetPackageID, moduleRootPath, err)
 	}
 	slog.InfoContext(ctx, "Goat: Command metadata extracted", "commandName", cmdMetadata.Name, "optionsStruct", returnedOptionsStructName)
 
+	if useConventionalInitializer && initializerName == "" {
+		initializerName = cmdMetadata.RunFunc.InitializerFunc
+	}
+
 	const goatMarkersImportPath = "github.com/podhmo/goat"
-	if opts.OptionsInitializerName != "" && returnedOptionsStructName != "" {
+	if initializerName != "" && returnedOptionsStructName != "" {
 		// targetFileAst is already available and is the correct AST to interpret the initializer from.
 		// Pass targetPackageID as currentPkgPath and the loader instance 'l'.
-		err = interpreter.InterpretInitializer(ctx, targetFileAst, returnedOptionsStructName, opts.OptionsInitializerName, cmdMetadata.Options, goatMarkersImportPath, targetPackageID, l)
+		err = interpreter.InterpretInitializer(ctx, targetFileAst, returnedOptionsStructName, initializerName, cmdMetadata.Options, goatMarkersImportPath, targetPackageID, l)
 		if err != nil {
-			return nil, targetFileAst, fmt.Errorf("failed to interpret options initializer %s: %w", opts.OptionsInitializerName, err)
+			return nil, fmt.Errorf("failed to interpret options initializer %s: %w", initializerName, err)
+		}
+		cmdMetadata.OptionGroups, err = interpreter.InterpretOptionGroups(ctx, targetFileAst, initializerName, cmdMetadata.Options, goatMarkersImportPath, l)
+		if err != nil {
+			return nil, fmt.Errorf("failed to interpret option groups of %s: %w", initializerName, err)
 		}
 		slog.InfoContext(ctx, "Goat: Options initializer interpreted successfully.")
 	} else {
-		slog.InfoContext(ctx, "Goat: Skipping options initializer interpretation", "initializerName", opts.OptionsInitializerName, "optionsStructName", returnedOptionsStructName)
+		slog.InfoContext(ctx, "Goat: Skipping options initializer interpretation", "initializerName", initializerName, "optionsStructName", returnedOptionsStructName)
 	}
-	return cmdMetadata, targetFileAst, nil
+
+	if runFunc := cmdMetadata.RunFunc; runFunc.GlobalInitializerFunc != "" {
+		err = interpreter.InterpretInitializer(ctx, targetFileAst, runFunc.GlobalOptionsArgTypeNameStripped, runFunc.GlobalInitializerFunc, cmdMetadata.GlobalOptions, goatMarkersImportPath, targetPackageID, l)
+		if err != nil {
+			return nil, fmt.Errorf("failed to interpret global options initializer %s: %w", runFunc.GlobalInitializerFunc, err)
+		}
+		cmdMetadata.GlobalOptionGroups, err = interpreter.InterpretOptionGroups(ctx, targetFileAst, runFunc.GlobalInitializerFunc, cmdMetadata.GlobalOptions, goatMarkersImportPath, l)
+		if err != nil {
+			return nil, fmt.Errorf("failed to interpret option groups of %s: %w", runFunc.GlobalInitializerFunc, err)
+		}
+		slog.InfoContext(ctx, "Goat: Global options initializer interpreted successfully.")
+	}
+
+	if err := analyzer.ApplyEnvPrefix(envPrefix, codegen.SupportsEnvVar, cmdMetadata.GlobalOptions, cmdMetadata.Options); err != nil {
+		return nil, fmt.Errorf("invalid environment variables for run function '%s': %w", runFuncName, err)
+	}
+	// Short names, aliases and constraints may come from markers, so they are checked after interpretation.
+	if err := analyzer.ValidateFlagNames(cmdMetadata.GlobalOptions, cmdMetadata.Options); err != nil {
+		return nil, fmt.Errorf("invalid flag names for run function '%s': %w", runFuncName, err)
+	}
+	if err := analyzer.ValidateValueConstraints(cmdMetadata.GlobalOptions, cmdMetadata.Options); err != nil {
+		return nil, fmt.Errorf("invalid value constraints for run function '%s': %w", runFuncName, err)
+	}
+	return cmdMetadata, nil
 }
 
 // findModuleRoot searches for a go.mod file starting from dir and going upwards.
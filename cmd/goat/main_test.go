@@ -704,3 +704,240 @@ func TestHelpMessageWithEnums(t *testing.T) {
 		t.Errorf("Help message for MyOtherEnumField does not contain correct enum values and default.\nWant substring: %q\nGot:\n%s", expectedMyOtherEnumFieldLine, gotHelp)
 	}
 }
+
+const testGoFileContentWithSubcommands = `
+// testapp is a tool with subcommands.
+package main
+
+import (
+	"context"
+
+	"testcmdmodule/internal/goat"
+)
+
+// ServeOptions holds the options for serve.
+type ServeOptions struct {
+	// Port to listen on.
+	Port int
+}
+
+// NewServeOptions is the initializer for ServeOptions.
+func NewServeOptions() *ServeOptions {
+	return &ServeOptions{
+		Port: goat.Default(8080),
+	}
+}
+
+// productionServeOptions is another initializer for ServeOptions.
+func productionServeOptions() *ServeOptions {
+	return &ServeOptions{
+		Port: goat.Default(80),
+	}
+}
+
+// MigrateOptions holds the options for migrate.
+type MigrateOptions struct {
+	// Show the plan only.
+	DryRun bool
+}
+
+// runServe starts the server.
+func runServe(ctx context.Context, opts *ServeOptions) error { return nil }
+
+// runMigrate applies migrations.
+func runMigrate(opts MigrateOptions) error { return nil }
+
+func main() {}
+`
+
+func TestScanSubcommand_MultipleRunFuncs(t *testing.T) {
+	tmpFile := setupTestAppWithGoMod(t, testGoFileContentWithSubcommands)
+
+	args := []string{"scan", "-run", "runServe,runMigrate", tmpFile}
+	out := runMainWithArgs(t, args...)
+
+	var metadataOutput metadata.CommandMetadata
+	if err := json.Unmarshal([]byte(out), &metadataOutput); err != nil {
+		t.Fatalf("Failed to unmarshal JSON output: %v\nOutput was:\n%s", err, out)
+	}
+
+	if metadataOutput.Description != "testapp is a tool with subcommands." {
+		t.Errorf("Expected metadata Description from the file doc, got %q", metadataOutput.Description)
+	}
+	if metadataOutput.RunFunc != nil {
+		t.Errorf("Expected no RunFunc on the parent command, got %+v", metadataOutput.RunFunc)
+	}
+	if len(metadataOutput.Subcommands) != 2 {
+		t.Fatalf("Expected 2 subcommands, got %d", len(metadataOutput.Subcommands))
+	}
+
+	serve := metadataOutput.Subcommands[0]
+	if serve.Name != "serve" || serve.RunFunc.Name != "runServe" || serve.Description != "runServe starts the server." {
+		t.Errorf("Unexpected serve subcommand: name=%q, runFunc=%q, description=%q", serve.Name, serve.RunFunc.Name, serve.Description)
+	}
+	if len(serve.Options) != 1 || serve.Options[0].CliName != "port" || serve.Options[0].DefaultValue != float64(8080) {
+		t.Errorf("Expected serve to have --port with default 8080 (from NewServeOptions), got %+v", serve.Options)
+	}
+
+	migrate := metadataOutput.Subcommands[1]
+	if migrate.Name != "migrate" || migrate.RunFunc.Name != "runMigrate" {
+		t.Errorf("Unexpected migrate subcommand: name=%q, runFunc=%q", migrate.Name, migrate.RunFunc.Name)
+	}
+	if len(migrate.Options) != 1 || migrate.Options[0].CliName != "dry-run" {
+		t.Errorf("Expected migrate to have --dry-run, got %+v", migrate.Options)
+	}
+}
+
+func TestScanSubcommand_MultipleRunFuncsWithInitializers(t *testing.T) {
+	tmpFile := setupTestAppWithGoMod(t, testGoFileContentWithSubcommands)
+	ctx := context.Background()
+
+	// An empty name keeps the conventional initializer.
+	opts := &Options{RunFuncName: "runServe,runMigrate", OptionsInitializerName: "productionServeOptions,", TargetFile: tmpFile}
+	cmdMetadata, _, err := scanMain(ctx, token.NewFileSet(), opts)
+	if err != nil {
+		t.Fatalf("scanMain() error = %v", err)
+	}
+	serve := cmdMetadata.Subcommands[0]
+	if serve.RunFunc.InitializerFunc != "productionServeOptions" || serve.Options[0].DefaultValue != int64(80) {
+		t.Errorf("Expected serve to use productionServeOptions with --port 80, got %q and %+v", serve.RunFunc.InitializerFunc, serve.Options[0])
+	}
+
+	t.Run("not matching the run functions", func(t *testing.T) {
+		opts := &Options{RunFuncName: "runServe,runMigrate", OptionsInitializerName: "productionServeOptions", TargetFile: tmpFile}
+		_, _, err := scanMain(ctx, token.NewFileSet(), opts)
+		if err == nil || !strings.Contains(err.Error(), "for each of the 2 run functions") {
+			t.Errorf("Expected an error for an initializer list not matching the run functions, got %v", err)
+		}
+	})
+}
+
+const testGoFileContentWithGlobalOptions = `
+package main
+
+import (
+	"context"
+
+	"testcmdmodule/internal/goat"
+)
+
+// GlobalOptions holds the options shared by all subcommands.
+type GlobalOptions struct {
+	// Log level.
+	LogLevel string
+}
+
+// NewGlobalOptions is the initializer for GlobalOptions.
+func NewGlobalOptions() *GlobalOptions {
+	return &GlobalOptions{
+		LogLevel: goat.Default("info"),
+	}
+}
+
+// OtherGlobalOptions is a different global options type.
+type OtherGlobalOptions struct {
+	Verbose bool
+}
+
+// ServeOptions holds the options for serve.
+type ServeOptions struct {
+	// Port to listen on.
+	Port int
+}
+
+// MigrateOptions holds the options for migrate.
+type MigrateOptions struct {
+	// Show the plan only.
+	DryRun bool
+}
+
+// runServe starts the server.
+func runServe(ctx context.Context, global GlobalOptions, opts *ServeOptions) error { return nil }
+
+// runMigrate applies migrations.
+func runMigrate(global *GlobalOptions, opts MigrateOptions) error { return nil }
+
+// runOther has different global options.
+func runOther(global OtherGlobalOptions, opts MigrateOptions) error { return nil }
+
+func main() {}
+`
+
+func TestScanSubcommand_GlobalOptions(t *testing.T) {
+	tmpFile := setupTestAppWithGoMod(t, testGoFileContentWithGlobalOptions)
+
+	ctx := context.Background()
+	opts := &Options{RunFuncName: "runServe,runMigrate", TargetFile: tmpFile}
+	cmdMetadata, _, err := scanMain(ctx, token.NewFileSet(), opts)
+	if err != nil {
+		t.Fatalf("scanMain() error = %v", err)
+	}
+
+	if len(cmdMetadata.GlobalOptions) != 1 || cmdMetadata.GlobalOptions[0].CliName != "log-level" || cmdMetadata.GlobalOptions[0].DefaultValue != "info" {
+		t.Errorf("Expected the parent to have --log-level with default \"info\" (from NewGlobalOptions), got %+v", cmdMetadata.GlobalOptions)
+	}
+	for _, sub := range cmdMetadata.Subcommands {
+		if sub.GlobalOptions != nil {
+			t.Errorf("Expected the global options to be moved to the parent, but %s has %+v", sub.Name, sub.GlobalOptions)
+		}
+		if sub.RunFunc.GlobalOptionsArgTypeNameStripped != "GlobalOptions" {
+			t.Errorf("Expected %s to take GlobalOptions, got %q", sub.Name, sub.RunFunc.GlobalOptionsArgTypeNameStripped)
+		}
+	}
+	if !cmdMetadata.Subcommands[1].RunFunc.GlobalOptionsArgIsPointer {
+		t.Errorf("Expected runMigrate to take a pointer to GlobalOptions")
+	}
+
+	t.Run("different global options", func(t *testing.T) {
+		opts := &Options{RunFuncName: "runServe,runOther", TargetFile: tmpFile}
+		_, _, err := scanMain(ctx, token.NewFileSet(), opts)
+		if err == nil || !strings.Contains(err.Error(), "must share the same global options") {
+			t.Errorf("Expected an error for subcommands with different global options, got %v", err)
+		}
+	})
+}
+
+const tagDefaultsWithInitializerAppContent = `
+package main
+
+import (
+	"fmt"
+
+	goat "testcmdmodule/internal/goat"
+)
+
+type Options struct {
+	Port int    ` + "`default:\"8080\" required:\"false\"`" + `
+	Host string ` + "`default:\"localhost\" required:\"false\"`" + `
+}
+
+func NewOptions() *Options {
+	return &Options{
+		Host: goat.Default("example.com"),
+	}
+}
+
+func Run(opts Options) error {
+	fmt.Printf("port=%d host=%s\n", opts.Port, opts.Host)
+	return nil
+}
+
+func main() {}
+`
+
+func TestEmitSubcommand_TagDefaultsWithInitializer(t *testing.T) {
+	tmpFile := setupTestAppWithGoMod(t, tagDefaultsWithInitializerAppContent)
+	runMainWithArgs(t, "emit", "-run", "Run", "-initializer", "NewOptions", tmpFile)
+
+	// The default of the tag is used for Port, which NewOptions does not set, and the initializer wins for Host.
+	cmd := exec.Command("go", "run", ".")
+	cmd.Dir = filepath.Dir(tmpFile)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		content, _ := os.ReadFile(tmpFile)
+		t.Fatalf("Failed to run the emitted app: %v\n%s\nContent:\n%s", err, out, content)
+	}
+	if want := "port=8080 host=example.com"; !strings.Contains(string(out), want) {
+		t.Errorf("Expected the output to contain %q, got %q", want, out)
+	}
+}
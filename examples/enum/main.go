@@ -91,12 +91,12 @@ Usage:
   enum [flags]
 
 Flags:
-  --local-enum-field             mylocalenum LocalEnumField demonstrates a locally defined enum. (default: "local-a") (env: ENUM_LOCAL_ENUM) (allowed: "local-a", "local-b")
+  --local-enum-field             mylocalenum  LocalEnumField demonstrates a locally defined enum. (default: "local-a") (env: ENUM_LOCAL_ENUM) (allowed: "local-a", "local-b")
   --imported-enum-field          mycustomenum ImportedEnumField demonstrates an enum imported from another package. (default: "option-x") (env: ENUM_IMPORTED_ENUM) (allowed: "option-x", "option-y", "option-z")
   --optional-imported-enum-field mycustomenum OptionalImportedEnumField demonstrates an optional enum (pointer type)
-                                        imported from another package. (env: ENUM_OPTIONAL_IMPORTED_ENUM) (allowed: "option-x", "option-y")
+                                            imported from another package. (env: ENUM_OPTIONAL_IMPORTED_ENUM) (allowed: "option-x", "option-y")
 
-  -h, --help                             Show this help message and exit
+  -h, --help                                 Show this help message and exit
 `)
 	}
 
@@ -146,7 +146,7 @@ imported from another package.`)
 
 	if !isValidChoice_LocalEnumField {
 		var currentValueForMsg interface{} = options.LocalEnumField // options.OptName
-		slog.ErrorContext(ctx, "Invalid value for flag", errors.New("Invalid value for flag"), "flag", "local-enum-field", "value", currentValueForMsg, "allowedChoices", strings.Join(allowedChoices_LocalEnumField, ", "))
+		slog.ErrorContext(ctx, "Invalid value for flag", "error", errors.New("Invalid value for flag"), "flag", "local-enum-field", "value", currentValueForMsg, "allowedChoices", strings.Join(allowedChoices_LocalEnumField, ", "))
 		os.Exit(1)
 	}
 
@@ -158,7 +158,7 @@ imported from another package.`)
 
 	if !isValidChoice_ImportedEnumField {
 		var currentValueForMsg interface{} = options.ImportedEnumField // options.OptName
-		slog.ErrorContext(ctx, "Invalid value for flag", errors.New("Invalid value for flag"), "flag", "imported-enum-field", "value", currentValueForMsg, "allowedChoices", strings.Join(allowedChoices_ImportedEnumField, ", "))
+		slog.ErrorContext(ctx, "Invalid value for flag", "error", errors.New("Invalid value for flag"), "flag", "imported-enum-field", "value", currentValueForMsg, "allowedChoices", strings.Join(allowedChoices_ImportedEnumField, ", "))
 		os.Exit(1)
 	}
 
@@ -166,9 +166,9 @@ imported from another package.`)
 	allowedChoices_OptionalImportedEnumField := []string{"option-x", "option-y"}
 
 	if options.OptionalImportedEnumField != nil {
-		currentValue_OptionalImportedEnumFieldStr := fmt.Sprintf("%%v", *options.OptionalImportedEnumField)
+		currentValue_OptionalImportedEnumFieldStr := fmt.Sprintf("%v", *options.OptionalImportedEnumField)
 		isValidChoice_OptionalImportedEnumField = slices.Contains(allowedChoices_OptionalImportedEnumField, currentValue_OptionalImportedEnumFieldStr)
-	} else { // Field is nil
+	} else {
 
 		isValidChoice_OptionalImportedEnumField = true
 	}
@@ -178,7 +178,7 @@ imported from another package.`)
 		if options.OptionalImportedEnumField != nil {
 			currentValueForMsg = *options.OptionalImportedEnumField
 		}
-		slog.ErrorContext(ctx, "Invalid value for flag", errors.New("Invalid value for flag"), "flag", "optional-imported-enum-field", "value", currentValueForMsg, "allowedChoices", strings.Join(allowedChoices_OptionalImportedEnumField, ", "))
+		slog.ErrorContext(ctx, "Invalid value for flag", "error", errors.New("Invalid value for flag"), "flag", "optional-imported-enum-field", "value", currentValueForMsg, "allowedChoices", strings.Join(allowedChoices_OptionalImportedEnumField, ", "))
 		os.Exit(1)
 	}
 	if err := run(*options); err != nil {
@@ -21,6 +21,7 @@ func stringPtr(s string) *string {
 }
 
 //go:generate goat emit -run run -initializer NewOptions main.go
+//go:generate goat docs -run run -initializer NewOptions -update README.md main.go
 
 // Options defines the command line options for this simple example tool.
 // This tool demonstrates the basic capabilities of goat for CLI generation.
@@ -70,7 +71,7 @@ func NewOptions() *Options {
 		// Existing fields from original
 		Name:      goat.Default("World"),
 		LogLevel:  goat.Default("info", goat.Enum([]string{"debug", "info", "warning", "error"})),
-		OutputDir: goat.Default("output"),
+		OutputDir: goat.With("output", goat.Dir(goat.CreateIfMissing(0o755))),
 		Mode:      goat.Default("standard", goat.Enum([]string{"standard", "turbo", "eco"})),
 		// Age is optional (pointer) and has no default here. It remains *int.
 		// Features is []string, handled by flag package. Env var should work.
@@ -156,7 +157,7 @@ Flags:
   --features                        strings  Features to enable, provided as a comma-separated list.
                                            Example: --features feat1,feat2 (required) (env: SIMPLE_FEATURES)
   --output-dir                      string   OutputDir for any generated files or reports.
-                                           Defaults to "output" if not specified by the user. (default: "output")
+                                           Defaults to "output" if not specified by the user. (default: "output") (directory, created if missing)
   --mode                            string   Mode of operation for the tool, affecting its behavior. (default: "standard") (env: SIMPLE_MODE) (allowed: "standard", "turbo", "eco")
   --super-verbose                   bool     Enable extra verbose output. (env: SIMPLE_SUPER_VERBOSE)
   --optional-toggle                 bool     An optional boolean flag with no default, should be nil if not set.
@@ -164,7 +165,7 @@ Flags:
   --pattern                         string   A glob pattern for input files. (env:"FULLSET_PATTERN") (default: "*.go") (env: FULLSET_PATTERN)
   --no-enable-feature-x             bool     Enable Feature X by default. Use --no-enable-feature-x to disable. (env:"FULLSET_FEATURE_X") (env: FULLSET_FEATURE_X)
   --host-ip                         ip       The host IP address for the service. (env:"FULLSET_HOST_IP") (required) (env: FULLSET_HOST_IP)
-  --existing-field-to-make-optional string   Example of an existing field made optional. (env:"FULLSET_OPTIONAL_EXISTING") (env: FULLSET_OPTIONAL_EXISTING)
+  --existing-field-to-make-optional string   Example of an existing field made optional. (env:"FULLSET_OPTIONAL_EXISTING") (default: "was set by default") (env: FULLSET_OPTIONAL_EXISTING)
 
   -h, --help                                Show this help message and exit
 `)
@@ -261,6 +262,19 @@ Flags:
 	}
 	flag.StringVar(&options.LogLevel, "log-level", options.LogLevel, `LogLevel for the application output.
 It can be one of: debug, info, warning, error.` /* Original Default: info, Env: SIMPLE_LOG_LEVEL */)
+	var isFeaturesSetByFlag bool
+	flag.Func("features", `Features to enable, provided as a comma-separated list.
+Example: --features feat1,feat2`, /* Env: SIMPLE_FEATURES */ func(s string) error {
+		if !isFeaturesSetByFlag {
+			isFeaturesSetByFlag = true
+			options.Features = nil // Replaces the default value (or the value from the environment variable).
+		}
+		for _, item := range strings.Split(s, ",") {
+			v := item
+			options.Features = append(options.Features, v)
+		}
+		return nil
+	})
 	flag.StringVar(&options.OutputDir, "output-dir", options.OutputDir, `OutputDir for any generated files or reports.
 Defaults to "output" if not specified by the user.` /* Original Default: output, Env:  */)
 	flag.StringVar(&options.Mode, "mode", options.Mode, "Mode of operation for the tool, affecting its behavior." /* Original Default: standard, Env: SIMPLE_MODE */)
@@ -288,9 +302,9 @@ Defaults to "output" if not specified by the user.` /* Original Default: output,
 		defaultExistingFieldToMakeOptionalValForFlag = *options.ExistingFieldToMakeOptional
 	}
 	if isExistingFieldToMakeOptionalNilInitially {
-		flag.StringVar(&tempExistingFieldToMakeOptionalVal, "existing-field-to-make-optional", "", "Example of an existing field made optional. (env:\"FULLSET_OPTIONAL_EXISTING\")" /* Env: FULLSET_OPTIONAL_EXISTING */)
+		flag.StringVar(&tempExistingFieldToMakeOptionalVal, "existing-field-to-make-optional", "", "Example of an existing field made optional. (env:\"FULLSET_OPTIONAL_EXISTING\")" /* Original Default: was set by default, Env: FULLSET_OPTIONAL_EXISTING */)
 	} else {
-		flag.StringVar(options.ExistingFieldToMakeOptional, "existing-field-to-make-optional", defaultExistingFieldToMakeOptionalValForFlag, "Example of an existing field made optional. (env:\"FULLSET_OPTIONAL_EXISTING\")" /* Env: FULLSET_OPTIONAL_EXISTING */)
+		flag.StringVar(options.ExistingFieldToMakeOptional, "existing-field-to-make-optional", defaultExistingFieldToMakeOptionalValForFlag, "Example of an existing field made optional. (env:\"FULLSET_OPTIONAL_EXISTING\")" /* Original Default: was set by default, Env: FULLSET_OPTIONAL_EXISTING */)
 	}
 
 	// 4. Parse.
@@ -320,7 +334,7 @@ Defaults to "output" if not specified by the user.` /* Original Default: output,
 		envNameWasSet = true
 	}
 	if options.Name == initialDefaultName && !isFlagExplicitlySet["name"] && !envNameWasSet {
-		slog.ErrorContext(ctx, "Missing required flag or environment variable not set", errors.New("Missing required flag or environment variable not set"), "flag", "name", "envVar", "SIMPLE_NAME", "option", "Name")
+		slog.ErrorContext(ctx, "Missing required flag or environment variable not set", "error", errors.New("Missing required flag or environment variable not set"), "flag", "name", "envVar", "SIMPLE_NAME", "option", "Name")
 		os.Exit(1)
 	}
 
@@ -330,7 +344,7 @@ Defaults to "output" if not specified by the user.` /* Original Default: output,
 		envLogLevelWasSet = true
 	}
 	if options.LogLevel == initialDefaultLogLevel && !isFlagExplicitlySet["log-level"] && !envLogLevelWasSet {
-		slog.ErrorContext(ctx, "Missing required flag or environment variable not set", errors.New("Missing required flag or environment variable not set"), "flag", "log-level", "envVar", "SIMPLE_LOG_LEVEL", "option", "LogLevel")
+		slog.ErrorContext(ctx, "Missing required flag or environment variable not set", "error", errors.New("Missing required flag or environment variable not set"), "flag", "log-level", "envVar", "SIMPLE_LOG_LEVEL", "option", "LogLevel")
 		os.Exit(1)
 	}
 
@@ -342,14 +356,14 @@ Defaults to "output" if not specified by the user.` /* Original Default: output,
 
 	if !isValidChoice_LogLevel {
 		var currentValueForMsg interface{} = options.LogLevel // options.OptName
-		slog.ErrorContext(ctx, "Invalid value for flag", errors.New("Invalid value for flag"), "flag", "log-level", "value", currentValueForMsg, "allowedChoices", strings.Join(allowedChoices_LogLevel, ", "))
+		slog.ErrorContext(ctx, "Invalid value for flag", "error", errors.New("Invalid value for flag"), "flag", "log-level", "value", currentValueForMsg, "allowedChoices", strings.Join(allowedChoices_LogLevel, ", "))
 		os.Exit(1)
 	}
 
 	initialDefaultOutputDir := "output"
 	envOutputDirWasSet := false
 	if options.OutputDir == initialDefaultOutputDir && !isFlagExplicitlySet["output-dir"] && !envOutputDirWasSet {
-		slog.ErrorContext(ctx, "Missing required flag or environment variable not set", errors.New("Missing required flag or environment variable not set"), "flag", "output-dir", "option", "OutputDir")
+		slog.ErrorContext(ctx, "Missing required flag or environment variable not set", "error", errors.New("Missing required flag or environment variable not set"), "flag", "output-dir", "option", "OutputDir")
 		os.Exit(1)
 	}
 
@@ -359,7 +373,7 @@ Defaults to "output" if not specified by the user.` /* Original Default: output,
 		envModeWasSet = true
 	}
 	if options.Mode == initialDefaultMode && !isFlagExplicitlySet["mode"] && !envModeWasSet {
-		slog.ErrorContext(ctx, "Missing required flag or environment variable not set", errors.New("Missing required flag or environment variable not set"), "flag", "mode", "envVar", "SIMPLE_MODE", "option", "Mode")
+		slog.ErrorContext(ctx, "Missing required flag or environment variable not set", "error", errors.New("Missing required flag or environment variable not set"), "flag", "mode", "envVar", "SIMPLE_MODE", "option", "Mode")
 		os.Exit(1)
 	}
 
@@ -371,7 +385,7 @@ Defaults to "output" if not specified by the user.` /* Original Default: output,
 
 	if !isValidChoice_Mode {
 		var currentValueForMsg interface{} = options.Mode // options.OptName
-		slog.ErrorContext(ctx, "Invalid value for flag", errors.New("Invalid value for flag"), "flag", "mode", "value", currentValueForMsg, "allowedChoices", strings.Join(allowedChoices_Mode, ", "))
+		slog.ErrorContext(ctx, "Invalid value for flag", "error", errors.New("Invalid value for flag"), "flag", "mode", "value", currentValueForMsg, "allowedChoices", strings.Join(allowedChoices_Mode, ", "))
 		os.Exit(1)
 	}
 
@@ -381,7 +395,7 @@ Defaults to "output" if not specified by the user.` /* Original Default: output,
 		envConfigFileWasSet = true
 	}
 	if options.ConfigFile == initialDefaultConfigFile && !isFlagExplicitlySet["config-file"] && !envConfigFileWasSet {
-		slog.ErrorContext(ctx, "Missing required flag or environment variable not set", errors.New("Missing required flag or environment variable not set"), "flag", "config-file", "envVar", "FULLSET_CONFIG_FILE", "option", "ConfigFile")
+		slog.ErrorContext(ctx, "Missing required flag or environment variable not set", "error", errors.New("Missing required flag or environment variable not set"), "flag", "config-file", "envVar", "FULLSET_CONFIG_FILE", "option", "ConfigFile")
 		os.Exit(1)
 	}
 
@@ -391,9 +405,16 @@ Defaults to "output" if not specified by the user.` /* Original Default: output,
 		envPatternWasSet = true
 	}
 	if options.Pattern == initialDefaultPattern && !isFlagExplicitlySet["pattern"] && !envPatternWasSet {
-		slog.ErrorContext(ctx, "Missing required flag or environment variable not set", errors.New("Missing required flag or environment variable not set"), "flag", "pattern", "envVar", "FULLSET_PATTERN", "option", "Pattern")
+		slog.ErrorContext(ctx, "Missing required flag or environment variable not set", "error", errors.New("Missing required flag or environment variable not set"), "flag", "pattern", "envVar", "FULLSET_PATTERN", "option", "Pattern")
 		os.Exit(1)
 	}
+
+	if options.OutputDir != "" {
+		if err := os.MkdirAll(options.OutputDir, 0o755); err != nil {
+			slog.ErrorContext(ctx, "Could not create directory", "error", err, "flag", "output-dir", "path", options.OutputDir)
+			os.Exit(1)
+		}
+	}
 	if err := run(ctx, options); err != nil {
 
 		slog.ErrorContext(ctx, "Runtime error", "error", err)
@@ -68,7 +68,7 @@ Flags:
 	initialDefaultConfigFile := ""
 	envConfigFileWasSet := false
 	if options.ConfigFile == initialDefaultConfigFile && !isFlagExplicitlySet["config-file"] && !envConfigFileWasSet {
-		slog.ErrorContext(ctx, "Missing required flag or environment variable not set", errors.New("Missing required flag or environment variable not set"), "flag", "config-file", "option", "ConfigFile")
+		slog.ErrorContext(ctx, "Missing required flag or environment variable not set", "error", errors.New("Missing required flag or environment variable not set"), "flag", "config-file", "option", "ConfigFile")
 		os.Exit(1)
 	}
 	if err := run(*options); err != nil {
@@ -0,0 +1,101 @@
+// positional is an example of a command that receives positional arguments.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Options defines the options of the copy command.
+type Options struct {
+	// Source file.
+	Src string `arg:"0"`
+	// Destination directory (default: the current directory).
+	Dst *string `arg:"1"`
+	// Files to exclude.
+	Exclude []string `args:"*"`
+
+	// Show what would be copied.
+	DryRun bool `short:"n"`
+}
+
+// run copies Src to Dst.
+func run(opts Options) error {
+	dst := "."
+	if opts.Dst != nil {
+		dst = *opts.Dst
+	}
+	fmt.Printf("copy %s to %s (exclude: %s, dry-run=%t)\n", opts.Src, dst, strings.Join(opts.Exclude, ","), opts.DryRun)
+	return nil
+}
+
+// This main function was auto-generated by goat.
+func main() {
+	ctx := context.Background()
+	isFlagExplicitlySet := make(map[string]bool)
+
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, `positional - run copies Src to Dst.
+
+Usage:
+  positional [flags] <src> [dst] [exclude...]
+
+Arguments:
+  src      Source file. (required)
+  dst      Destination directory (default: the current directory). (optional)
+  exclude  Files to exclude. (zero or more)
+
+Flags:
+  -n, --dry-run bool     Show what would be copied.
+
+  -h, --help            Show this help message and exit
+`)
+	}
+
+	// 1. Create Options with default values (no initializer function provided).
+	options := new(Options) // options is now a valid pointer to a zeroed struct
+
+	// The following block populates the fields of the options struct.
+	// This logic is only executed if no InitializerFunc is provided.
+
+	// 2. Override with environment variable values.
+	// This section assumes 'options' is already initialized.
+
+	// 3. Set flags.
+	flag.BoolVar(&options.DryRun, "dry-run", options.DryRun, "Show what would be copied.")
+	flag.Var(flag.Lookup("dry-run").Value, "n", "Alias for --dry-run")
+
+	// 4. Parse.
+	flag.Parse()
+	flag.Visit(func(f *flag.Flag) { isFlagExplicitlySet[f.Name] = true })
+	isFlagExplicitlySet["dry-run"] = isFlagExplicitlySet["dry-run"] || isFlagExplicitlySet["n"]
+
+	// Assign positional arguments.
+	positionalArgs := flag.Args()
+	if len(positionalArgs) < 1 {
+		slog.ErrorContext(ctx, "Missing required positional arguments", "error", errors.New("Missing required positional arguments"), "min", 1, "got", len(positionalArgs))
+		os.Exit(1)
+	}
+	options.Src = positionalArgs[0]
+	if len(positionalArgs) > 1 {
+		v := positionalArgs[1]
+		options.Dst = &v
+	}
+	if len(positionalArgs) > 2 {
+		options.Exclude = positionalArgs[2:]
+	}
+
+	// 6. Assign values for initially nil pointers if flags were explicitly set
+
+	// 5. Perform required checks (excluding booleans).
+	if err := run(*options); err != nil {
+
+		slog.ErrorContext(ctx, "Runtime error", "error", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,263 @@
+// subcommands is an example of a CLI with git-style subcommands.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/podhmo/goat"
+)
+
+// GlobalOptions defines the options shared by all subcommands.
+type GlobalOptions struct {
+	// Enable verbose output.
+	Verbose bool `env:"SUBCOMMANDS_VERBOSE" short:"v"`
+}
+
+// ServeOptions defines the options for the serve subcommand.
+type ServeOptions struct {
+	// Port to listen on.
+	Port int `env:"SUBCOMMANDS_PORT" required:"false"`
+	// Host to bind.
+	Host *string
+}
+
+// NewServeOptions returns the default options for the serve subcommand.
+func NewServeOptions() *ServeOptions {
+	return &ServeOptions{
+		Port: goat.With(8080, goat.Short("p"), goat.Range(1, 65535)),
+	}
+}
+
+// MigrateOptions defines the options for the migrate subcommand.
+type MigrateOptions struct {
+	// Direction of the migration.
+	Direction string `required:"false"`
+	// Show the plan without applying it.
+	DryRun bool
+}
+
+// NewMigrateOptions returns the default options for the migrate subcommand.
+func NewMigrateOptions() *MigrateOptions {
+	return &MigrateOptions{
+		Direction: goat.Default("up", goat.Enum([]string{"up", "down"})),
+	}
+}
+
+// runServe starts the server.
+func runServe(ctx context.Context, global GlobalOptions, opts *ServeOptions) error {
+	if global.Verbose {
+		fmt.Printf("serve options: %+v\n", *opts)
+	}
+	host := "localhost"
+	if opts.Host != nil {
+		host = *opts.Host
+	}
+	fmt.Printf("serving on %s:%d\n", host, opts.Port)
+	return nil
+}
+
+// runMigrate applies database migrations.
+func runMigrate(global GlobalOptions, opts *MigrateOptions) error {
+	if global.Verbose {
+		fmt.Printf("migrate options: %+v\n", *opts)
+	}
+	fmt.Printf("migrating %s (dry-run=%t)\n", opts.Direction, opts.DryRun)
+	return nil
+}
+
+// This main function was auto-generated by goat.
+func main() {
+	ctx := context.Background()
+
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, `subcommands - subcommands is an example of a CLI with git-style subcommands.
+
+Usage:
+  subcommands [global flags] <command> [flags]
+
+Commands:
+  serve    runServe starts the server.
+  migrate  runMigrate applies database migrations.
+
+Global Flags:
+  -v, --verbose bool     Enable verbose output. (env: SUBCOMMANDS_VERBOSE)
+
+Use "subcommands <command> --help" for more information about a command.
+`)
+	}
+	isFlagExplicitlySet := make(map[string]bool)
+
+	// 1. Create GlobalOptions with default values (no initializer function provided).
+	globalOptions := new(GlobalOptions) // globalOptions is now a valid pointer to a zeroed struct
+
+	// The following block populates the fields of the options struct.
+	// This logic is only executed if no InitializerFunc is provided.
+
+	// 2. Override with environment variable values.
+	// This section assumes 'globalOptions' is already initialized.
+
+	if val, ok := os.LookupEnv("SUBCOMMANDS_VERBOSE"); ok {
+
+		if v, err := strconv.ParseBool(val); err == nil {
+			globalOptions.Verbose = v
+		} else {
+			slog.WarnContext(ctx, "Could not parse environment variable as bool for option", "envVar", "SUBCOMMANDS_VERBOSE", "option", "Verbose", "value", val, "error", err)
+		}
+	}
+
+	// 3. Set global flags.
+	flag.BoolVar(&globalOptions.Verbose, "verbose", globalOptions.Verbose, "Enable verbose output." /* Env: SUBCOMMANDS_VERBOSE */)
+	flag.Var(flag.Lookup("verbose").Value, "v", "Alias for --verbose")
+
+	// 4. Parse global flags.
+	flag.Parse()
+	flag.Visit(func(f *flag.Flag) { isFlagExplicitlySet[f.Name] = true })
+	args := flag.Args()
+	isFlagExplicitlySet["verbose"] = isFlagExplicitlySet["verbose"] || isFlagExplicitlySet["v"]
+
+	// 6. Assign values for initially nil pointers if flags were explicitly set
+
+	// 5. Perform required checks (excluding booleans).
+
+	if len(args) < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	switch args[0] {
+	case "-h", "--help", "help":
+		flag.Usage()
+		os.Exit(0)
+	case "serve":
+		isFlagExplicitlySet := make(map[string]bool)
+		fs := flag.NewFlagSet("serve", flag.ExitOnError)
+		fs.Usage = func() {
+			fmt.Fprint(os.Stderr, `subcommands serve - runServe starts the server.
+
+Usage:
+  subcommands serve [flags]
+
+Flags:
+  -p, --port  int      Port to listen on. (default: 8080) (env: SUBCOMMANDS_PORT) (range: 1..65535)
+  --host      string   Host to bind.
+
+  -h, --help          Show this help message and exit
+`)
+		}
+
+		// 1. Create Options using the initializer function.
+		options := NewServeOptions()
+
+		// 2. Override with environment variable values.
+		// This section assumes 'options' is already initialized.
+
+		if val, ok := os.LookupEnv("SUBCOMMANDS_PORT"); ok {
+
+			if v, err := strconv.Atoi(val); err == nil {
+				options.Port = v
+			} else {
+				slog.WarnContext(ctx, "Could not parse environment variable as int for option", "envVar", "SUBCOMMANDS_PORT", "option", "Port", "value", val, "error", err)
+			}
+		}
+
+		// 3. Set flags.
+		fs.IntVar(&options.Port, "port", options.Port, "Port to listen on." /* Original Default: 8080, Env: SUBCOMMANDS_PORT */)
+		fs.Var(fs.Lookup("port").Value, "p", "Alias for --port")
+		isHostNilInitially := options.Host == nil
+		var tempHostVal string
+		var defaultHostValForFlag string
+		if options.Host != nil {
+			defaultHostValForFlag = *options.Host
+		}
+		if isHostNilInitially {
+			fs.StringVar(&tempHostVal, "host", "", "Host to bind.")
+		} else {
+			fs.StringVar(options.Host, "host", defaultHostValForFlag, "Host to bind.")
+		}
+
+		// 4. Parse.
+		fs.Parse(args[1:])
+		fs.Visit(func(f *flag.Flag) { isFlagExplicitlySet[f.Name] = true })
+		isFlagExplicitlySet["port"] = isFlagExplicitlySet["port"] || isFlagExplicitlySet["p"]
+
+		// 6. Assign values for initially nil pointers if flags were explicitly set
+		if isHostNilInitially && isFlagExplicitlySet["host"] {
+			options.Host = &tempHostVal
+		}
+
+		// 5. Perform required checks (excluding booleans).
+
+		if options.Port < 1 || options.Port > 65535 {
+			slog.ErrorContext(ctx, "Value out of range for flag", "error", errors.New("Value out of range for flag"), "flag", "port", "value", options.Port, "range", "1..65535")
+			os.Exit(1)
+		}
+		if err := runServe(ctx, *globalOptions, options); err != nil {
+
+			slog.ErrorContext(ctx, "Runtime error", "error", err)
+			os.Exit(1)
+		}
+	case "migrate":
+		isFlagExplicitlySet := make(map[string]bool)
+		fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+		fs.Usage = func() {
+			fmt.Fprint(os.Stderr, `subcommands migrate - runMigrate applies database migrations.
+
+Usage:
+  subcommands migrate [flags]
+
+Flags:
+  --direction string   Direction of the migration. (default: "up") (allowed: "up", "down")
+  --dry-run   bool     Show the plan without applying it.
+
+  -h, --help          Show this help message and exit
+`)
+		}
+
+		// 1. Create Options using the initializer function.
+		options := NewMigrateOptions()
+
+		// 2. Override with environment variable values.
+		// This section assumes 'options' is already initialized.
+
+		// 3. Set flags.
+		fs.StringVar(&options.Direction, "direction", options.Direction, "Direction of the migration." /* Original Default: up, Env:  */)
+		fs.BoolVar(&options.DryRun, "dry-run", options.DryRun, "Show the plan without applying it.")
+
+		// 4. Parse.
+		fs.Parse(args[1:])
+		fs.Visit(func(f *flag.Flag) { isFlagExplicitlySet[f.Name] = true })
+
+		// 6. Assign values for initially nil pointers if flags were explicitly set
+
+		// 5. Perform required checks (excluding booleans).
+
+		isValidChoice_Direction := false
+		allowedChoices_Direction := []string{"up", "down"}
+
+		currentValue_DirectionStr := fmt.Sprintf("%v", options.Direction)
+		isValidChoice_Direction = slices.Contains(allowedChoices_Direction, currentValue_DirectionStr)
+
+		if !isValidChoice_Direction {
+			var currentValueForMsg interface{} = options.Direction // options.OptName
+			slog.ErrorContext(ctx, "Invalid value for flag", "error", errors.New("Invalid value for flag"), "flag", "direction", "value", currentValueForMsg, "allowedChoices", strings.Join(allowedChoices_Direction, ", "))
+			os.Exit(1)
+		}
+		if err := runMigrate(*globalOptions, options); err != nil {
+
+			slog.ErrorContext(ctx, "Runtime error", "error", err)
+			os.Exit(1)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command: %q\n\n", args[0])
+		flag.Usage()
+		os.Exit(2)
+	}
+}
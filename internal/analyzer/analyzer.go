@@ -53,6 +53,12 @@ func Analyze(ctx context.Context, fset *token.FileSet, files []*ast.File, runFun
 		}
 	}
 
+	// Populate GlobalOptionsArgTypeNameStripped and GlobalOptionsArgIsPointer
+	if runFuncInfo.GlobalOptionsArgType != "" {
+		runFuncInfo.GlobalOptionsArgIsPointer = strings.HasPrefix(runFuncInfo.GlobalOptionsArgType, "*")
+		runFuncInfo.GlobalOptionsArgTypeNameStripped = strings.TrimPrefix(runFuncInfo.GlobalOptionsArgType, "*")
+	}
+
 	cmdMeta.Name = targetPackageID // Use targetPackageID as the command name identifier
 	cmdMeta.Description = runFuncDoc
 	cmdMeta.RunFunc = runFuncInfo
@@ -67,57 +73,7 @@ func Analyze(ctx context.Context, fset *token.FileSet, files []*ast.File, runFun
 			initializerFuncName = "New" + runFuncInfo.OptionsArgTypeNameStripped
 			slog.DebugContext(ctx, "Goat: Looking for conventional options initializer function", "expectedName", initializerFuncName)
 		}
-		initializerFuncFoundInAst := false // Flag to track if we found any function with the name
-
-		for _, file := range files {
-			if file.Name.Name != "main" { // Check if the file belongs to package "main"
-				slog.DebugContext(ctx, "Goat: Skipping file as it does not belong to package main for initializer search", "fileName", fset.File(file.Pos()).Name(), "packageName", file.Name.Name)
-				continue // Skip files not in package "main"
-			}
-
-			if runFuncInfo.InitializerFunc != "" { // Already found and validated in a "main" package file
-				break
-			}
-
-			for _, decl := range file.Decls {
-				if fn, ok := decl.(*ast.FuncDecl); ok && fn.Name.Name == initializerFuncName {
-					initializerFuncFoundInAst = true // Found a function with the conventional name in a "main" package file
-					// Check signature: must have no parameters.
-					// A more robust future check might inspect return types: e.g. *OptionsType or (*OptionsType, error).
-					if fn.Type.Params == nil || len(fn.Type.Params.List) == 0 {
-						runFuncInfo.InitializerFunc = initializerFuncName
-						// Log with the actual package name from the AST file node, which we've confirmed is "main"
-						slog.InfoContext(ctx, "Goat: Found and using conventional initializer function", "name", initializerFuncName, "package", file.Name.Name)
-						// No need to 'break' inner loop here, outer loop will break due to InitializerFunc being set.
-					} else {
-						slog.WarnContext(ctx, "Goat: Conventional initializer function found in package main but has unexpected parameters; it will be ignored.",
-							"functionName", initializerFuncName,
-							"paramCount", len(fn.Type.Params.List),
-							"package", file.Name.Name) // Log with actual package name "main"
-						// Do not set runFuncInfo.InitializerFunc, let it remain empty.
-					}
-					break // Found the function by name, processed it (either used or warned), stop checking other decls in this file.
-				}
-			}
-		}
-
-		if runFuncInfo.InitializerFunc == "" && !initializerFuncFoundInAst {
-			if initializerFuncNameOption != "" {
-				slog.InfoContext(ctx, "Goat: User-specified initializer function not found", "specifiedName", initializerFuncNameOption)
-			} else {
-				slog.InfoContext(ctx, "Goat: No conventional initializer function found with the expected name in package main", "expectedName", initializerFuncName)
-			}
-		} else if runFuncInfo.InitializerFunc == "" && initializerFuncFoundInAst {
-			// This case means a function was found by name in a "main" package file, but it had the wrong signature (and a warning was logged).
-			// No additional general message needed here, the specific warning is sufficient.
-			// The logging for this specific case (found but wrong signature) is handled where the signature check occurs.
-			// We can add a debug log here if needed, but the existing warning for wrong signature should be prominent.
-			if initializerFuncNameOption != "" {
-				slog.DebugContext(ctx, "Goat: A function matching user-specified initializer name was found in package main but ignored due to signature.", "specifiedName", initializerFuncNameOption)
-			} else {
-				slog.DebugContext(ctx, "Goat: A function matching conventional initializer name was found in package main but ignored due to signature.", "expectedName", initializerFuncName)
-			}
-		}
+		runFuncInfo.InitializerFunc = findInitializerFunc(ctx, fset, files, initializerFuncName, initializerFuncNameOption != "")
 	}
 
 	if runFuncInfo.OptionsArgName != "" && runFuncInfo.OptionsArgType != "" {
@@ -134,6 +90,13 @@ func Analyze(ctx context.Context, fset *token.FileSet, files []*ast.File, runFun
 		if err != nil {
 			return nil, "", fmt.Errorf("analyzing options struct for run function '%s' in package '%s': %w", runFuncName, targetPackageID, err)
 		}
+		if err := ValidatePositionalArgs(options); err != nil {
+			return nil, "", fmt.Errorf("analyzing positional arguments of options struct '%s': %w", foundOptionsStructName, err)
+		}
+		runFuncInfo.OptionsHasValidate, err = HasValidateMethod(ctx, runFuncInfo.OptionsArgType, targetPackageID, moduleRootPath, loader)
+		if err != nil {
+			return nil, "", fmt.Errorf("checking Validate method of options struct '%s': %w", foundOptionsStructName, err)
+		}
 		cmdMeta.Options = options
 		optionsStructName = foundOptionsStructName // Assign to the variable that will be returned
 	} else {
@@ -144,6 +107,27 @@ func Analyze(ctx context.Context, fset *token.FileSet, files []*ast.File, runFun
 		return nil, "", fmt.Errorf("run function '%s' must have an options parameter, or it's not in the expected format", runFuncName)
 	}
 
+	if runFuncInfo.GlobalOptionsArgType != "" {
+		// The global options are initialized by the conventional initializer (New<GlobalOptionsType>), if any.
+		runFuncInfo.GlobalInitializerFunc = findInitializerFunc(ctx, fset, files, "New"+runFuncInfo.GlobalOptionsArgTypeNameStripped, false)
+
+		slog.DebugContext(ctx, "Goat: Analyzing global options", "targetPackageID", targetPackageID, "moduleRootPath", moduleRootPath)
+		globalOptions, _, err := AnalyzeOptions(ctx, fset, runFuncInfo.GlobalOptionsArgType, targetPackageID, moduleRootPath, loader)
+		if err != nil {
+			return nil, "", fmt.Errorf("analyzing global options struct for run function '%s' in package '%s': %w", runFuncName, targetPackageID, err)
+		}
+		for _, opt := range globalOptions {
+			if opt.Positional != nil {
+				return nil, "", fmt.Errorf("global options cannot receive positional arguments, but field '%s' has an arg or args tag", opt.Name)
+			}
+		}
+		runFuncInfo.GlobalOptionsHasValidate, err = HasValidateMethod(ctx, runFuncInfo.GlobalOptionsArgType, targetPackageID, moduleRootPath, loader)
+		if err != nil {
+			return nil, "", fmt.Errorf("checking Validate method of global options struct '%s': %w", runFuncInfo.GlobalOptionsArgTypeNameStripped, err)
+		}
+		cmdMeta.GlobalOptions = globalOptions
+	}
+
 	// Find the main function to get its position for future code replacement
 	emitTargetFuncName := "main" // TODO: Make this configurable if needed
 	for _, targetFileAst := range files {
@@ -163,3 +147,62 @@ func Analyze(ctx context.Context, fset *token.FileSet, files []*ast.File, runFun
 
 	return cmdMeta, optionsStructName, nil
 }
+
+// findInitializerFunc looks for the options initializer function named initializerFuncName in the files of package main.
+// It returns the name if the function is found and takes no parameters, otherwise an empty string.
+// userSpecified is only used to adjust the log messages.
+func findInitializerFunc(ctx context.Context, fset *token.FileSet, files []*ast.File, initializerFuncName string, userSpecified bool) string {
+	var initializerFunc string
+	initializerFuncFoundInAst := false // Flag to track if we found any function with the name
+
+	for _, file := range files {
+		if file.Name.Name != "main" { // Check if the file belongs to package "main"
+			slog.DebugContext(ctx, "Goat: Skipping file as it does not belong to package main for initializer search", "fileName", fset.File(file.Pos()).Name(), "packageName", file.Name.Name)
+			continue // Skip files not in package "main"
+		}
+
+		if initializerFunc != "" { // Already found and validated in a "main" package file
+			break
+		}
+
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Name.Name == initializerFuncName {
+				initializerFuncFoundInAst = true // Found a function with the conventional name in a "main" package file
+				// Check signature: must have no parameters.
+				// A more robust future check might inspect return types: e.g. *OptionsType or (*OptionsType, error).
+				if fn.Type.Params == nil || len(fn.Type.Params.List) == 0 {
+					initializerFunc = initializerFuncName
+					// Log with the actual package name from the AST file node, which we've confirmed is "main"
+					slog.InfoContext(ctx, "Goat: Found and using conventional initializer function", "name", initializerFuncName, "package", file.Name.Name)
+					// No need to 'break' inner loop here, outer loop will break due to InitializerFunc being set.
+				} else {
+					slog.WarnContext(ctx, "Goat: Conventional initializer function found in package main but has unexpected parameters; it will be ignored.",
+						"functionName", initializerFuncName,
+						"paramCount", len(fn.Type.Params.List),
+						"package", file.Name.Name) // Log with actual package name "main"
+					// Do not set initializerFunc, let it remain empty.
+				}
+				break // Found the function by name, processed it (either used or warned), stop checking other decls in this file.
+			}
+		}
+	}
+
+	if initializerFunc == "" && !initializerFuncFoundInAst {
+		if userSpecified {
+			slog.InfoContext(ctx, "Goat: User-specified initializer function not found", "specifiedName", initializerFuncName)
+		} else {
+			slog.InfoContext(ctx, "Goat: No conventional initializer function found with the expected name in package main", "expectedName", initializerFuncName)
+		}
+	} else if initializerFunc == "" && initializerFuncFoundInAst {
+		// This case means a function was found by name in a "main" package file, but it had the wrong signature (and a warning was logged).
+		// No additional general message needed here, the specific warning is sufficient.
+		// The logging for this specific case (found but wrong signature) is handled where the signature check occurs.
+		// We can add a debug log here if needed, but the existing warning for wrong signature should be prominent.
+		if userSpecified {
+			slog.DebugContext(ctx, "Goat: A function matching user-specified initializer name was found in package main but ignored due to signature.", "specifiedName", initializerFuncName)
+		} else {
+			slog.DebugContext(ctx, "Goat: A function matching conventional initializer name was found in package main but ignored due to signature.", "expectedName", initializerFuncName)
+		}
+	}
+	return initializerFunc
+}
@@ -9,10 +9,15 @@ import (
 	"go/token"
 	"go/types"
 
-	// "log/slog" // Unused
+	"log/slog"
 	"os"            // Re-add for ReadDir
 	"path/filepath" // Re-add for Join
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	// No longer need "bytes" or "go/format" for overlay population from ASTs
 	// "golang.org/x/tools/go/packages" // Removed unused import
@@ -57,6 +62,64 @@ func init() {
 	textMarshalerType = types.NewInterfaceType([]*types.Func{textMarshalerMeth}, nil).Complete()
 }
 
+// loadTargetPackage loads the package targetPackagePath, which contains the options struct.
+func loadTargetPackage(ctx context.Context, targetPackagePath string, baseDir string, loader *loader.Loader) (*loader.Package, error) {
+	// Heuristic adjustment for loadPattern based on typical test setups.
+	// If targetPackagePath is simple (no slashes, e.g., a module name) and baseDir is set,
+	// it's likely a test scenario where baseDir is the module root. In this case, "." is the correct
+	// pattern for `go list` to identify the package at the root of the module.
+	loadPattern := targetPackagePath
+	if baseDir != "" && !strings.Contains(targetPackagePath, "/") {
+		// Check if go.mod exists to strengthen the heuristic, assuming module mode.
+		goModPath := filepath.Join(baseDir, "go.mod")
+		if _, statErr := os.Stat(goModPath); statErr == nil {
+			loadPattern = "." // Load package in current directory (baseDir)
+		}
+	}
+
+	loadedPkgs, err := loader.Load(ctx, loadPattern) // Use the passed-in loader. baseDir is handled by the locator if necessary.
+	if err != nil {
+		// Updated error message to reflect that baseDir is not directly used by Load() here.
+		return nil, fmt.Errorf("error loading package '%s' (derived load pattern '%s') with loader: %w", targetPackagePath, loadPattern, err)
+	}
+	if len(loadedPkgs) == 0 {
+		// Updated error message
+		return nil, fmt.Errorf("no package found for '%s' (derived load pattern '%s') by loader", targetPackagePath, loadPattern)
+	}
+	return loadedPkgs[0], nil
+}
+
+// HasValidateMethod checks if the options struct type has a `Validate() error` method
+// (with a value or pointer receiver), which the generated main() calls before the run function.
+// Like FieldInfo.ImplementsInterface, the check is based on the AST of the package.
+func HasValidateMethod(ctx context.Context, optionsTypeName string, targetPackagePath string, baseDir string, loader *loader.Loader) (bool, error) {
+	currentPkg, err := loadTargetPackage(ctx, targetPackagePath, baseDir, loader)
+	if err != nil {
+		return false, err
+	}
+	typeName := strings.TrimPrefix(optionsTypeName, "*")
+	if i := strings.LastIndex(typeName, "."); i >= 0 {
+		typeName = typeName[i+1:]
+	}
+	methods, err := currentPkg.GetMethodsForType(typeName)
+	if err != nil {
+		return false, fmt.Errorf("failed to get methods for type '%s' in package '%s': %w", typeName, currentPkg.ImportPath, err)
+	}
+	for _, method := range methods {
+		if method.Name.Name != "Validate" {
+			continue
+		}
+		funcType := method.Type
+		if funcType.Params.NumFields() != 0 || funcType.Results.NumFields() != 1 {
+			continue
+		}
+		if ident, ok := funcType.Results.List[0].Type.(*ast.Ident); ok && ident.Name == "error" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // AnalyzeOptions finds the Options struct definition using the lazyload package.
 // It performs type analysis for interface checking and resolving embedded structs.
 //
@@ -79,31 +142,10 @@ func AnalyzeOptions( // Renamed from AnalyzeOptionsV3
 	baseDir string,
 	loader *loader.Loader, // Changed from llConfig *loader.Config
 ) ([]*metadata.OptionMetadata, string, error) {
-	// Heuristic adjustment for loadPattern based on typical test setups.
-	// If targetPackagePath is simple (no slashes, e.g., a module name) and baseDir is set,
-	// it's likely a test scenario where baseDir is the module root. In this case, "." is the correct
-	// pattern for `go list` to identify the package at the root of the module.
-	loadPattern := targetPackagePath
-	if baseDir != "" && !strings.Contains(targetPackagePath, "/") {
-		// Check if go.mod exists to strengthen the heuristic, assuming module mode.
-		goModPath := filepath.Join(baseDir, "go.mod")
-		if _, statErr := os.Stat(goModPath); statErr == nil {
-			loadPattern = "." // Load package in current directory (baseDir)
-		}
-	}
-
-	// loader is now passed in directly
-
-	loadedPkgs, err := loader.Load(ctx, loadPattern) // Use the passed-in loader. baseDir is handled by the locator if necessary.
+	currentPkg, err := loadTargetPackage(ctx, targetPackagePath, baseDir, loader)
 	if err != nil {
-		// Updated error message to reflect that baseDir is not directly used by Load() here.
-		return nil, "", fmt.Errorf("error loading package '%s' (derived load pattern '%s') with loader: %w", targetPackagePath, loadPattern, err)
+		return nil, "", err
 	}
-	if len(loadedPkgs) == 0 {
-		// Updated error message
-		return nil, "", fmt.Errorf("no package found for '%s' (derived load pattern '%s') by loader", targetPackagePath, loadPattern)
-	}
-	currentPkg := loadedPkgs[0]
 
 	simpleOptionsTypeName := optionsTypeName
 	if strings.Contains(optionsTypeName, ".") {
@@ -144,62 +186,16 @@ func AnalyzeOptions( // Renamed from AnalyzeOptionsV3
 
 	var extractedOptions []*metadata.OptionMetadata
 	for _, fieldInfo := range optionsStructInfo.Fields {
+		if fieldInfo.GetTag("goat") == "-" {
+			continue // Skipped, like `json:"-"`
+		}
 		if fieldInfo.Embedded {
 			embeddedTypeName := astutils.ExprToTypeName(fieldInfo.TypeExpr)
-			var embeddedOptions []*metadata.OptionMetadata
-			var embErr error
-
-			var externalPkgSelector string
-			var typeNameInExternalPkg string
-			isExternal := false
-
-			typeExpr := fieldInfo.TypeExpr
-			if starExpr, ok := typeExpr.(*ast.StarExpr); ok {
-				typeExpr = starExpr.X
-			}
-			if selExpr, ok := typeExpr.(*ast.SelectorExpr); ok {
-				if ident, ok := selExpr.X.(*ast.Ident); ok {
-					externalPkgSelector = ident.Name
-					typeNameInExternalPkg = selExpr.Sel.Name
-					isExternal = true
-				}
-			}
-
-			if isExternal {
-				resolvedExternalImportPath := ""
-				for _, importSpec := range fileContainingOptionsStruct.Imports {
-					path := strings.Trim(importSpec.Path.Value, "\"")
-					if importSpec.Name != nil {
-						if importSpec.Name.Name == externalPkgSelector {
-							resolvedExternalImportPath = path
-							break
-						}
-					} else {
-						tempResolvedPkg, errTmpResolve := currentPkg.ResolveImport(ctx, path)
-						if errTmpResolve == nil && tempResolvedPkg != nil && tempResolvedPkg.Name == externalPkgSelector {
-							resolvedExternalImportPath = path
-							break
-						}
-					}
-				}
-				if resolvedExternalImportPath == "" {
-					return nil, actualStructName, fmt.Errorf("unable to resolve import path for selector '%s' in embedded type '%s'", externalPkgSelector, embeddedTypeName)
-				}
-				resolvedExternalPkg, errResolve := currentPkg.ResolveImport(ctx, resolvedExternalImportPath)
-				if errResolve != nil {
-					return nil, actualStructName, fmt.Errorf("could not resolve imported package for path '%s': %w", resolvedExternalImportPath, errResolve)
-				}
-				if resolvedExternalPkg == nil {
-					return nil, actualStructName, fmt.Errorf("resolved imported package is nil for path '%s'", resolvedExternalImportPath)
-				}
-				// Pass loader directly
-				embeddedOptions, _, embErr = AnalyzeOptions(ctx, fset, typeNameInExternalPkg, resolvedExternalPkg.ImportPath, resolvedExternalPkg.Dir, loader)
-			} else { // Embedded struct from the same package
-				cleanEmbeddedTypeName := strings.TrimPrefix(embeddedTypeName, "*")
-				// Pass loader directly
-				embeddedOptions, _, embErr = AnalyzeOptions(ctx, fset, cleanEmbeddedTypeName, targetPackagePath, baseDir, loader)
+			embeddedPkgPath, embeddedDir, embeddedTypeNameInPkg, err := resolveTypeLocation(ctx, currentPkg, fileContainingOptionsStruct, fieldInfo.TypeExpr, targetPackagePath, baseDir)
+			if err != nil {
+				return nil, actualStructName, fmt.Errorf("embedded type '%s': %w", embeddedTypeName, err)
 			}
-
+			embeddedOptions, _, embErr := AnalyzeOptions(ctx, fset, embeddedTypeNameInPkg, embeddedPkgPath, embeddedDir, loader)
 			if embErr != nil {
 				return nil, actualStructName, fmt.Errorf("error analyzing embedded struct '%s': %w", embeddedTypeName, embErr)
 			}
@@ -212,6 +208,15 @@ func AnalyzeOptions( // Renamed from AnalyzeOptionsV3
 			continue
 		}
 
+		nestedOptions, err := analyzeNestedStruct(ctx, fset, fieldInfo, optionsStructInfo, currentPkg, fileContainingOptionsStruct, targetPackagePath, baseDir, loader)
+		if err != nil {
+			return nil, actualStructName, err
+		}
+		if nestedOptions != nil {
+			extractedOptions = append(extractedOptions, nestedOptions...)
+			continue
+		}
+
 		opt := &metadata.OptionMetadata{
 			Name:       fieldName,
 			CliName:    stringutils.ToKebabCase(fieldName),
@@ -219,6 +224,10 @@ func AnalyzeOptions( // Renamed from AnalyzeOptionsV3
 			IsPointer:  astutils.IsPointerType(fieldInfo.TypeExpr),
 			IsRequired: !astutils.IsPointerType(fieldInfo.TypeExpr),
 		}
+		if fieldInfo.Pos.IsValid() {
+			pos := loader.Fset().Position(fieldInfo.Pos)
+			opt.FieldPosition = &pos
+		}
 		opt.UnderlyingKind = "" // Initialize
 
 		var typeExprForKindCheck ast.Expr = fieldInfo.TypeExpr
@@ -261,8 +270,10 @@ func AnalyzeOptions( // Renamed from AnalyzeOptionsV3
 			} else {
 				resolveErr = fmt.Errorf("definingPkg is nil for selector %s for field %s", pkgSelectorIdent.Name, fieldInfo.Name)
 			}
+		case *ast.MapType: // A map is its own underlying type (e.g. "map[string]string")
+			opt.UnderlyingKind = astutils.ExprToTypeName(te)
 		default:
-			// Not a type name we can easily look up (e.g., could be built-in, or complex like []string, map, func)
+			// Not a type name we can easily look up (e.g., could be built-in, or complex like []string, func)
 			// opt.UnderlyingKind remains ""
 		}
 
@@ -283,24 +294,39 @@ func AnalyzeOptions( // Renamed from AnalyzeOptionsV3
 					// Not a basic type we're explicitly handling for UnderlyingKind.
 					// opt.UnderlyingKind remains ""
 				}
+			} else if mapType, ok := resolvedTypeSpec.Type.(*ast.MapType); ok {
+				opt.UnderlyingKind = astutils.ExprToTypeName(mapType) // e.g. "map[string]string" for `type Labels map[string]string`
 			}
 		}
 		// End of UnderlyingKind determination
 
-		isUnmarshaler, errUnmarshaler := fieldInfo.ImplementsInterface(ctx, "encoding", "TextUnmarshaler")
-		if errUnmarshaler != nil {
-			fmt.Println(fmt.Sprintf("analyzer: warning: error checking TextUnmarshaler for field %s type %s: %v", fieldInfo.Name, opt.TypeName, errUnmarshaler))
-			opt.IsTextUnmarshaler = false
-		} else {
-			opt.IsTextUnmarshaler = isUnmarshaler
-		}
+		// For a slice (e.g. []Level), the elements are checked instead, so that each flag value can be parsed.
+		if arrayType, ok := fieldInfo.TypeExpr.(*ast.ArrayType); ok && arrayType.Len == nil {
+			if ident, isIdent := arrayType.Elt.(*ast.Ident); !isIdent || types.Universe.Lookup(ident.Name) == nil {
+				elemInfo := fieldInfo
+				elemInfo.TypeExpr = arrayType.Elt
+				isElemUnmarshaler, errElem := elemInfo.ImplementsInterface(ctx, "encoding", "TextUnmarshaler")
+				if errElem != nil {
+					slog.WarnContext(ctx, "analyzer: error checking TextUnmarshaler for the elements of field", "field", fieldInfo.Name, "type", opt.TypeName, "error", errElem)
+				}
+				opt.ElemIsTextUnmarshaler = isElemUnmarshaler
+			}
+		} else if _, isMap := fieldInfo.TypeExpr.(*ast.MapType); !isMap {
+			isUnmarshaler, errUnmarshaler := fieldInfo.ImplementsInterface(ctx, "encoding", "TextUnmarshaler")
+			if errUnmarshaler != nil {
+				fmt.Println(fmt.Sprintf("analyzer: warning: error checking TextUnmarshaler for field %s type %s: %v", fieldInfo.Name, opt.TypeName, errUnmarshaler))
+				opt.IsTextUnmarshaler = false
+			} else {
+				opt.IsTextUnmarshaler = isUnmarshaler
+			}
 
-		isMarshaler, errMarshaler := fieldInfo.ImplementsInterface(ctx, "encoding", "TextMarshaler")
-		if errMarshaler != nil {
-			fmt.Println(fmt.Sprintf("analyzer: warning: error checking TextMarshaler for field %s type %s: %v", fieldInfo.Name, opt.TypeName, errMarshaler))
-			opt.IsTextMarshaler = false
-		} else {
-			opt.IsTextMarshaler = isMarshaler
+			isMarshaler, errMarshaler := fieldInfo.ImplementsInterface(ctx, "encoding", "TextMarshaler")
+			if errMarshaler != nil {
+				fmt.Println(fmt.Sprintf("analyzer: warning: error checking TextMarshaler for field %s type %s: %v", fieldInfo.Name, opt.TypeName, errMarshaler))
+				opt.IsTextMarshaler = false
+			} else {
+				opt.IsTextMarshaler = isMarshaler
+			}
 		}
 
 		var fieldASTNode *ast.Field
@@ -346,14 +372,544 @@ func AnalyzeOptions( // Renamed from AnalyzeOptionsV3
 			opt.HelpText = strings.TrimSpace(opt.HelpText)
 		}
 
-		if tagVal := fieldInfo.GetTag("env"); tagVal != "" {
+		if tagPos := fieldInfo.TagKeyPos("env"); tagPos.IsValid() {
+			pos := loader.Fset().Position(tagPos)
+			opt.EnvTagPosition = &pos
+		}
+		if tagVal := fieldInfo.GetTag("env"); tagVal == "-" {
+			opt.NoEnvVar = true // No environment variable is derived (see ApplyEnvPrefix)
+		} else if tagVal != "" {
 			opt.EnvVar = tagVal
 		}
+		if tagVal := fieldInfo.GetTag("short"); tagVal != "" {
+			opt.ShortName = tagVal
+		}
+		if tagVal := fieldInfo.GetTag("alias"); tagVal != "" {
+			for _, alias := range strings.Split(tagVal, ",") {
+				if alias = strings.TrimSpace(alias); alias != "" {
+					opt.Aliases = append(opt.Aliases, alias)
+				}
+			}
+		}
+		if err := applyOptionTags(opt, fieldInfo); err != nil {
+			return nil, actualStructName, fmt.Errorf("invalid tag on field '%s': %w", fieldName, err)
+		}
+		positional, err := positionalArgFromTags(opt, fieldInfo.GetTag("arg"), fieldInfo.GetTag("args"))
+		if err != nil {
+			return nil, actualStructName, fmt.Errorf("invalid positional argument tag on field '%s': %w", fieldName, err)
+		}
+		opt.Positional = positional
 		extractedOptions = append(extractedOptions, opt)
 	}
 	return extractedOptions, actualStructName, nil
 }
 
+// resolveTypeLocation returns the import path, the directory and the name of the named type of typeExpr
+// (e.g. Options, *Options or pkg.Options), resolving the package of a qualified name from the imports of file.
+func resolveTypeLocation(ctx context.Context, currentPkg *loader.Package, file *ast.File, typeExpr ast.Expr, targetPackagePath string, baseDir string) (string, string, string, error) {
+	if starExpr, ok := typeExpr.(*ast.StarExpr); ok {
+		typeExpr = starExpr.X
+	}
+	selExpr, ok := typeExpr.(*ast.SelectorExpr)
+	if !ok {
+		return targetPackagePath, baseDir, strings.TrimPrefix(astutils.ExprToTypeName(typeExpr), "*"), nil
+	}
+	ident, ok := selExpr.X.(*ast.Ident)
+	if !ok {
+		return "", "", "", fmt.Errorf("unsupported selector expression %s", astutils.ExprToTypeName(typeExpr))
+	}
+	pkgSelector := ident.Name
+
+	resolvedImportPath := ""
+	for _, importSpec := range file.Imports {
+		path := strings.Trim(importSpec.Path.Value, "\"")
+		if importSpec.Name != nil {
+			if importSpec.Name.Name == pkgSelector {
+				resolvedImportPath = path
+				break
+			}
+		} else {
+			tempResolvedPkg, errTmpResolve := currentPkg.ResolveImport(ctx, path)
+			if errTmpResolve == nil && tempResolvedPkg != nil && tempResolvedPkg.Name == pkgSelector {
+				resolvedImportPath = path
+				break
+			}
+		}
+	}
+	if resolvedImportPath == "" {
+		return "", "", "", fmt.Errorf("unable to resolve import path for selector '%s'", pkgSelector)
+	}
+	resolvedPkg, err := currentPkg.ResolveImport(ctx, resolvedImportPath)
+	if err != nil {
+		return "", "", "", fmt.Errorf("could not resolve imported package for path '%s': %w", resolvedImportPath, err)
+	}
+	if resolvedPkg == nil {
+		return "", "", "", fmt.Errorf("resolved imported package is nil for path '%s'", resolvedImportPath)
+	}
+	return resolvedPkg.ImportPath, resolvedPkg.Dir, selExpr.Sel.Name, nil
+}
+
+// analyzeNestedStruct returns the options of a (non-embedded, non-pointer) field of a struct type, e.g. `DB DBOptions`,
+// or nil if the field is not of a struct type (or the struct type implements encoding.TextUnmarshaler, like time.Time).
+// The options are prefixed with the field: `DB.Host` for the Go field, `--db-host` for the flag (the `prefix` tag overrides "db"),
+// and DB_ for the environment variables of the fields with an `env` tag (the `envPrefix` tag overrides "DB").
+// Their Section is the doc comment of the field (or its name), used as the heading in the help message.
+func analyzeNestedStruct(ctx context.Context, fset *token.FileSet, fieldInfo loader.FieldInfo, structInfo *loader.StructInfo, currentPkg *loader.Package, file *ast.File, targetPackagePath string, baseDir string, loader *loader.Loader) ([]*metadata.OptionMetadata, error) {
+	switch fieldInfo.TypeExpr.(type) {
+	case *ast.Ident, *ast.SelectorExpr:
+	default:
+		return nil, nil // Pointers, slices, maps, etc.
+	}
+	if ident, ok := fieldInfo.TypeExpr.(*ast.Ident); ok && types.Universe.Lookup(ident.Name) != nil {
+		return nil, nil // Predeclared types
+	}
+
+	pkgPath, dir, typeName, err := resolveTypeLocation(ctx, currentPkg, file, fieldInfo.TypeExpr, targetPackagePath, baseDir)
+	if err != nil {
+		return nil, nil // Not resolvable here, treated as a plain option.
+	}
+	pkg, err := loadTargetPackage(ctx, pkgPath, dir, loader)
+	if err != nil {
+		return nil, nil
+	}
+	if _, err := pkg.GetStruct(typeName); err != nil {
+		return nil, nil // Not a struct type
+	}
+	if isUnmarshaler, err := fieldInfo.ImplementsInterface(ctx, "encoding", "TextUnmarshaler"); err == nil && isUnmarshaler {
+		return nil, nil // Parsed from a single value
+	}
+
+	nestedOptions, _, err := AnalyzeOptions(ctx, fset, typeName, pkgPath, dir, loader)
+	if err != nil {
+		return nil, fmt.Errorf("error analyzing nested struct '%s' of field '%s': %w", astutils.ExprToTypeName(fieldInfo.TypeExpr), fieldInfo.Name, err)
+	}
+
+	flagPrefix := stringutils.ToKebabCase(fieldInfo.Name)
+	if tagVal := fieldInfo.GetTag("prefix"); tagVal != "" {
+		flagPrefix = tagVal
+	}
+	envPrefix := stringutils.ToUpperSnakeCase(fieldInfo.Name)
+	if tagVal := fieldInfo.GetTag("envPrefix"); tagVal != "" {
+		envPrefix = strings.TrimSuffix(tagVal, "_")
+	}
+	section := fieldInfo.Name
+	for _, astField := range structInfo.Node.Type.(*ast.StructType).Fields.List {
+		for _, nameIdent := range astField.Names {
+			if nameIdent.Name == fieldInfo.Name && astField.Doc != nil {
+				section = strings.TrimSpace(astField.Doc.Text())
+			}
+		}
+	}
+
+	for _, opt := range nestedOptions {
+		if opt.Positional != nil {
+			return nil, fmt.Errorf("field '%s.%s' of nested struct '%s' cannot receive positional arguments", fieldInfo.Name, opt.Name, astutils.ExprToTypeName(fieldInfo.TypeExpr))
+		}
+		opt.Name = fieldInfo.Name + "." + opt.Name
+		opt.CliName = flagPrefix + "-" + opt.CliName
+		for i, alias := range opt.Aliases {
+			opt.Aliases[i] = flagPrefix + "-" + alias
+		}
+		if opt.EnvVar != "" {
+			opt.EnvVar = envPrefix + "_" + opt.EnvVar
+		}
+		if opt.ConfigKey != "" && opt.ConfigKey != "-" {
+			opt.ConfigKey = flagPrefix + "-" + opt.ConfigKey
+		}
+		opt.Section = section // For deeper nesting, the options are shown in the section of the outermost field.
+	}
+	return nestedOptions, nil
+}
+
+// applyOptionTags applies the struct tags declaring the metadata of opt without an initializer function:
+// `flag` (the flag name), `required` and `secret` ("true" or "false"), `default` and `enum` (comma-separated values),
+// `goat:"config"` (the option is the path of the config file) and `json` (the key in the config file).
+// The values of `default` and `enum` are parsed as the type of the field, so that a mistake is reported when the code is generated.
+// Markers in the initializer function (e.g. goat.Default) take precedence over the tags.
+func applyOptionTags(opt *metadata.OptionMetadata, fieldInfo loader.FieldInfo) error {
+	if tagVal := fieldInfo.GetTag("flag"); tagVal != "" {
+		opt.CliName = tagVal
+	}
+	switch tagVal := fieldInfo.GetTag("goat"); tagVal {
+	case "":
+	case "config":
+		opt.IsConfigFile = true
+	default:
+		return fmt.Errorf("`goat:%q` must be \"-\" or \"config\"", tagVal)
+	}
+	if tagVal := fieldInfo.GetTag("json"); tagVal != "" {
+		if name, _, _ := strings.Cut(tagVal, ","); name != "" {
+			opt.ConfigKey = name
+		}
+	}
+	if tagVal := fieldInfo.GetTag("required"); tagVal != "" {
+		required, err := strconv.ParseBool(tagVal)
+		if err != nil {
+			return fmt.Errorf("`required:%q` must be true or false", tagVal)
+		}
+		opt.IsRequired = required
+	}
+	if tagVal := fieldInfo.GetTag("secret"); tagVal != "" {
+		secret, err := strconv.ParseBool(tagVal)
+		if err != nil {
+			return fmt.Errorf("`secret:%q` must be true or false", tagVal)
+		}
+		opt.IsSecret = secret
+	}
+	if tagVal := fieldInfo.GetTag("enum"); tagVal != "" {
+		for _, item := range strings.Split(tagVal, ",") {
+			value, err := parseTagScalar(tagValueKind(opt, true), strings.TrimSpace(item))
+			if err != nil {
+				return fmt.Errorf("`enum:%q`: %w", tagVal, err)
+			}
+			opt.EnumValues = append(opt.EnumValues, value)
+		}
+	}
+	if tagVal, ok := fieldInfo.LookupTag("default"); ok {
+		value, err := parseTagValue(opt, tagVal)
+		if err != nil {
+			return fmt.Errorf("`default:%q`: %w", tagVal, err)
+		}
+		if len(opt.EnumValues) > 0 && !strings.HasPrefix(opt.TypeName, "[]") && !slices.Contains(opt.EnumValues, value) {
+			return fmt.Errorf("`default:%q` is not one of the enum values %v", tagVal, opt.EnumValues)
+		}
+		opt.DefaultValue = value
+		opt.DefaultFromTag = true
+	}
+	return nil
+}
+
+// tagValueKind returns the type used to parse the tag values of opt: the element type for slices (if elem is true),
+// the value type for maps, and the underlying kind for named basic types (e.g. "string" for `type Mode string`).
+func tagValueKind(opt *metadata.OptionMetadata, elem bool) string {
+	if opt.IsTextUnmarshaler {
+		return "string" // Parsed by UnmarshalText at runtime
+	}
+	if strings.TrimPrefix(opt.TypeName, "*") == "time.Duration" {
+		return "time.Duration" // Not its underlying int64
+	}
+	if strings.HasPrefix(opt.UnderlyingKind, "map[") {
+		return opt.UnderlyingKind[strings.Index(opt.UnderlyingKind, "]")+1:]
+	}
+	if opt.UnderlyingKind != "" {
+		return opt.UnderlyingKind
+	}
+	kind := strings.TrimPrefix(opt.TypeName, "*")
+	if elem && strings.HasPrefix(kind, "[]") {
+		kind = strings.TrimPrefix(kind, "[]")
+		if opt.ElemIsTextUnmarshaler {
+			return "string"
+		}
+	}
+	return kind
+}
+
+// parseTagValue parses the `default` tag value s as the type of opt.
+// Slices are comma-separated items ([]any), and maps are comma-separated key=value pairs (map[string]any).
+func parseTagValue(opt *metadata.OptionMetadata, s string) (any, error) {
+	if strings.HasPrefix(opt.UnderlyingKind, "map[") {
+		values := make(map[string]any)
+		if s == "" {
+			return values, nil
+		}
+		for _, item := range strings.Split(s, ",") {
+			key, value, ok := strings.Cut(item, "=")
+			if !ok {
+				return nil, fmt.Errorf("%q is not in the key=value form", item)
+			}
+			parsed, err := parseTagScalar(tagValueKind(opt, true), value)
+			if err != nil {
+				return nil, err
+			}
+			values[key] = parsed
+		}
+		return values, nil
+	}
+	if strings.HasPrefix(opt.TypeName, "[]") {
+		values := make([]any, 0)
+		if s == "" {
+			return values, nil
+		}
+		for _, item := range strings.Split(s, ",") {
+			parsed, err := parseTagScalar(tagValueKind(opt, true), item)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, parsed)
+		}
+		return values, nil
+	}
+	return parseTagScalar(tagValueKind(opt, false), s)
+}
+
+// parseTagScalar parses a single tag value s as kind, the name of a basic type or time.Duration.
+// Integers are int for "int" (like the int options), int64 or uint64 for the other integer types,
+// and durations are kept in their string form (e.g. "1m30s"), like the defaults of goat.Default.
+func parseTagScalar(kind string, s string) (any, error) {
+	switch kind {
+	case "string":
+		return s, nil
+	case "bool":
+		return strconv.ParseBool(s)
+	case "int":
+		v, err := strconv.ParseInt(s, 0, 0)
+		return int(v), err
+	case "int8", "int16", "int32", "int64":
+		bitSize, _ := strconv.Atoi(strings.TrimPrefix(kind, "int"))
+		return strconv.ParseInt(s, 0, bitSize)
+	case "uint", "uint8", "uint16", "uint32", "uint64":
+		bitSize, _ := strconv.Atoi(strings.TrimPrefix(kind, "uint")) // 0 for uint
+		return strconv.ParseUint(s, 0, bitSize)
+	case "float32", "float64":
+		bitSize, _ := strconv.Atoi(strings.TrimPrefix(kind, "float"))
+		return strconv.ParseFloat(s, bitSize)
+	case "time.Duration":
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, err
+		}
+		return d.String(), nil
+	}
+	return nil, fmt.Errorf("values of type %s cannot be given in tags", kind)
+}
+
+// positionalArgFromTags returns the positional argument described by the `arg` (a single argument at the given index)
+// or `args` (the remaining arguments) struct tag of opt, or nil if the option is a flag.
+func positionalArgFromTags(opt *metadata.OptionMetadata, argTag string, argsTag string) (*metadata.PositionalArg, error) {
+	switch {
+	case argTag != "" && argsTag != "":
+		return nil, fmt.Errorf("both arg and args tags are specified")
+	case argTag != "":
+		index, err := strconv.Atoi(argTag)
+		if err != nil || index < 0 {
+			return nil, fmt.Errorf("arg tag must be a non-negative index, got %q", argTag)
+		}
+		if opt.TypeName != "string" && opt.TypeName != "*string" && opt.UnderlyingKind != "string" {
+			return nil, fmt.Errorf("arg tag is only supported for string fields, got %s", opt.TypeName)
+		}
+		return &metadata.PositionalArg{Index: index}, nil
+	case argsTag != "":
+		if argsTag != "+" && argsTag != "*" {
+			if n, err := strconv.Atoi(argsTag); err != nil || n <= 0 {
+				return nil, fmt.Errorf(`args tag must be "+", "*" or a positive number, got %q`, argsTag)
+			}
+		}
+		if opt.TypeName != "[]string" {
+			return nil, fmt.Errorf("args tag is only supported for []string fields, got %s", opt.TypeName)
+		}
+		return &metadata.PositionalArg{Arity: argsTag}, nil
+	}
+	return nil, nil
+}
+
+// ValidatePositionalArgs checks that the single positional arguments are numbered 0..N-1 without gaps,
+// that required ones do not follow optional ones, and that at most one field receives the remaining arguments.
+// The remaining arguments start after the single ones, so their Index is set here.
+func ValidatePositionalArgs(options []*metadata.OptionMetadata) error {
+	var singles []*metadata.OptionMetadata
+	var rest *metadata.OptionMetadata
+	for _, opt := range options {
+		switch {
+		case opt.Positional == nil:
+			continue
+		case opt.Positional.Arity != "":
+			if rest != nil {
+				return fmt.Errorf("both %s and %s receive the remaining positional arguments", rest.Name, opt.Name)
+			}
+			rest = opt
+		default:
+			singles = append(singles, opt)
+		}
+	}
+
+	sort.SliceStable(singles, func(i, j int) bool { return singles[i].Positional.Index < singles[j].Positional.Index })
+	for i, opt := range singles {
+		if opt.Positional.Index != i {
+			return fmt.Errorf("positional argument indexes must be 0..%d without duplicates or gaps, but %s has %d", len(singles)-1, opt.Name, opt.Positional.Index)
+		}
+		if i > 0 && singles[i-1].IsOptionalPositional() && !opt.IsOptionalPositional() {
+			return fmt.Errorf("required positional argument %s follows optional positional argument %s", opt.Name, singles[i-1].Name)
+		}
+	}
+	if rest != nil {
+		rest.Positional.Index = len(singles)
+		if len(singles) > 0 && singles[len(singles)-1].IsOptionalPositional() && !rest.IsOptionalPositional() {
+			return fmt.Errorf("required positional arguments %s follow optional positional argument %s", rest.Name, singles[len(singles)-1].Name)
+		}
+	}
+	return nil
+}
+
+// ApplyEnvPrefix derives the environment variables of the options without an `env` tag from the prefix (`goat emit -env-prefix`),
+// e.g. APP_USER_NAME for UserName and APP_DB_HOST for DB.Host with the prefix "APP".
+// The options with `env:"-"`, the positional options and the options whose values cannot be read from an environment variable
+// (for which supportsEnvVar returns false, e.g. a pointer to a struct) are left as is.
+// An error is returned if a derived name collides with another environment variable of the options.
+func ApplyEnvPrefix(prefix string, supportsEnvVar func(*metadata.OptionMetadata) bool, optionsList ...[]*metadata.OptionMetadata) error {
+	prefix = strings.TrimSuffix(prefix, "_")
+	if prefix == "" {
+		return nil
+	}
+	derived := map[*metadata.OptionMetadata]bool{}
+	for _, options := range optionsList {
+		for _, opt := range options {
+			if opt.EnvVar != "" || opt.NoEnvVar || opt.Positional != nil || !supportsEnvVar(opt) {
+				continue
+			}
+			name := prefix
+			for _, part := range strings.Split(opt.Name, ".") {
+				name += "_" + stringutils.ToUpperSnakeCase(part)
+			}
+			opt.EnvVar = name
+			derived[opt] = true
+		}
+	}
+
+	owners := map[string]*metadata.OptionMetadata{}
+	for _, options := range optionsList {
+		for _, opt := range options {
+			if opt.EnvVar == "" {
+				continue
+			}
+			if owner, ok := owners[opt.EnvVar]; ok && (derived[opt] || derived[owner]) {
+				return fmt.Errorf("environment variable %s of %s is already used by %s", opt.EnvVar, opt.Name, owner.Name)
+			}
+			owners[opt.EnvVar] = opt
+		}
+	}
+	return nil
+}
+
+// ValidateFlagNames checks the flag names of the options (CLI names, short names and aliases) registered in the same flag set.
+// Short names must be a single character, and no name may be used twice (including the --<flag>-file flags of the secret options).
+// Short names and aliases must not shadow the help flag (-h, --help); a field named Help is left as is.
+// Positional options have no flag and are ignored.
+func ValidateFlagNames(optionsList ...[]*metadata.OptionMetadata) error {
+	owners := map[string]string{}
+	for _, options := range optionsList {
+		for _, opt := range options {
+			if opt.Positional != nil {
+				if opt.ShortName != "" || len(opt.Aliases) > 0 {
+					return fmt.Errorf("positional argument %s cannot have a short name or aliases", opt.Name)
+				}
+				continue
+			}
+			if opt.ShortName != "" && utf8.RuneCountInString(opt.ShortName) != 1 {
+				return fmt.Errorf("short name of %s must be a single character, got %q", opt.Name, opt.ShortName)
+			}
+			cliName := opt.CliName
+			if cliName == "" {
+				cliName = stringutils.ToKebabCase(opt.Name)
+			}
+			names := append([]string{cliName, opt.ShortName}, opt.Aliases...)
+			if opt.IsSecret {
+				names = append(names, opt.SecretFileFlag())
+			}
+			for _, name := range names {
+				if name == "" {
+					continue
+				}
+				if strings.HasPrefix(name, "-") || strings.ContainsAny(name, "= ") {
+					return fmt.Errorf("invalid flag name %q for %s", name, opt.Name)
+				}
+				if name != cliName && (name == "h" || name == "help") {
+					return fmt.Errorf("flag name %q of %s is already used by the help flag", name, opt.Name)
+				}
+				if owner, ok := owners[name]; ok {
+					return fmt.Errorf("flag name %q of %s is already used by %s", name, opt.Name, owner)
+				}
+				owners[name] = opt.Name
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateValueConstraints checks that the range constraints (goat.Min, goat.Max, goat.Range) are used on numeric options
+// and the length constraints (goat.MinLen, goat.MaxLen) on string or slice options, with the minimum not exceeding the maximum.
+// The pattern constraint (goat.Pattern) is only allowed on string options, and the file options (goat.MustExist, goat.GlobPattern)
+// on string or []string options. Directory options (goat.Dir) must be strings with at most one policy,
+// and the config file option (`goat:"config"`) must be a string, at most one per options struct.
+// Secret options (goat.Secret) must be strings given by a flag, so that they can also be read from a file.
+func ValidateValueConstraints(optionsList ...[]*metadata.OptionMetadata) error {
+	for _, options := range optionsList {
+		configFileOption := ""
+		for _, opt := range options {
+			kind := strings.TrimPrefix(opt.TypeName, "*")
+			if opt.UnderlyingKind != "" {
+				kind = opt.UnderlyingKind
+			}
+			if opt.MinValue != nil || opt.MaxValue != nil {
+				if !isNumericKind(kind) {
+					return fmt.Errorf("range constraint of %s requires a numeric type, got %s", opt.Name, opt.TypeName)
+				}
+				for _, v := range []any{opt.MinValue, opt.MaxValue} {
+					if _, isFloat := v.(float64); isFloat && !strings.HasPrefix(kind, "float") {
+						return fmt.Errorf("range constraint of %s must be integers for %s, got %v", opt.Name, opt.TypeName, v)
+					}
+				}
+				if opt.MinValue != nil && opt.MaxValue != nil && toFloat64(opt.MinValue) > toFloat64(opt.MaxValue) {
+					return fmt.Errorf("invalid range of %s: %s", opt.Name, opt.ValueRange())
+				}
+			}
+			if opt.MinLen != nil || opt.MaxLen != nil {
+				if kind != "string" && !strings.HasPrefix(kind, "[]") && !strings.HasPrefix(kind, "map[") {
+					return fmt.Errorf("length constraint of %s requires a string, slice or map type, got %s", opt.Name, opt.TypeName)
+				}
+				if (opt.MinLen != nil && *opt.MinLen < 0) || (opt.MaxLen != nil && *opt.MaxLen < 0) ||
+					(opt.MinLen != nil && opt.MaxLen != nil && *opt.MinLen > *opt.MaxLen) {
+					return fmt.Errorf("invalid length constraint of %s: %s", opt.Name, opt.LengthRange())
+				}
+			}
+			if opt.Pattern != "" && kind != "string" {
+				return fmt.Errorf("pattern constraint of %s requires a string type, got %s", opt.Name, opt.TypeName)
+			}
+			if (opt.FileMustExist || opt.FileGlobPattern) && kind != "string" && opt.TypeName != "[]string" {
+				return fmt.Errorf("file options of %s require a string or []string type, got %s", opt.Name, opt.TypeName)
+			}
+			if opt.IsDir {
+				if kind != "string" {
+					return fmt.Errorf("directory option %s requires a string type, got %s", opt.Name, opt.TypeName)
+				}
+				if opt.DirMustExist && opt.DirCreateIfMissing {
+					return fmt.Errorf("directory option %s cannot have both MustExist and CreateIfMissing", opt.Name)
+				}
+			}
+			if opt.IsSecret && (strings.TrimPrefix(opt.TypeName, "*") != "string" || opt.Positional != nil) {
+				return fmt.Errorf("secret option %s requires a string type and a flag, got %s", opt.Name, opt.TypeName)
+			}
+			if opt.IsConfigFile {
+				if strings.TrimPrefix(opt.TypeName, "*") != "string" {
+					return fmt.Errorf("config file option %s requires a string type, got %s", opt.Name, opt.TypeName)
+				}
+				if configFileOption != "" {
+					return fmt.Errorf("only one config file option is allowed, got %s and %s", configFileOption, opt.Name)
+				}
+				configFileOption = opt.Name
+			}
+		}
+	}
+	return nil
+}
+
+func isNumericKind(kind string) bool {
+	switch kind {
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64", "float32", "float64":
+		return true
+	}
+	return false
+}
+
+func toFloat64(v any) float64 {
+	switch v := v.(type) {
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	}
+	return 0
+}
+
 /*
 // Original AnalyzeOptions - keep for now if other parts of the codebase use it,
 // or remove if AnalyzeOptionsV2 is a direct replacement.
@@ -8,7 +8,8 @@ import (
 	"go/token"
 	"os"
 	"path/filepath" // For writing temp files
-	"strconv"       // Added for strconv.Unquote in NewTestPackageLocator
+	"reflect"
+	"strconv" // Added for strconv.Unquote in NewTestPackageLocator
 	"strings"
 	"testing"
 
@@ -559,3 +560,729 @@ type Config struct {
 		t.Errorf("Expected option name 'Exported', got '%s'", options[0].Name)
 	}
 }
+
+func TestAnalyzeOptions_PositionalArgs_LazyLoad(t *testing.T) {
+	pkgPath := "testpositionalv3"
+	content := `
+package main
+type Config struct {
+	Src     string   ` + "`arg:\"0\"`" + `
+	Dst     *string  ` + "`arg:\"1\"`" + `
+	Files   []string ` + "`args:\"*\"`" + `
+	Verbose bool
+}
+`
+	packages := TestModulePackages{
+		".": {{Name: "config.go", Content: content}},
+	}
+	fset, tempModRoot := setupTestEnvironmentForLazyLoad(t, pkgPath, packages)
+
+	llCfg := loader.Config{
+		Fset:    fset,
+		Locator: NewTestPackageLocator(tempModRoot, t),
+	}
+	ctx := context.Background()
+	loader := loader.New(llCfg)
+	options, _, err := AnalyzeOptions(ctx, fset, "Config", pkgPath, tempModRoot, loader)
+	if err != nil {
+		t.Fatalf("AnalyzeOptions failed for PositionalArgs: %v. Content:\n%s", err, content)
+	}
+	if err := ValidatePositionalArgs(options); err != nil {
+		t.Fatalf("ValidatePositionalArgs failed: %v", err)
+	}
+	if len(options) != 4 {
+		t.Fatalf("Expected 4 options, got %d", len(options))
+	}
+
+	expected := []*metadata.PositionalArg{{Index: 0}, {Index: 1}, {Index: 2, Arity: "*"}, nil}
+	for i, opt := range options {
+		if !reflect.DeepEqual(opt.Positional, expected[i]) {
+			t.Errorf("Expected %s to have Positional %+v, got %+v", opt.Name, expected[i], opt.Positional)
+		}
+	}
+}
+
+func TestValidatePositionalArgs(t *testing.T) {
+	single := func(name string, index int, isPointer bool) *metadata.OptionMetadata {
+		return &metadata.OptionMetadata{Name: name, TypeName: "string", IsPointer: isPointer, Positional: &metadata.PositionalArg{Index: index}}
+	}
+	rest := func(name string, arity string) *metadata.OptionMetadata {
+		return &metadata.OptionMetadata{Name: name, TypeName: "[]string", Positional: &metadata.PositionalArg{Arity: arity}}
+	}
+
+	tests := []struct {
+		name    string
+		options []*metadata.OptionMetadata
+		wantErr string
+	}{
+		{name: "ok", options: []*metadata.OptionMetadata{single("B", 1, true), single("A", 0, false), rest("C", "*")}},
+		{name: "gap", options: []*metadata.OptionMetadata{single("A", 0, false), single("B", 2, false)}, wantErr: "without duplicates or gaps"},
+		{name: "duplicate", options: []*metadata.OptionMetadata{single("A", 0, false), single("B", 0, false)}, wantErr: "without duplicates or gaps"},
+		{name: "required after optional", options: []*metadata.OptionMetadata{single("A", 0, true), single("B", 1, false)}, wantErr: "follows optional"},
+		{name: "required rest after optional", options: []*metadata.OptionMetadata{single("A", 0, true), rest("B", "+")}, wantErr: "follow optional"},
+		{name: "two rests", options: []*metadata.OptionMetadata{rest("A", "*"), rest("B", "+")}, wantErr: "remaining positional arguments"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePositionalArgs(tt.options)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ValidatePositionalArgs() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidatePositionalArgs() error = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAnalyzeOptions_FlagNameTags_LazyLoad(t *testing.T) {
+	pkgPath := "testflagnamesv3"
+	content := `
+package main
+type Config struct {
+	Verbose   bool   ` + "`short:\"v\"`" + `
+	OutputDir string ` + "`alias:\"out, o-dir\"`" + `
+}
+`
+	packages := TestModulePackages{
+		".": {{Name: "config.go", Content: content}},
+	}
+	fset, tempModRoot := setupTestEnvironmentForLazyLoad(t, pkgPath, packages)
+
+	llCfg := loader.Config{
+		Fset:    fset,
+		Locator: NewTestPackageLocator(tempModRoot, t),
+	}
+	ctx := context.Background()
+	loader := loader.New(llCfg)
+	options, _, err := AnalyzeOptions(ctx, fset, "Config", pkgPath, tempModRoot, loader)
+	if err != nil {
+		t.Fatalf("AnalyzeOptions failed for FlagNameTags: %v. Content:\n%s", err, content)
+	}
+	if len(options) != 2 {
+		t.Fatalf("Expected 2 options, got %d", len(options))
+	}
+	if options[0].ShortName != "v" {
+		t.Errorf("Expected Verbose to have short name %q, got %q", "v", options[0].ShortName)
+	}
+	if want := []string{"out", "o-dir"}; !reflect.DeepEqual(options[1].Aliases, want) {
+		t.Errorf("Expected OutputDir to have aliases %v, got %v", want, options[1].Aliases)
+	}
+}
+
+func TestAnalyzeOptions_SourcePositions_LazyLoad(t *testing.T) {
+	pkgPath := "testsourcepositionsv3"
+	content := `package main
+
+type Config struct {
+	Port    int    ` + "`json:\"port\" env:\"PORT\"`" + `
+	Verbose bool
+}
+`
+	packages := TestModulePackages{
+		".": {{Name: "config.go", Content: content}},
+	}
+	fset, tempModRoot := setupTestEnvironmentForLazyLoad(t, pkgPath, packages)
+
+	llCfg := loader.Config{
+		Fset:    fset,
+		Locator: NewTestPackageLocator(tempModRoot, t),
+	}
+	ctx := context.Background()
+	loader := loader.New(llCfg)
+	options, _, err := AnalyzeOptions(ctx, fset, "Config", pkgPath, tempModRoot, loader)
+	if err != nil {
+		t.Fatalf("AnalyzeOptions failed for SourcePositions: %v. Content:\n%s", err, content)
+	}
+	if len(options) != 2 {
+		t.Fatalf("Expected 2 options, got %d", len(options))
+	}
+
+	lineColumn := func(pos *token.Position) string {
+		if pos == nil {
+			return "<nil>"
+		}
+		if filepath.Base(pos.Filename) != "config.go" {
+			t.Errorf("Expected the position in config.go, got %s", pos)
+		}
+		return fmt.Sprintf("%d:%d", pos.Line, pos.Column)
+	}
+	expected := []struct{ field, envTag string }{
+		{"4:2", "4:30"},  // The env key in the tag
+		{"5:2", "<nil>"}, // No tag
+	}
+	for i, want := range expected {
+		if got := lineColumn(options[i].FieldPosition); got != want.field {
+			t.Errorf("%s: expected the field at %s, got %s", options[i].Name, want.field, got)
+		}
+		if got := lineColumn(options[i].EnvTagPosition); got != want.envTag {
+			t.Errorf("%s: expected the env tag at %s, got %s", options[i].Name, want.envTag, got)
+		}
+	}
+}
+
+func TestAnalyzeOptions_SliceElemTextUnmarshaler_LazyLoad(t *testing.T) {
+	pkgPath := "testsliceelemv3"
+	content := `
+package main
+
+type Level int
+
+func (l *Level) UnmarshalText(text []byte) error { return nil }
+
+type Point struct{ X, Y int }
+
+type Config struct {
+	Levels []Level
+	Points []Point
+	Tags   []string
+}
+`
+	packages := TestModulePackages{
+		".": {{Name: "config.go", Content: content}},
+	}
+	fset, tempModRoot := setupTestEnvironmentForLazyLoad(t, pkgPath, packages)
+
+	testLocator := NewTestPackageLocator(tempModRoot, t)
+	llCfg := loader.Config{
+		Fset: fset,
+		Locator: func(ctx context.Context, pattern string, buildCtx loader.BuildContext) ([]loader.PackageMetaInfo, error) {
+			if pattern == "encoding" { // The test locator does not know the standard library.
+				return loader.GoListLocator(ctx, pattern, buildCtx)
+			}
+			return testLocator(ctx, pattern, buildCtx)
+		},
+	}
+	ctx := context.Background()
+	loader := loader.New(llCfg)
+	options, _, err := AnalyzeOptions(ctx, fset, "Config", pkgPath, tempModRoot, loader)
+	if err != nil {
+		t.Fatalf("AnalyzeOptions failed for SliceElemTextUnmarshaler: %v. Content:\n%s", err, content)
+	}
+	if len(options) != 3 {
+		t.Fatalf("Expected 3 options, got %d", len(options))
+	}
+	for i, want := range []bool{true, false, false} {
+		if options[i].ElemIsTextUnmarshaler != want {
+			t.Errorf("Expected %s to have ElemIsTextUnmarshaler %v, got %v", options[i].Name, want, options[i].ElemIsTextUnmarshaler)
+		}
+		if options[i].IsTextUnmarshaler {
+			t.Errorf("Expected %s (a slice) not to be a TextUnmarshaler itself", options[i].Name)
+		}
+	}
+}
+
+func TestAnalyzeOptions_MapTypes_LazyLoad(t *testing.T) {
+	pkgPath := "testmapsv3"
+	content := `
+package main
+
+type Labels map[string]string
+
+type Config struct {
+	Label  Labels
+	Limit  map[string]int
+	Weight *map[string]float64
+}
+`
+	packages := TestModulePackages{
+		".": {{Name: "config.go", Content: content}},
+	}
+	fset, tempModRoot := setupTestEnvironmentForLazyLoad(t, pkgPath, packages)
+
+	llCfg := loader.Config{
+		Fset:    fset,
+		Locator: NewTestPackageLocator(tempModRoot, t),
+	}
+	ctx := context.Background()
+	loader := loader.New(llCfg)
+	options, _, err := AnalyzeOptions(ctx, fset, "Config", pkgPath, tempModRoot, loader)
+	if err != nil {
+		t.Fatalf("AnalyzeOptions failed for MapTypes: %v. Content:\n%s", err, content)
+	}
+	if len(options) != 3 {
+		t.Fatalf("Expected 3 options, got %d", len(options))
+	}
+	for i, want := range []string{"map[string]string", "map[string]int", "map[string]float64"} {
+		if options[i].UnderlyingKind != want {
+			t.Errorf("Expected %s to have UnderlyingKind %q, got %q", options[i].Name, want, options[i].UnderlyingKind)
+		}
+	}
+}
+
+func TestAnalyzeOptions_NestedStructs_LazyLoad(t *testing.T) {
+	pkgPath := "testnestedv3"
+	content := `
+package main
+
+type TLSOptions struct {
+	Cert string ` + "`env:\"CERT\"`" + `
+}
+
+type DBOptions struct {
+	// Host of the database.
+	Host string ` + "`env:\"HOST\" alias:\"server\"`" + `
+	Port int
+	TLS  TLSOptions
+}
+
+type Level int
+
+type Config struct {
+	Verbose bool
+
+	// Database connection.
+	// (the primary one)
+	DB    DBOptions ` + "`envPrefix:\"APP_DB_\"`" + `
+	Cache DBOptions ` + "`prefix:\"redis\"`" + `
+	Level Level
+}
+`
+	packages := TestModulePackages{
+		".": {{Name: "config.go", Content: content}},
+	}
+	fset, tempModRoot := setupTestEnvironmentForLazyLoad(t, pkgPath, packages)
+
+	llCfg := loader.Config{
+		Fset:    fset,
+		Locator: NewTestPackageLocator(tempModRoot, t),
+	}
+	ctx := context.Background()
+	loader := loader.New(llCfg)
+	options, _, err := AnalyzeOptions(ctx, fset, "Config", pkgPath, tempModRoot, loader)
+	if err != nil {
+		t.Fatalf("AnalyzeOptions failed for NestedStructs: %v. Content:\n%s", err, content)
+	}
+
+	type nestedOption struct {
+		Name, CliName, EnvVar, Section string
+		Aliases                        []string
+	}
+	var got []nestedOption
+	for _, opt := range options {
+		got = append(got, nestedOption{Name: opt.Name, CliName: opt.CliName, EnvVar: opt.EnvVar, Section: opt.Section, Aliases: opt.Aliases})
+	}
+	want := []nestedOption{
+		{Name: "Verbose", CliName: "verbose"},
+		{Name: "DB.Host", CliName: "db-host", EnvVar: "APP_DB_HOST", Section: "Database connection.\n(the primary one)", Aliases: []string{"db-server"}},
+		{Name: "DB.Port", CliName: "db-port", Section: "Database connection.\n(the primary one)"},
+		{Name: "DB.TLS.Cert", CliName: "db-tls-cert", EnvVar: "APP_DB_TLS_CERT", Section: "Database connection.\n(the primary one)"},
+		{Name: "Cache.Host", CliName: "redis-host", EnvVar: "CACHE_HOST", Section: "Cache", Aliases: []string{"redis-server"}},
+		{Name: "Cache.Port", CliName: "redis-port", Section: "Cache"},
+		{Name: "Cache.TLS.Cert", CliName: "redis-tls-cert", EnvVar: "CACHE_TLS_CERT", Section: "Cache"},
+		{Name: "Level", CliName: "level"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("nested options mismatch:\n got: %+v\nwant: %+v", got, want)
+	}
+}
+
+func TestAnalyzeOptions_NestedStructPositional_LazyLoad(t *testing.T) {
+	pkgPath := "testnestedargsv3"
+	content := `
+package main
+
+type FilesOptions struct {
+	Paths []string ` + "`args:\"*\"`" + `
+}
+
+type Config struct {
+	Files FilesOptions
+}
+`
+	packages := TestModulePackages{
+		".": {{Name: "config.go", Content: content}},
+	}
+	fset, tempModRoot := setupTestEnvironmentForLazyLoad(t, pkgPath, packages)
+
+	llCfg := loader.Config{
+		Fset:    fset,
+		Locator: NewTestPackageLocator(tempModRoot, t),
+	}
+	ctx := context.Background()
+	loader := loader.New(llCfg)
+	_, _, err := AnalyzeOptions(ctx, fset, "Config", pkgPath, tempModRoot, loader)
+	if err == nil || !strings.Contains(err.Error(), "cannot receive positional arguments") {
+		t.Errorf("Expected an error about positional arguments in a nested struct, got %v", err)
+	}
+}
+
+func TestAnalyzeOptions_DeclarationTags_LazyLoad(t *testing.T) {
+	pkgPath := "testdeclarationtagsv3"
+	content := `
+package main
+
+import "time"
+
+type Mode string
+
+type Config struct {
+	Port     int               ` + "`default:\"8080\" flag:\"listen-port\" short:\"p\" required:\"false\"`" + `
+	LogLevel string            ` + "`default:\"info\" enum:\"debug, info, warn\"`" + `
+	Mode     Mode              ` + "`default:\"fast\"`" + `
+	Name     *string           ` + "`default:\"\" required:\"true\"`" + `
+	Tags     []string          ` + "`default:\"a,b\"`" + `
+	Ports    []int             ` + "`default:\"80,443\"`" + `
+	Labels   map[string]int    ` + "`default:\"x=1,y=2\"`" + `
+	Timeout  time.Duration     ` + "`default:\"90s\"`" + `
+	Ratio    float32           ` + "`default:\"0.5\"`" + `
+	Token    string            ` + "`env:\"-\" required:\"false\" secret:\"true\"`" + `
+	Internal string            ` + "`goat:\"-\"`" + `
+}
+`
+	packages := TestModulePackages{
+		".": {{Name: "config.go", Content: content}},
+	}
+	fset, tempModRoot := setupTestEnvironmentForLazyLoad(t, pkgPath, packages)
+
+	llCfg := loader.Config{
+		Fset:    fset,
+		Locator: NewTestPackageLocator(tempModRoot, t),
+	}
+	ctx := context.Background()
+	loader := loader.New(llCfg)
+	options, _, err := AnalyzeOptions(ctx, fset, "Config", pkgPath, tempModRoot, loader)
+	if err != nil {
+		t.Fatalf("AnalyzeOptions failed for DeclarationTags: %v. Content:\n%s", err, content)
+	}
+
+	type declaredOption struct {
+		Name         string
+		CliName      string
+		IsRequired   bool
+		DefaultValue any
+		EnumValues   []any
+	}
+	var got []declaredOption
+	for _, opt := range options {
+		got = append(got, declaredOption{Name: opt.Name, CliName: opt.CliName, IsRequired: opt.IsRequired, DefaultValue: opt.DefaultValue, EnumValues: opt.EnumValues})
+	}
+	want := []declaredOption{
+		{Name: "Port", CliName: "listen-port", DefaultValue: 8080},
+		{Name: "LogLevel", CliName: "log-level", IsRequired: true, DefaultValue: "info", EnumValues: []any{"debug", "info", "warn"}},
+		{Name: "Mode", CliName: "mode", IsRequired: true, DefaultValue: "fast"},
+		{Name: "Name", CliName: "name", IsRequired: true, DefaultValue: ""},
+		{Name: "Tags", CliName: "tags", IsRequired: true, DefaultValue: []any{"a", "b"}},
+		{Name: "Ports", CliName: "ports", IsRequired: true, DefaultValue: []any{80, 443}},
+		{Name: "Labels", CliName: "labels", IsRequired: true, DefaultValue: map[string]any{"x": 1, "y": 2}},
+		{Name: "Timeout", CliName: "timeout", IsRequired: true, DefaultValue: "1m30s"},
+		{Name: "Ratio", CliName: "ratio", IsRequired: true, DefaultValue: float64(0.5)},
+		{Name: "Token", CliName: "token"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("declared options mismatch:\n got: %+v\nwant: %+v", got, want)
+	}
+	if options[0].ShortName != "p" {
+		t.Errorf("Expected Port to have short name %q, got %q", "p", options[0].ShortName)
+	}
+	if token := options[len(options)-1]; !token.NoEnvVar || token.EnvVar != "" {
+		t.Errorf("Expected Token to opt out of the environment variable, got NoEnvVar=%v EnvVar=%q", token.NoEnvVar, token.EnvVar)
+	}
+	if token := options[len(options)-1]; !token.IsSecret {
+		t.Errorf("Expected Token to be a secret option")
+	}
+}
+
+func TestAnalyzeOptions_ConfigFileTags_LazyLoad(t *testing.T) {
+	pkgPath := "testconfigfiletags"
+	content := `
+package main
+
+type DBOptions struct {
+	Host string ` + "`json:\"hostname,omitempty\"`" + `
+	Port int
+}
+
+type Config struct {
+	Config  string ` + "`goat:\"config\" default:\"app.json\"`" + `
+	Name    string ` + "`json:\"userName\"`" + `
+	Token   string ` + "`json:\"-\"`" + `
+	Verbose bool
+	DB      DBOptions
+}
+`
+	packages := TestModulePackages{
+		".": {{Name: "config.go", Content: content}},
+	}
+	fset, tempModRoot := setupTestEnvironmentForLazyLoad(t, pkgPath, packages)
+
+	llCfg := loader.Config{
+		Fset:    fset,
+		Locator: NewTestPackageLocator(tempModRoot, t),
+	}
+	ctx := context.Background()
+	loader := loader.New(llCfg)
+	options, _, err := AnalyzeOptions(ctx, fset, "Config", pkgPath, tempModRoot, loader)
+	if err != nil {
+		t.Fatalf("AnalyzeOptions failed for ConfigFileTags: %v. Content:\n%s", err, content)
+	}
+
+	type configOption struct {
+		Name         string
+		IsConfigFile bool
+		ConfigKey    string
+	}
+	var got []configOption
+	for _, opt := range options {
+		got = append(got, configOption{Name: opt.Name, IsConfigFile: opt.IsConfigFile, ConfigKey: opt.ConfigKey})
+	}
+	want := []configOption{
+		{Name: "Config", IsConfigFile: true},
+		{Name: "Name", ConfigKey: "userName"},
+		{Name: "Token", ConfigKey: "-"},
+		{Name: "Verbose"},
+		{Name: "DB.Host", ConfigKey: "db-hostname"},
+		{Name: "DB.Port"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("config file options mismatch:\n got: %+v\nwant: %+v", got, want)
+	}
+}
+
+func TestAnalyzeOptions_InvalidDeclarationTags_LazyLoad(t *testing.T) {
+	testCases := []struct {
+		name    string
+		field   string
+		wantErr string
+	}{
+		{name: "int default", field: "Port int `default:\"http\"`", wantErr: "invalid tag on field 'Port': `default:\"http\"`: strconv.ParseInt"},
+		{name: "int8 out of range", field: "Level int8 `default:\"300\"`", wantErr: "value out of range"},
+		{name: "bool required", field: "Name string `required:\"maybe\"`", wantErr: "`required:\"maybe\"` must be true or false"},
+		{name: "enum", field: "Level int `enum:\"1,two\"`", wantErr: "`enum:\"1,two\"`"},
+		{name: "default not in enum", field: "Level string `default:\"trace\" enum:\"debug,info\"`", wantErr: "is not one of the enum values"},
+		{name: "map entry", field: "Labels map[string]string `default:\"a\"`", wantErr: "\"a\" is not in the key=value form"},
+		{name: "unsupported type", field: "Point struct{ X int } `default:\"1\"`", wantErr: "cannot be given in tags"},
+		{name: "goat tag", field: "Config string `goat:\"file\"`", wantErr: "`goat:\"file\"` must be \"-\" or \"config\""},
+		{name: "bool secret", field: "Password string `secret:\"yes\"`", wantErr: "`secret:\"yes\"` must be true or false"},
+	}
+	for i, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pkgPath := fmt.Sprintf("testinvalidtagsv3_%d", i)
+			content := "package main\n\ntype Config struct {\n\t" + tc.field + "\n}\n"
+			packages := TestModulePackages{
+				".": {{Name: "config.go", Content: content}},
+			}
+			fset, tempModRoot := setupTestEnvironmentForLazyLoad(t, pkgPath, packages)
+			llCfg := loader.Config{
+				Fset:    fset,
+				Locator: NewTestPackageLocator(tempModRoot, t),
+			}
+			_, _, err := AnalyzeOptions(context.Background(), fset, "Config", pkgPath, tempModRoot, loader.New(llCfg))
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("Expected an error containing %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestHasValidateMethod_LazyLoad(t *testing.T) {
+	pkgPath := "testvalidatev3"
+	content := `
+package main
+
+import "errors"
+
+type Config struct {
+	Min int
+	Max int
+}
+
+func (c *Config) Validate() error {
+	if c.Min > c.Max {
+		return errors.New("min must not be greater than max")
+	}
+	return nil
+}
+
+type NoValidate struct{}
+
+type WrongSignature struct{}
+
+func (w WrongSignature) Validate(strict bool) error { return nil }
+
+type NoError struct{}
+
+func (n NoError) Validate() bool { return true }
+`
+	packages := TestModulePackages{
+		".": {{Name: "config.go", Content: content}},
+	}
+	fset, tempModRoot := setupTestEnvironmentForLazyLoad(t, pkgPath, packages)
+
+	llCfg := loader.Config{
+		Fset:    fset,
+		Locator: NewTestPackageLocator(tempModRoot, t),
+	}
+	ctx := context.Background()
+	loader := loader.New(llCfg)
+
+	tests := []struct {
+		typeName string
+		want     bool
+	}{
+		{typeName: "Config", want: true},
+		{typeName: "*Config", want: true},
+		{typeName: "NoValidate", want: false},
+		{typeName: "WrongSignature", want: false},
+		{typeName: "NoError", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.typeName, func(t *testing.T) {
+			got, err := HasValidateMethod(ctx, tt.typeName, pkgPath, tempModRoot, loader)
+			if err != nil {
+				t.Fatalf("HasValidateMethod failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("HasValidateMethod(%q) = %v, want %v", tt.typeName, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateFlagNames(t *testing.T) {
+	flag := func(name string, short string, aliases ...string) *metadata.OptionMetadata {
+		return &metadata.OptionMetadata{Name: name, TypeName: "string", ShortName: short, Aliases: aliases}
+	}
+
+	tests := []struct {
+		name        string
+		options     []*metadata.OptionMetadata
+		globalFlags []*metadata.OptionMetadata
+		wantErr     string
+	}{
+		{name: "ok", options: []*metadata.OptionMetadata{flag("Verbose", "v"), flag("OutputDir", "o", "out")}, globalFlags: []*metadata.OptionMetadata{flag("Debug", "d")}},
+		{name: "short collides with short", options: []*metadata.OptionMetadata{flag("Verbose", "v"), flag("Version", "v")}, wantErr: `"v" of Version is already used by Verbose`},
+		{name: "alias collides with name", options: []*metadata.OptionMetadata{flag("Output", ""), flag("OutputDir", "", "output")}, wantErr: `"output" of OutputDir is already used by Output`},
+		{name: "collides with global", options: []*metadata.OptionMetadata{flag("Verbose", "v")}, globalFlags: []*metadata.OptionMetadata{flag("Version", "v")}, wantErr: `"v" of Verbose is already used by Version`},
+		{name: "help", options: []*metadata.OptionMetadata{flag("Host", "h")}, wantErr: "the help flag"},
+		{name: "long short name", options: []*metadata.OptionMetadata{flag("Verbose", "vv")}, wantErr: "single character"},
+		{name: "invalid alias", options: []*metadata.OptionMetadata{flag("Verbose", "", "--loud")}, wantErr: "invalid flag name"},
+		{name: "secret file flag", options: []*metadata.OptionMetadata{{Name: "Password", TypeName: "string", IsSecret: true}, flag("PasswordFile", "")}, wantErr: `"password-file" of PasswordFile is already used by Password`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateFlagNames(tt.globalFlags, tt.options)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ValidateFlagNames() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateFlagNames() error = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestApplyEnvPrefix(t *testing.T) {
+	newOptions := func() ([]*metadata.OptionMetadata, []*metadata.OptionMetadata) {
+		global := []*metadata.OptionMetadata{
+			{Name: "Verbose", TypeName: "bool"},
+		}
+		options := []*metadata.OptionMetadata{
+			{Name: "UserName", TypeName: "string"},
+			{Name: "Port", TypeName: "int", EnvVar: "PORT"},
+			{Name: "Token", TypeName: "string", NoEnvVar: true},
+			{Name: "DB.Host", TypeName: "string"},
+			{Name: "Cache", TypeName: "*DBOptions"},
+			{Name: "Files", TypeName: "[]string", Positional: &metadata.PositionalArg{Index: 0, Arity: "*"}},
+		}
+		return global, options
+	}
+	supportsEnvVar := func(opt *metadata.OptionMetadata) bool { return opt.TypeName != "*DBOptions" }
+
+	t.Run("derived names", func(t *testing.T) {
+		global, options := newOptions()
+		if err := ApplyEnvPrefix("APP_", supportsEnvVar, global, options); err != nil {
+			t.Fatalf("ApplyEnvPrefix() unexpected error: %v", err)
+		}
+		got := map[string]string{}
+		for _, opt := range append(global, options...) {
+			got[opt.Name] = opt.EnvVar
+		}
+		want := map[string]string{"Verbose": "APP_VERBOSE", "UserName": "APP_USER_NAME", "Port": "PORT", "Token": "", "DB.Host": "APP_DB_HOST", "Cache": "", "Files": ""}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("environment variables mismatch:\n got: %v\nwant: %v", got, want)
+		}
+	})
+
+	t.Run("no prefix", func(t *testing.T) {
+		global, options := newOptions()
+		if err := ApplyEnvPrefix("", supportsEnvVar, global, options); err != nil {
+			t.Fatalf("ApplyEnvPrefix() unexpected error: %v", err)
+		}
+		if options[0].EnvVar != "" {
+			t.Errorf("Expected no environment variable without a prefix, got %q", options[0].EnvVar)
+		}
+	})
+
+	t.Run("collision", func(t *testing.T) {
+		global, options := newOptions()
+		options[1].EnvVar = "APP_USER_NAME"
+		err := ApplyEnvPrefix("APP", supportsEnvVar, global, options)
+		want := "environment variable APP_USER_NAME of Port is already used by UserName"
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ApplyEnvPrefix() error = %v, want an error containing %q", err, want)
+		}
+	})
+}
+
+func TestValidateValueConstraints(t *testing.T) {
+	intPtr := func(n int) *int { return &n }
+
+	tests := []struct {
+		name    string
+		option  *metadata.OptionMetadata
+		wantErr string
+	}{
+		{name: "int range", option: &metadata.OptionMetadata{Name: "Port", TypeName: "int", MinValue: int64(1), MaxValue: int64(65535)}},
+		{name: "float range", option: &metadata.OptionMetadata{Name: "Ratio", TypeName: "*float64", MinValue: int64(0), MaxValue: 0.5}},
+		{name: "named int", option: &metadata.OptionMetadata{Name: "Level", TypeName: "Level", UnderlyingKind: "int", MinValue: int64(0)}},
+		{name: "string length", option: &metadata.OptionMetadata{Name: "Name", TypeName: "string", MinLen: intPtr(1), MaxLen: intPtr(8)}},
+		{name: "slice length", option: &metadata.OptionMetadata{Name: "Tags", TypeName: "[]string", MaxLen: intPtr(3)}},
+		{name: "range on string", option: &metadata.OptionMetadata{Name: "Name", TypeName: "string", MinValue: int64(1)}, wantErr: "requires a numeric type"},
+		{name: "float range on int", option: &metadata.OptionMetadata{Name: "Port", TypeName: "int", MaxValue: 1.5}, wantErr: "must be integers"},
+		{name: "min greater than max", option: &metadata.OptionMetadata{Name: "Port", TypeName: "int", MinValue: int64(10), MaxValue: int64(1)}, wantErr: "invalid range"},
+		{name: "length on int", option: &metadata.OptionMetadata{Name: "Port", TypeName: "int", MinLen: intPtr(1)}, wantErr: "requires a string, slice or map type"},
+		{name: "pattern", option: &metadata.OptionMetadata{Name: "Name", TypeName: "*string", Pattern: "^[a-z]+$"}},
+		{name: "pattern on int", option: &metadata.OptionMetadata{Name: "Port", TypeName: "int", Pattern: "^[0-9]+$"}, wantErr: "requires a string type"},
+		{name: "file", option: &metadata.OptionMetadata{Name: "Inputs", TypeName: "[]string", FileMustExist: true, FileGlobPattern: true}},
+		{name: "file on int", option: &metadata.OptionMetadata{Name: "Port", TypeName: "int", FileMustExist: true}, wantErr: "require a string or []string type"},
+		{name: "dir", option: &metadata.OptionMetadata{Name: "OutputDir", TypeName: "string", IsDir: true, DirCreateIfMissing: true}},
+		{name: "dir on slice", option: &metadata.OptionMetadata{Name: "Dirs", TypeName: "[]string", IsDir: true}, wantErr: "requires a string type"},
+		{name: "dir with both policies", option: &metadata.OptionMetadata{Name: "OutputDir", TypeName: "string", IsDir: true, DirMustExist: true, DirCreateIfMissing: true}, wantErr: "cannot have both"},
+		{name: "min length greater than max", option: &metadata.OptionMetadata{Name: "Name", TypeName: "string", MinLen: intPtr(5), MaxLen: intPtr(1)}, wantErr: "invalid length constraint"},
+		{name: "config file", option: &metadata.OptionMetadata{Name: "Config", TypeName: "*string", IsConfigFile: true}},
+		{name: "config file on int", option: &metadata.OptionMetadata{Name: "Config", TypeName: "int", IsConfigFile: true}, wantErr: "requires a string type"},
+		{name: "secret", option: &metadata.OptionMetadata{Name: "Password", TypeName: "*string", IsSecret: true}},
+		{name: "secret on int", option: &metadata.OptionMetadata{Name: "Pin", TypeName: "int", IsSecret: true}, wantErr: "requires a string type and a flag"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateValueConstraints([]*metadata.OptionMetadata{tt.option})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ValidateValueConstraints() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateValueConstraints() error = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("two config files", func(t *testing.T) {
+		err := ValidateValueConstraints([]*metadata.OptionMetadata{
+			{Name: "Config", TypeName: "string", IsConfigFile: true},
+			{Name: "Settings", TypeName: "string", IsConfigFile: true},
+		})
+		if err == nil || !strings.Contains(err.Error(), "only one config file option") {
+			t.Errorf("ValidateValueConstraints() error = %v, want an error containing %q", err, "only one config file option")
+		}
+	})
+}
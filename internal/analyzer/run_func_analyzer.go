@@ -4,9 +4,11 @@ import (
 	"fmt"
 	"go/ast"
 	"strings"
+	"unicode"
 
 	"github.com/podhmo/goat/internal/metadata"
 	"github.com/podhmo/goat/internal/utils/astutils"
+	"github.com/podhmo/goat/internal/utils/stringutils"
 )
 
 // AnalyzeRunFunc finds the specified 'run' function in the AST and extracts its metadata.
@@ -14,7 +16,7 @@ import (
 func AnalyzeRunFunc(files []*ast.File, funcName string) (*metadata.RunFuncInfo, string, error) {
 	var runFuncDecl *ast.FuncDecl
 	var docComment string
-	// var foundInFile *ast.File // To store the AST of the file where the function is found - Not strictly needed by current logic
+	var foundInFile *ast.File // The file where the function is found, to resolve the imports in its signature
 
 	for _, currentFileAst := range files {
 		ast.Inspect(currentFileAst, func(n ast.Node) bool {
@@ -23,7 +25,7 @@ func AnalyzeRunFunc(files []*ast.File, funcName string) (*metadata.RunFuncInfo,
 				if fn.Doc != nil {
 					docComment = fn.Doc.Text()
 				}
-				// foundInFile = currentFileAst // Save the file where it was found
+				foundInFile = currentFileAst
 				return false // Stop searching this file
 			}
 			return true
@@ -42,27 +44,68 @@ func AnalyzeRunFunc(files []*ast.File, funcName string) (*metadata.RunFuncInfo,
 		// PackageName will be set by the caller (analyzer.Analyze)
 	}
 
-	// Analyze parameters: expecting `run(options MyOptions) error` or `run(ctx context.Context, options MyOptions) error`
+	// Analyze parameters: expecting `run(options MyOptions) error`, `run(ctx context.Context, options MyOptions) error`,
+	// `run(global GlobalOptions, options MyOptions) error` or `run(ctx context.Context, global GlobalOptions, options MyOptions) error`
 	params := runFuncDecl.Type.Params.List
-	if len(params) == 1 {
-		if len(params[0].Names) > 0 {
-			info.OptionsArgName = params[0].Names[0].Name
-		}
-		info.OptionsArgType = astutils.ExprToTypeName(params[0].Type)
-	} else if len(params) == 2 {
-		if len(params[0].Names) > 0 {
-			info.ContextArgName = params[0].Names[0].Name
+	if len(params) == 0 || len(params) > 3 {
+		return nil, strings.TrimSpace(docComment), fmt.Errorf("function '%s' has unexpected signature: expected 1 to 3 parameters, got %d", funcName, len(params))
+	}
+
+	// The last parameter is always the options struct.
+	optionsParam := params[len(params)-1]
+	if len(optionsParam.Names) > 0 {
+		info.OptionsArgName = optionsParam.Names[0].Name
+	}
+	info.OptionsArgType = astutils.ExprToTypeName(optionsParam.Type)
+
+	rest := params[:len(params)-1]
+	if len(rest) > 0 && isContextType(foundInFile, rest[0].Type) {
+		if len(rest[0].Names) > 0 {
+			info.ContextArgName = rest[0].Names[0].Name
 		}
-		info.ContextArgType = astutils.ExprToTypeName(params[0].Type)
-		if len(params[1].Names) > 0 {
-			info.OptionsArgName = params[1].Names[0].Name
+		info.ContextArgType = astutils.ExprToTypeName(rest[0].Type)
+		rest = rest[1:]
+	} else if len(rest) == 2 {
+		return nil, strings.TrimSpace(docComment), fmt.Errorf("function '%s' has unexpected signature: the first of 3 parameters must be context.Context, got %s", funcName, astutils.ExprToTypeName(rest[0].Type))
+	}
+
+	// The remaining parameter (if any) is the global options struct.
+	if len(rest) > 0 {
+		if len(rest[0].Names) > 0 {
+			info.GlobalOptionsArgName = rest[0].Names[0].Name
 		}
-		info.OptionsArgType = astutils.ExprToTypeName(params[1].Type)
-	} else {
-		return nil, strings.TrimSpace(docComment), fmt.Errorf("function '%s' has unexpected signature: expected 1 or 2 parameters, got %d", funcName, len(params))
+		info.GlobalOptionsArgType = astutils.ExprToTypeName(rest[0].Type)
 	}
 
 	// TODO: Analyze return type (expecting `error`)
 
 	return info, strings.TrimSpace(docComment), nil
 }
+
+// isContextType reports whether expr is the type context.Context, with the package name of the import of "context"
+// in file (e.g. `stdctx.Context` with `import stdctx "context"`).
+func isContextType(file *ast.File, expr ast.Expr) bool {
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "Context" {
+		return false
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	return astutils.GetImportPath(file, pkg.Name) == "context"
+}
+
+// SubcommandName derives a subcommand name from the name of its run function.
+// A leading "run" (followed by an upper-case letter) is stripped and the rest is converted to kebab-case.
+// Example: "runServe" -> "serve", "RunDBMigrate" -> "db-migrate", "Migrate" -> "migrate"
+func SubcommandName(runFuncName string) string {
+	name := runFuncName
+	for _, prefix := range []string{"run", "Run"} {
+		if rest, ok := strings.CutPrefix(name, prefix); ok && rest != "" && unicode.IsUpper(rune(rest[0])) {
+			name = rest
+			break
+		}
+	}
+	return stringutils.ToKebabCase(name)
+}
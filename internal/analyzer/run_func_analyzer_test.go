@@ -84,6 +84,88 @@ func RunWithCtx(ctx context.Context, appOpts AppOptions) error { return nil }
 	}
 }
 
+func TestAnalyzeRunFunc_WithAliasedContext(t *testing.T) {
+	content := `
+package main
+
+import stdctx "context"
+
+func run(ctx stdctx.Context, opts Options) error { return nil }
+`
+	_, fileAst := parseSingleFileAst(t, content)
+	runFuncInfo, _, err := AnalyzeRunFunc([]*ast.File{fileAst}, "run")
+	if err != nil {
+		t.Fatalf("AnalyzeRunFunc failed: %v", err)
+	}
+	if runFuncInfo.ContextArgName != "ctx" || runFuncInfo.ContextArgType != "stdctx.Context" {
+		t.Errorf("Expected context arg 'ctx stdctx.Context', got '%s %s'", runFuncInfo.ContextArgName, runFuncInfo.ContextArgType)
+	}
+	if runFuncInfo.GlobalOptionsArgName != "" || runFuncInfo.GlobalOptionsArgType != "" {
+		t.Errorf("Expected no global options, got '%s %s'", runFuncInfo.GlobalOptionsArgName, runFuncInfo.GlobalOptionsArgType)
+	}
+	if runFuncInfo.OptionsArgType != "Options" {
+		t.Errorf("Expected options arg type 'Options', got '%s'", runFuncInfo.OptionsArgType)
+	}
+}
+
+func TestAnalyzeRunFunc_WithGlobalOptions(t *testing.T) {
+	tests := []struct {
+		name            string
+		content         string
+		wantContextArg  string
+		wantGlobalArg   string
+		wantGlobalType  string
+		wantOptionsType string
+	}{
+		{
+			name: "with context",
+			content: `
+package main
+
+import "context"
+
+func run(ctx context.Context, global GlobalOptions, opts *ServeOptions) error { return nil }
+`,
+			wantContextArg:  "ctx",
+			wantGlobalArg:   "global",
+			wantGlobalType:  "GlobalOptions",
+			wantOptionsType: "*ServeOptions",
+		},
+		{
+			name: "without context",
+			content: `
+package main
+
+func run(global *GlobalOptions, opts ServeOptions) error { return nil }
+`,
+			wantGlobalArg:   "global",
+			wantGlobalType:  "*GlobalOptions",
+			wantOptionsType: "ServeOptions",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, fileAst := parseSingleFileAst(t, tt.content)
+			runFuncInfo, _, err := AnalyzeRunFunc([]*ast.File{fileAst}, "run")
+			if err != nil {
+				t.Fatalf("AnalyzeRunFunc failed: %v", err)
+			}
+			if runFuncInfo.ContextArgName != tt.wantContextArg {
+				t.Errorf("Expected context arg name '%s', got '%s'", tt.wantContextArg, runFuncInfo.ContextArgName)
+			}
+			if runFuncInfo.GlobalOptionsArgName != tt.wantGlobalArg {
+				t.Errorf("Expected global options arg name '%s', got '%s'", tt.wantGlobalArg, runFuncInfo.GlobalOptionsArgName)
+			}
+			if runFuncInfo.GlobalOptionsArgType != tt.wantGlobalType {
+				t.Errorf("Expected global options arg type '%s', got '%s'", tt.wantGlobalType, runFuncInfo.GlobalOptionsArgType)
+			}
+			if runFuncInfo.OptionsArgType != tt.wantOptionsType {
+				t.Errorf("Expected options arg type '%s', got '%s'", tt.wantOptionsType, runFuncInfo.OptionsArgType)
+			}
+		})
+	}
+}
+
 func TestAnalyzeRunFunc_NotFound(t *testing.T) {
 	content := `package main; func SomeOtherFunc() {}`
 	_, fileAst := parseSingleFileAst(t, content)
@@ -106,4 +188,28 @@ func TestAnalyzeRunFunc_InvalidSignature(t *testing.T) {
 	if !strings.Contains(err.Error(), "unexpected signature") {
 		t.Errorf("Unexpected error message for invalid signature: %v", err)
 	}
+
+	content = `package main; func MyRun(a A, b B, c C) error { return nil }` // 3 params without context.Context
+	_, fileAst = parseSingleFileAst(t, content)
+	if _, _, err := AnalyzeRunFunc([]*ast.File{fileAst}, "MyRun"); err == nil || !strings.Contains(err.Error(), "unexpected signature") {
+		t.Errorf("Expected an unexpected signature error for 3 parameters without context.Context, got %v", err)
+	}
+}
+
+func TestSubcommandName(t *testing.T) {
+	tests := []struct {
+		runFuncName string
+		want        string
+	}{
+		{"runServe", "serve"},
+		{"RunDBMigrate", "db-migrate"},
+		{"Migrate", "migrate"},
+		{"run", "run"},
+		{"runner", "runner"},
+	}
+	for _, tt := range tests {
+		if got := SubcommandName(tt.runFuncName); got != tt.want {
+			t.Errorf("SubcommandName(%q) = %q, want %q", tt.runFuncName, got, tt.want)
+		}
+	}
 }
@@ -2,7 +2,10 @@ package codegen
 
 import (
 	"fmt"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	// "text/template" // Removed
 	// "bytes"         // Removed
@@ -51,9 +54,6 @@ func formatHelpText(text string) string {
 	}
 }
 
-// GenerateMain creates the Go code string for the new main() function
-// based on the extracted command metadata.
-// If generateFullFile is true, it returns a complete Go file content including package and imports.
 // GetEffectiveEnumValues converts enum values to a slice of strings.
 func GetEffectiveEnumValues(opt *metadata.OptionMetadata) []string {
 	if opt != nil && opt.EnumValues != nil {
@@ -66,13 +66,162 @@ func GetEffectiveEnumValues(opt *metadata.OptionMetadata) []string {
 	return nil
 }
 
-// GenerateMain creates the Go code string for the new main() function
-// based on the extracted command metadata.
-// If generateFullFile is true, it returns a complete Go file content including package and imports.
-// Otherwise, it returns only the main function body.
+// numericType describes how the options of a numeric type other than int (or time.Duration) are handled.
+type numericType struct {
+	FlagFunc string // Method of flag.FlagSet registering the flag (e.g. "Float64Var"), or "" to register it with Func
+	Parse    string // Format of the expression parsing a string into (value, error), e.g. "strconv.ParseInt(%s, 0, 8)"
+	Convert  bool   // True if the parsed value must be converted to the type (e.g. int8(v))
+}
+
+// numericTypes are the numeric types supported in addition to int, keyed by type name.
+var numericTypes = map[string]numericType{
+	"int8":          {Parse: "strconv.ParseInt(%s, 0, 8)", Convert: true},
+	"int16":         {Parse: "strconv.ParseInt(%s, 0, 16)", Convert: true},
+	"int32":         {Parse: "strconv.ParseInt(%s, 0, 32)", Convert: true},
+	"int64":         {FlagFunc: "Int64Var", Parse: "strconv.ParseInt(%s, 0, 64)"},
+	"uint":          {FlagFunc: "UintVar", Parse: "strconv.ParseUint(%s, 0, 0)", Convert: true},
+	"uint8":         {Parse: "strconv.ParseUint(%s, 0, 8)", Convert: true},
+	"uint16":        {Parse: "strconv.ParseUint(%s, 0, 16)", Convert: true},
+	"uint32":        {Parse: "strconv.ParseUint(%s, 0, 32)", Convert: true},
+	"uint64":        {FlagFunc: "Uint64Var", Parse: "strconv.ParseUint(%s, 0, 64)"},
+	"float32":       {Parse: "strconv.ParseFloat(%s, 32)", Convert: true},
+	"float64":       {FlagFunc: "Float64Var", Parse: "strconv.ParseFloat(%s, 64)"},
+	"time.Duration": {FlagFunc: "DurationVar", Parse: "time.ParseDuration(%s)"},
+}
 
-// generateMainContent will be filled in later.
-// For now, it returns a placeholder or minimal valid Go main function.
+// numericTypeOf returns the type name (without pointer) and the numericType of opt,
+// or false if opt is not of one of numericTypes.
+func numericTypeOf(opt *metadata.OptionMetadata) (string, numericType, bool) {
+	baseType := strings.TrimPrefix(opt.TypeName, "*")
+	nt, ok := numericTypes[baseType]
+	return baseType, nt, ok
+}
+
+// parsedValue returns the expression converting the parsed value v to baseType, if needed.
+func (nt numericType) parsedValue(baseType string, v string) string {
+	if nt.Convert {
+		return fmt.Sprintf("%s(%s)", baseType, v)
+	}
+	return v
+}
+
+// numericLiteral returns the Go expression of the value of baseType (one of numericTypes),
+// e.g. "float32(0.5)", or "time.Duration(5000000000)" for a default value of "5s".
+func numericLiteral(baseType string, value any) string {
+	if baseType == "time.Duration" {
+		if s, ok := value.(string); ok {
+			if d, err := time.ParseDuration(s); err == nil {
+				return fmt.Sprintf("time.Duration(%d)", int64(d))
+			}
+		}
+	}
+	if value == nil {
+		value = 0
+	}
+	return fmt.Sprintf("%s(%v)", baseType, value)
+}
+
+// sliceElemOf returns the element type of a slice option, or false if the elements cannot be parsed from strings
+// (the supported elements are string, int, numericTypes and types implementing encoding.TextUnmarshaler).
+func sliceElemOf(opt *metadata.OptionMetadata) (string, bool) {
+	if !strings.HasPrefix(opt.TypeName, "[]") {
+		return "", false
+	}
+	elemType := strings.TrimPrefix(opt.TypeName, "[]")
+	if _, ok := numericTypes[elemType]; ok || elemType == "string" || elemType == "int" || opt.ElemIsTextUnmarshaler {
+		return elemType, true
+	}
+	return "", false
+}
+
+// sliceItemParse returns the statements parsing the string `item` into `v` of elemType,
+// which run onError (e.g. "return err") if the item cannot be parsed.
+func sliceItemParse(opt *metadata.OptionMetadata, elemType string, onError string) string {
+	switch {
+	case elemType == "string":
+		return "v := item"
+	case elemType == "int":
+		return fmt.Sprintf("v, err := strconv.Atoi(item)\nif err != nil {\n%s\n}", onError)
+	case opt.ElemIsTextUnmarshaler:
+		return fmt.Sprintf("var v %s\nif err := v.UnmarshalText([]byte(item)); err != nil {\n%s\n}", elemType, onError)
+	}
+	nt := numericTypes[elemType]
+	if !nt.Convert {
+		return fmt.Sprintf("v, err := %s\nif err != nil {\n%s\n}", fmt.Sprintf(nt.Parse, "item"), onError)
+	}
+	return fmt.Sprintf("parsed, err := %s\nif err != nil {\n%s\n}\nv := %s", fmt.Sprintf(nt.Parse, "item"), onError, nt.parsedValue(elemType, "parsed"))
+}
+
+// mapValueTypeOf returns the value type of a map option, or false if the option is not one of
+// the supported maps (map[string]string and map[string]int, or a named type of them, but not a pointer to them).
+func mapValueTypeOf(opt *metadata.OptionMetadata) (string, bool) {
+	if opt.IsPointer {
+		return "", false
+	}
+	switch opt.UnderlyingKind {
+	case "map[string]string":
+		return "string", true
+	case "map[string]int":
+		return "int", true
+	}
+	return "", false
+}
+
+// mapItemParse returns the statements parsing the string `item` ("key=value") into `key` and `v` of valueType,
+// which run onError (e.g. "return err") if the item cannot be parsed.
+func mapItemParse(valueType string, onError string) string {
+	code := fmt.Sprintf("key, value, ok := strings.Cut(item, \"=\")\nif !ok {\nerr := fmt.Errorf(\"%%q is not in the key=value form\", item)\n%s\n}\n", onError)
+	if valueType == "int" {
+		return code + fmt.Sprintf("v, err := strconv.Atoi(value)\nif err != nil {\n%s\n}", onError)
+	}
+	return code + "v := value"
+}
+
+// compositeLiteral returns the Go expression of the default value of a slice or map option (e.g. `[]string{"a", "b"}`),
+// or false if the option has no such default value.
+func compositeLiteral(opt *metadata.OptionMetadata) (string, bool) {
+	if values, ok := opt.DefaultValue.([]any); ok {
+		elemType, ok := sliceElemOf(opt)
+		if !ok || opt.ElemIsTextUnmarshaler {
+			return "", false
+		}
+		items := make([]string, len(values))
+		for i, v := range values {
+			items[i] = elemLiteral(elemType, v)
+		}
+		return fmt.Sprintf("%s{%s}", opt.TypeName, strings.Join(items, ", ")), true
+	}
+	if values, ok := opt.DefaultValue.(map[string]any); ok {
+		valueType, ok := mapValueTypeOf(opt)
+		if !ok {
+			return "", false
+		}
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		items := make([]string, len(keys))
+		for i, key := range keys {
+			items[i] = fmt.Sprintf("%q: %s", key, elemLiteral(valueType, values[key]))
+		}
+		return fmt.Sprintf("%s{%s}", opt.TypeName, strings.Join(items, ", ")), true
+	}
+	return "", false
+}
+
+// elemLiteral returns the Go expression of an element v of a slice or map of typeName (string, int or one of numericTypes).
+func elemLiteral(typeName string, v any) string {
+	if _, ok := numericTypes[typeName]; ok {
+		return numericLiteral(typeName, v)
+	}
+	if s, ok := v.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// generateMainContent returns the source of a main() function for a single command.
 func generateMainContent(cmdMeta *metadata.CommandMetadata, helpText string) (string, error) {
 	var sb strings.Builder
 
@@ -89,577 +238,1756 @@ func main() {
 	}
 `, formatHelpText(helpText)))
 	}
+	if cmdMeta.DotEnv {
+		writeDotEnvLoading(&sb)
+	}
+	if cmdMeta.PrintConfig {
+		writeSourceTracking(&sb)
+	}
 
-	if cmdMeta.RunFunc.OptionsArgTypeNameStripped != "" {
-		// Initial declaration removed
+	var layers []*optionsLayer
+	if globalLayer := newGlobalOptionsLayer(cmdMeta.RunFunc, cmdMeta.GlobalOptions, cmdMeta.GlobalOptionGroups); globalLayer != nil {
+		layers = append(layers, globalLayer)
+	}
+	writeCommandBody(&sb, cmdMeta, layers, "flag", "flag.Parse()")
+	sb.WriteString("}\n")
+	return sb.String(), nil
+}
 
-		if cmdMeta.RunFunc.InitializerFunc != "" {
-			sb.WriteString(fmt.Sprintf(`
-	// 1. Create Options using the initializer function.
-	options := %s()
-`, cmdMeta.RunFunc.InitializerFunc))
-		} else {
-			sb.WriteString(fmt.Sprintf(`
-	// 1. Create Options with default values (no initializer function provided).
-	options := new(%s) // options is now a valid pointer to a zeroed struct
+// generateSubcommandsMainContent returns the source of a main() function that dispatches
+// on os.Args[1] to one of cmdMeta.Subcommands. Each subcommand gets its own flag.FlagSet,
+// and subcommandHelpTexts (keyed by subcommand name) are used as the per-subcommand usage.
+// If the subcommands share global options (or --env-file is accepted), they are parsed first with the top-level flag set,
+// and the subcommand is taken from the remaining arguments.
+func generateSubcommandsMainContent(cmdMeta *metadata.CommandMetadata, helpText string, subcommandHelpTexts map[string]string) (string, error) {
+	var sb strings.Builder
 
-	// The following block populates the fields of the options struct.
-	// This logic is only executed if no InitializerFunc is provided.
-`, cmdMeta.RunFunc.OptionsArgTypeNameStripped))
-			for _, opt := range cmdMeta.Options {
-				switch opt.TypeName {
-				case "string":
-					// If opt.DefaultValue is nil, this assignment is skipped.
-					// options.FieldName will retain its zero value ("") from new(OptionsType).
-					if opt.DefaultValue != nil {
-						valStr := fmt.Sprintf("%v", opt.DefaultValue)
-						defaultValueStr := `""` // Default for empty string after conversion
-						if valStr != "" {
-							defaultValueStr = fmt.Sprintf("%q", valStr)
-						}
-						sb.WriteString(fmt.Sprintf("	options.%s = %s\n", opt.Name, defaultValueStr))
-					}
-				case "int":
-					if opt.DefaultValue != nil {
-						defaultValueStr := "0" // Default to 0
-						if dvInt, ok := opt.DefaultValue.(int); ok {
-							defaultValueStr = fmt.Sprintf("%d", dvInt)
-						} else {
-							// Attempt to format non-int default value, though this path is less ideal
-							defaultValueStr = fmt.Sprintf("%v", opt.DefaultValue)
-						}
-						sb.WriteString(fmt.Sprintf("	options.%s = %s\n", opt.Name, defaultValueStr))
-					}
-				case "bool":
-					if opt.DefaultValue != nil {
-						defaultValueStr := "false" // Default to false
-						if dvBool, ok := opt.DefaultValue.(bool); ok {
-							defaultValueStr = fmt.Sprintf("%t", dvBool)
-						} else {
-							// Attempt to format non-bool default value
-							defaultValueStr = fmt.Sprintf("%v", opt.DefaultValue)
-						}
-						sb.WriteString(fmt.Sprintf("	options.%s = %s\n", opt.Name, defaultValueStr))
-					}
-				case "*string":
-					sb.WriteString(fmt.Sprintf("	options.%s = new(string)\n", opt.Name))
-					if opt.DefaultValue != nil {
-						if dvStr, ok := opt.DefaultValue.(string); ok {
-							sb.WriteString(fmt.Sprintf("	*options.%s = %q\n", opt.Name, dvStr))
-						}
-						// If opt.DefaultValue is not nil AND not a string, we skip assignment, relying on new(string).
-						// This avoids *options.X = <nil> if DefaultValue was, e.g., a nil pointer of another type.
-					}
-				case "*int":
-					sb.WriteString(fmt.Sprintf("	options.%s = new(int)\n", opt.Name))
-					if opt.DefaultValue != nil {
-						if dvInt, ok := opt.DefaultValue.(int); ok {
-							sb.WriteString(fmt.Sprintf("	*options.%s = %d\n", opt.Name, dvInt))
-						}
+	sb.WriteString(`// This main function was auto-generated by goat.
+func main() {
+	ctx := context.Background()
+`)
+	if helpText != "" {
+		sb.WriteString(fmt.Sprintf(`
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, %s)
+	}
+`, formatHelpText(helpText)))
+	}
+	if cmdMeta.DotEnv {
+		writeDotEnvLoading(&sb)
+	}
+	if cmdMeta.PrintConfig {
+		writeSourceTracking(&sb)
+	}
+
+	// Without global options, the subcommand is os.Args[1]; otherwise, it is the first non-flag argument.
+	lenCheck, subcommandArg, restArgs := "len(os.Args) < 2", "os.Args[1]", "os.Args[2:]"
+	if globalLayer := newGlobalOptionsLayer(cmdMeta.Subcommands[0].RunFunc, cmdMeta.GlobalOptions, cmdMeta.GlobalOptionGroups); globalLayer != nil {
+		lenCheck, subcommandArg, restArgs = "len(args) < 1", "args[0]", "args[1:]"
+
+		sb.WriteString(`	isFlagExplicitlySet := make(map[string]bool)
+`)
+		writeOptionsInitialization(&sb, globalLayer)
+		writeEnvOverrides(&sb, globalLayer)
+		sb.WriteString(`
+	// 3. Set global flags.
+`)
+		writeFlagRegistrations(&sb, globalLayer, "flag")
+		if cmdMeta.DotEnv {
+			writeDotEnvFlagRegistration(&sb, "flag")
+		}
+		if cmdMeta.PrintConfig {
+			writePrintConfigFlagRegistration(&sb, "flag")
+		}
+		sb.WriteString(`
+	// 4. Parse global flags.
+	flag.Parse()
+	flag.Visit(func(f *flag.Flag) { isFlagExplicitlySet[f.Name] = true })
+	args := flag.Args()
+`)
+		writeAliasNormalization(&sb, globalLayer)
+		writeNoFlagAssignments(&sb, globalLayer)
+		sb.WriteString(`
+
+	// 6. Assign values for initially nil pointers if flags were explicitly set
+`)
+		writePointerAssignments(&sb, globalLayer)
+		writeSecretFileReadings(&sb, globalLayer)
+		writeConfigFileLoading(&sb, []*optionsLayer{globalLayer}, cmdMeta.PrintConfig)
+		if cmdMeta.PrintConfig {
+			writeEffectiveOptions(&sb, globalLayer) // Printed with the options of the subcommand
+		}
+		sb.WriteString(`
+	// 5. Perform required checks (excluding booleans).
+`)
+		writeValidations(&sb, globalLayer)
+		writeGroupChecks(&sb, globalLayer)
+		writeValidateCall(&sb, globalLayer, "flag")
+		writeDirCreations(&sb, globalLayer)
+	} else if cmdMeta.DotEnv {
+		// --env-file is also accepted before the subcommand (e.g. myapp --env-file .env.local serve), like a global option.
+		lenCheck, subcommandArg, restArgs = "len(args) < 1", "args[0]", "args[1:]"
+
+		sb.WriteString(`
+	// 3. Set global flags.
+`)
+		writeDotEnvFlagRegistration(&sb, "flag")
+		sb.WriteString(`
+	// 4. Parse global flags.
+	flag.Parse()
+	args := flag.Args()
+`)
+	}
+
+	sb.WriteString(fmt.Sprintf(`
+	if %s {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	switch %s {
+	case "-h", "--help", "help":
+		flag.Usage()
+		os.Exit(0)
+`, lenCheck, subcommandArg))
+	for _, sub := range cmdMeta.Subcommands {
+		sb.WriteString(fmt.Sprintf(`	case %q:
+		isFlagExplicitlySet := make(map[string]bool)
+		fs := flag.NewFlagSet(%q, flag.ExitOnError)
+`, sub.Name, sub.Name))
+		if subHelpText := subcommandHelpTexts[sub.Name]; subHelpText != "" {
+			sb.WriteString(fmt.Sprintf(`		fs.Usage = func() {
+			fmt.Fprint(os.Stderr, %s)
+		}
+`, formatHelpText(subHelpText)))
+		}
+		// Global options are already parsed above, so only the subcommand's own options are handled here.
+		writeCommandBody(&sb, sub, nil, "fs", fmt.Sprintf("fs.Parse(%s)", restArgs))
+	}
+	sb.WriteString(fmt.Sprintf(`	default:
+		fmt.Fprintf(os.Stderr, "unknown command: %%q\n\n", %s)
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+`, subcommandArg))
+	return sb.String(), nil
+}
+
+// writeDotEnvLoading writes the loading of the .env file (step 0, `goat emit -dotenv`), before the environment variables are read.
+// The path is taken from --env-file, looked up in os.Args because the flags are parsed after the environment variables.
+// The variables already set in the environment are not overridden, and a missing .env file is ignored unless --env-file is given.
+func writeDotEnvLoading(sb *strings.Builder) {
+	sb.WriteString(`
+	// 0. Load the .env file (or the file given by --env-file) into the environment, without overriding it.
+	envFile, isEnvFileGiven := ".env", false
+	for i, arg := range os.Args[1:] {
+		if arg == "--" {
+			break
+		}
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		if name := strings.TrimLeft(arg, "-"); name == "env-file" && i+2 < len(os.Args) {
+			envFile, isEnvFileGiven = os.Args[i+2], true
+		} else if value, ok := strings.CutPrefix(name, "env-file="); ok {
+			envFile, isEnvFileGiven = value, true
+		}
+	}
+	loadDotEnv := func(path string) error {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		for i, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+			key, value, ok := strings.Cut(line, "=")
+			if !ok {
+				return fmt.Errorf("%s:%d: %q is not in the KEY=VALUE form", path, i+1, line)
+			}
+			key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+			if value != "" && (value[0] == '"' || value[0] == '\'') {
+				end := 1
+				for end < len(value) && value[end] != value[0] {
+					if value[0] == '"' && value[end] == '\\' {
+						end++ // Skip the escaped character
 					}
-				case "*bool":
-					sb.WriteString(fmt.Sprintf("	options.%s = new(bool)\n", opt.Name))
-					if opt.DefaultValue != nil {
-						if dvBool, ok := opt.DefaultValue.(bool); ok {
-							sb.WriteString(fmt.Sprintf("	*options.%s = %t\n", opt.Name, dvBool))
-						}
+					end++
+				}
+				if end >= len(value) {
+					return fmt.Errorf("%s:%d: unterminated quoted value of %s", path, i+1, key)
+				}
+				if value[0] == '"' {
+					if value, err = strconv.Unquote(value[:end+1]); err != nil {
+						return fmt.Errorf("%s:%d: invalid quoted value of %s: %w", path, i+1, key, err)
 					}
+				} else {
+					value = value[1:end] // Single-quoted values are taken literally
 				}
+			} else if j := strings.Index(value, " #"); j >= 0 {
+				value = strings.TrimSpace(value[:j]) // Inline comment
+			}
+			if _, exists := os.LookupEnv(key); !exists {
+				os.Setenv(key, value)
 			}
 		}
+		return nil
+	}
+	if err := loadDotEnv(envFile); err != nil && (isEnvFileGiven || !os.IsNotExist(err)) {
+		slog.ErrorContext(ctx, "Could not load the env file", "error", err, "path", envFile)
+		os.Exit(1)
+	}
+`)
+}
+
+// writeDotEnvFlagRegistration writes the registration of --env-file to the flag set fs (step 3, `goat emit -dotenv`).
+// The file is already loaded by writeDotEnvLoading, so the flag is only registered to be accepted.
+func writeDotEnvFlagRegistration(sb *strings.Builder, fs string) {
+	sb.WriteString(fmt.Sprintf("	%s.StringVar(&envFile, \"env-file\", envFile, \"Load environment variables from the file\")\n", fs))
+}
+
+// writeSourceTracking writes the declarations recording the sources of the option values for --print-config (`goat emit -print-config`).
+// The effective options are added by writeEffectiveOptions after the values are settled, and printed by writePrintConfig.
+func writeSourceTracking(sb *strings.Builder) {
+	sb.WriteString(`
+	// Sources of the option values, printed by --print-config.
+	type effectiveOption struct {
+		Name   string ` + "`json:\"name\"`" + `
+		Value  any    ` + "`json:\"value\"`" + `
+		Source string ` + "`json:\"source\"`" + `           // "default", "initializer", "env", "config" or "flag"
+		Origin string ` + "`json:\"origin,omitempty\"`" + ` // The environment variable or the config file
+	}
+	var effectiveOptions []effectiveOption
+	printConfigFormat := ""
+	configSources := make(map[string]string) // Flag names of the options set by the config file, to its path
+	addEffectiveOption := func(name string, value any, isFlagSet bool, initialSource string, envVars ...string) {
+		option := effectiveOption{Name: name, Value: value, Source: initialSource}
+		if path, ok := configSources[name]; ok {
+			option.Source, option.Origin = "config", path
+		} else if isFlagSet {
+			option.Source = "flag"
+		} else {
+			for _, envVar := range envVars {
+				if _, ok := os.LookupEnv(envVar); ok {
+					option.Source, option.Origin = "env", envVar
+					break
+				}
+			}
+		}
+		effectiveOptions = append(effectiveOptions, option)
+	}
+`)
+}
+
+// writePrintConfigFlagRegistration writes the registration of --print-config to the flag set fs (step 3, `goat emit -print-config`).
+// The flag is a boolean flag also accepting the format, e.g. --print-config=json.
+func writePrintConfigFlagRegistration(sb *strings.Builder, fs string) {
+	sb.WriteString(fmt.Sprintf(`	%s.BoolFunc("print-config", "Print the effective options and their sources (text or json), and exit", func(format string) error {
+		switch format {
+		case "true", "text":
+			printConfigFormat = "text"
+		case "json":
+			printConfigFormat = "json"
+		case "false":
+			printConfigFormat = ""
+		default:
+			return fmt.Errorf("unknown format %%q, must be text or json", format)
+		}
+		return nil
+	})
+`, fs))
+}
 
-		// Environment variable processing
+// writeEffectiveOptions writes the recording of the effective options of the layer and their sources (after the config file is loaded).
+// Positional arguments are not recorded, and the values of secret options are redacted.
+func writeEffectiveOptions(sb *strings.Builder, l *optionsLayer) {
+	initialSource := "default"
+	if l.InitializerFunc != "" {
+		initialSource = "initializer"
+	}
+	sb.WriteString("\n")
+	for _, opt := range l.Options {
+		if opt.Positional != nil {
+			continue
+		}
+		ref := l.ref(opt)
+		kebabCaseName := cliNameOf(opt)
+		isFlagSet := fmt.Sprintf("isFlagExplicitlySet[%q]", kebabCaseName)
+		if opt.TypeName == "bool" && opt.IsRequired && fmt.Sprintf("%v", opt.DefaultValue) == "true" {
+			isFlagSet += fmt.Sprintf(" || isFlagExplicitlySet[%q]", "no-"+kebabCaseName)
+		}
+		isDuration := strings.TrimPrefix(opt.TypeName, "*") == "time.Duration"
+		value := ref
+		if opt.IsPointer {
+			value = "*" + ref
+		}
+		if isDuration {
+			value = "(" + value + ").String()" // Instead of the nanoseconds in JSON
+		}
+		args := fmt.Sprintf("%q, %%s, %s, %q", kebabCaseName, isFlagSet, initialSource)
+		for _, envVar := range []string{opt.EnvVar, opt.SecretFileEnvVar()} {
+			if envVar != "" && (envVar == opt.EnvVar || opt.IsSecret) {
+				args += fmt.Sprintf(", %q", envVar)
+			}
+		}
+		if opt.IsSecret { // Only whether the value is empty is shown
+			nilGuard := ""
+			if opt.IsPointer {
+				nilGuard = ref + " != nil && "
+			}
+			sb.WriteString(fmt.Sprintf(`	if %s%s != "" {
+		addEffectiveOption(%s)
+	} else {
+		addEffectiveOption(%s)
+	}
+`, nilGuard, value, fmt.Sprintf(args, strconv.Quote(metadata.RedactedValue)), fmt.Sprintf(args, `""`)))
+		} else if opt.IsPointer {
+			sb.WriteString(fmt.Sprintf(`	if %s != nil {
+		addEffectiveOption(%s)
+	} else {
+		addEffectiveOption(%s)
+	}
+`, ref, fmt.Sprintf(args, value), fmt.Sprintf(args, "nil")))
+		} else {
+			sb.WriteString(fmt.Sprintf("	addEffectiveOption(%s)\n", fmt.Sprintf(args, value)))
+		}
+	}
+}
+
+// writePrintConfig writes the printing of the effective options for --print-config, before the required checks,
+// so that the missing options are shown too.
+func writePrintConfig(sb *strings.Builder) {
+	sb.WriteString(`
+	if printConfigFormat == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(effectiveOptions); err != nil {
+			slog.ErrorContext(ctx, "Could not print the effective options", "error", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	} else if printConfigFormat != "" {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, option := range effectiveOptions {
+			value := fmt.Sprint(option.Value)
+			if s, ok := option.Value.(string); ok {
+				value = strconv.Quote(s)
+			}
+			source := option.Source
+			if option.Origin != "" {
+				source += " (" + option.Origin + ")"
+			}
+			fmt.Fprintf(w, "--%s\t%s\t%s\n", option.Name, value, source)
+		}
+		w.Flush()
+		os.Exit(0)
+	}
+`)
+}
+
+// optionsLayer is a set of options bound to a single variable of the generated main(),
+// e.g. the options of the run function ("options") or the shared global options ("globalOptions").
+type optionsLayer struct {
+	VarName         string // Name of the variable holding the options struct (e.g. "options")
+	IdentPrefix     string // Prefix of the generated local identifiers, to avoid collisions between layers (e.g. "Global")
+	Label           string // Label used in the generated comments (e.g. "Options")
+	TypeName        string // Options struct type name without pointer (e.g. "Options")
+	InitializerFunc string // Name of the initializer function, if any (e.g. "NewOptions")
+	Options         []*metadata.OptionMetadata
+	Groups          []*metadata.OptionGroup // Constraints on the options (goat.OneOf, goat.Together)
+	HasValidate     bool                    // True if the options struct has a Validate() error method
+}
+
+// ref returns the expression to access the field of opt (e.g. "options.Name").
+func (l *optionsLayer) ref(opt *metadata.OptionMetadata) string {
+	return l.VarName + "." + opt.Name
+}
+
+// ident returns the name used for the local identifiers generated for opt (e.g. "isNameNilInitially").
+// The field path of a nested struct option is joined (e.g. "DBHost" for "DB.Host").
+func (l *optionsLayer) ident(opt *metadata.OptionMetadata) string {
+	return l.IdentPrefix + strings.ReplaceAll(opt.Name, ".", "")
+}
+
+// newOptionsLayer returns the layer of the run function's own options.
+func newOptionsLayer(cmdMeta *metadata.CommandMetadata) *optionsLayer {
+	return &optionsLayer{
+		VarName:         "options",
+		Label:           "Options",
+		TypeName:        cmdMeta.RunFunc.OptionsArgTypeNameStripped,
+		InitializerFunc: cmdMeta.RunFunc.InitializerFunc,
+		Options:         cmdMeta.Options,
+		Groups:          cmdMeta.OptionGroups,
+		HasValidate:     cmdMeta.RunFunc.OptionsHasValidate,
+	}
+}
+
+// newGlobalOptionsLayer returns the layer of the shared global options, or nil if the run function has none.
+func newGlobalOptionsLayer(runFunc *metadata.RunFuncInfo, options []*metadata.OptionMetadata, groups []*metadata.OptionGroup) *optionsLayer {
+	if runFunc == nil || runFunc.GlobalOptionsArgTypeNameStripped == "" {
+		return nil
+	}
+	return &optionsLayer{
+		VarName:         "globalOptions",
+		IdentPrefix:     "Global",
+		Label:           "GlobalOptions",
+		TypeName:        runFunc.GlobalOptionsArgTypeNameStripped,
+		InitializerFunc: runFunc.GlobalInitializerFunc,
+		Options:         options,
+		Groups:          groups,
+		HasValidate:     runFunc.GlobalOptionsHasValidate,
+	}
+}
+
+// cliNameOf returns the flag name of opt, falling back to the kebab-case of its field name.
+func cliNameOf(opt *metadata.OptionMetadata) string {
+	if opt.CliName != "" {
+		return opt.CliName
+	}
+	return stringutils.ToKebabCase(opt.Name)
+}
+
+// writeCommandBody writes the statements that build the options of a single command,
+// parse its flags and call its run function.
+// extraLayers are parsed together with the command's own options (e.g. global options).
+// fs is the expression used to register flags (e.g. "flag" or a *flag.FlagSet variable),
+// and parseCall is the statement that parses the command line.
+func writeCommandBody(sb *strings.Builder, cmdMeta *metadata.CommandMetadata, extraLayers []*optionsLayer, fs string, parseCall string) {
+	layers := extraLayers[:len(extraLayers):len(extraLayers)]
+	if cmdMeta.RunFunc.OptionsArgTypeNameStripped != "" {
+		layers = append(layers, newOptionsLayer(cmdMeta))
+	}
+	if len(layers) > 0 {
+		for _, l := range layers {
+			writeOptionsInitialization(sb, l)
+		}
+		for _, l := range layers {
+			writeEnvOverrides(sb, l)
+		}
+
+		// Flag setup
 		sb.WriteString(`
-	// 2. Override with environment variable values.
-	// This section assumes 'options' is already initialized.
+	// 3. Set flags.
 `)
-		for _, opt := range cmdMeta.Options {
-			if opt.EnvVar == "" {
-				continue
+		for _, l := range layers {
+			writeFlagRegistrations(sb, l, fs)
+		}
+		if cmdMeta.DotEnv {
+			writeDotEnvFlagRegistration(sb, fs)
+		}
+		if cmdMeta.PrintConfig {
+			writePrintConfigFlagRegistration(sb, fs)
+		}
+
+		sb.WriteString(fmt.Sprintf(`
+	// 4. Parse.
+	%s
+	%s.Visit(func(f *flag.Flag) { isFlagExplicitlySet[f.Name] = true })
+`, parseCall, fs))
+		for _, l := range layers {
+			writeAliasNormalization(sb, l)
+		}
+		for _, l := range layers {
+			writeNoFlagAssignments(sb, l)
+		}
+		for _, l := range layers {
+			writePositionalArgs(sb, l, fs)
+		}
+
+		sb.WriteString(`
+
+	// 6. Assign values for initially nil pointers if flags were explicitly set
+`)
+		for _, l := range layers {
+			writePointerAssignments(sb, l)
+		}
+		for _, l := range layers {
+			writeSecretFileReadings(sb, l)
+		}
+		writeConfigFileLoading(sb, layers, cmdMeta.PrintConfig)
+		if cmdMeta.PrintConfig {
+			for _, l := range layers {
+				writeEffectiveOptions(sb, l)
 			}
-			sb.WriteString(fmt.Sprintf(`
+			writePrintConfig(sb)
+		}
+
+		sb.WriteString(`
+	// 5. Perform required checks (excluding booleans).
+`)
+		for _, l := range layers {
+			writeValidations(sb, l)
+		}
+		for _, l := range layers {
+			writeGroupChecks(sb, l)
+		}
+		for _, l := range layers {
+			writeValidateCall(sb, l, fs)
+		}
+		for _, l := range layers {
+			writeDirCreations(sb, l)
+		}
+	}
+
+	writeRunFuncCall(sb, cmdMeta.RunFunc)
+}
+
+// writeOptionsInitialization writes the creation of the options struct of the layer (step 1).
+func writeOptionsInitialization(sb *strings.Builder, l *optionsLayer) {
+	if l.InitializerFunc != "" {
+		sb.WriteString(fmt.Sprintf(`
+	// 1. Create %s using the initializer function.
+	%s := %s()
+`, l.Label, l.VarName, l.InitializerFunc))
+		// The defaults of the `default` tags of the fields that the initializer does not set.
+		for _, opt := range l.Options {
+			if opt.DefaultFromTag && opt.DefaultValue != nil {
+				writeDefaultAssignment(sb, l, opt)
+			}
+		}
+		return
+	}
+
+	sb.WriteString(fmt.Sprintf(`
+	// 1. Create %s with default values (no initializer function provided).
+	%s := new(%s) // %s is now a valid pointer to a zeroed struct
+
+	// The following block populates the fields of the options struct.
+	// This logic is only executed if no InitializerFunc is provided.
+`, l.Label, l.VarName, l.TypeName, l.VarName))
+	for _, opt := range l.Options {
+		writeDefaultAssignment(sb, l, opt)
+	}
+}
+
+// writeDefaultAssignment writes the assignment of the default value of opt to the field of the layer
+// (and the allocation of a pointer field), if any.
+func writeDefaultAssignment(sb *strings.Builder, l *optionsLayer, opt *metadata.OptionMetadata) {
+	ref := l.ref(opt)
+	switch opt.TypeName {
+	case "string":
+		// If opt.DefaultValue is nil, this assignment is skipped.
+		// options.FieldName will retain its zero value ("") from new(OptionsType).
+		if opt.DefaultValue != nil {
+			valStr := fmt.Sprintf("%v", opt.DefaultValue)
+			defaultValueStr := `""` // Default for empty string after conversion
+			if valStr != "" {
+				defaultValueStr = fmt.Sprintf("%q", valStr)
+			}
+			sb.WriteString(fmt.Sprintf("	%s = %s\n", ref, defaultValueStr))
+		}
+	case "int":
+		if opt.DefaultValue != nil {
+			defaultValueStr := "0" // Default to 0
+			if dvInt, ok := opt.DefaultValue.(int); ok {
+				defaultValueStr = fmt.Sprintf("%d", dvInt)
+			} else {
+				// Attempt to format non-int default value, though this path is less ideal
+				defaultValueStr = fmt.Sprintf("%v", opt.DefaultValue)
+			}
+			sb.WriteString(fmt.Sprintf("	%s = %s\n", ref, defaultValueStr))
+		}
+	case "bool":
+		if opt.DefaultValue != nil {
+			defaultValueStr := "false" // Default to false
+			if dvBool, ok := opt.DefaultValue.(bool); ok {
+				defaultValueStr = fmt.Sprintf("%t", dvBool)
+			} else {
+				// Attempt to format non-bool default value
+				defaultValueStr = fmt.Sprintf("%v", opt.DefaultValue)
+			}
+			sb.WriteString(fmt.Sprintf("	%s = %s\n", ref, defaultValueStr))
+		}
+	case "*string":
+		if opt.Positional != nil && opt.DefaultValue == nil {
+			break // An optional positional argument stays nil unless given.
+		}
+		sb.WriteString(fmt.Sprintf("	%s = new(string)\n", ref))
+		if opt.DefaultValue != nil {
+			if dvStr, ok := opt.DefaultValue.(string); ok {
+				sb.WriteString(fmt.Sprintf("	*%s = %q\n", ref, dvStr))
+			}
+			// If opt.DefaultValue is not nil AND not a string, we skip assignment, relying on new(string).
+			// This avoids *options.X = <nil> if DefaultValue was, e.g., a nil pointer of another type.
+		}
+	case "*int":
+		sb.WriteString(fmt.Sprintf("	%s = new(int)\n", ref))
+		if opt.DefaultValue != nil {
+			if dvInt, ok := opt.DefaultValue.(int); ok {
+				sb.WriteString(fmt.Sprintf("	*%s = %d\n", ref, dvInt))
+			}
+		}
+	case "*bool":
+		sb.WriteString(fmt.Sprintf("	%s = new(bool)\n", ref))
+		if opt.DefaultValue != nil {
+			if dvBool, ok := opt.DefaultValue.(bool); ok {
+				sb.WriteString(fmt.Sprintf("	*%s = %t\n", ref, dvBool))
+			}
+		}
+	default:
+		if opt.Positional != nil {
+			break
+		}
+		if literal, ok := compositeLiteral(opt); ok {
+			sb.WriteString(fmt.Sprintf("	%s = %s\n", ref, literal))
+			break
+		}
+		if s, ok := opt.DefaultValue.(string); ok && !opt.IsPointer {
+			if opt.IsTextUnmarshaler {
+				sb.WriteString(fmt.Sprintf(`	if err := (&%s).UnmarshalText([]byte(%q)); err != nil {
+	slog.ErrorContext(ctx, "Invalid default value for option", "error", err, "option", %q)
+	os.Exit(1)
+}
+`, ref, s, opt.Name))
+				break
+			}
+			if opt.UnderlyingKind == "string" {
+				sb.WriteString(fmt.Sprintf("	%s = %s(%q)\n", ref, opt.TypeName, s))
+				break
+			}
+		}
+		baseType, _, ok := numericTypeOf(opt)
+		if !ok {
+			break
+		}
+		if opt.IsPointer {
+			sb.WriteString(fmt.Sprintf("	%s = new(%s)\n", ref, baseType))
+			if opt.DefaultValue != nil {
+				sb.WriteString(fmt.Sprintf("	*%s = %s\n", ref, numericLiteral(baseType, opt.DefaultValue)))
+			}
+		} else if opt.DefaultValue != nil {
+			sb.WriteString(fmt.Sprintf("	%s = %s\n", ref, numericLiteral(baseType, opt.DefaultValue)))
+		}
+	}
+}
+
+// SupportsEnvVar reports whether the generated main() can read the value of opt from an environment variable.
+// Options of other types (e.g. a pointer to a struct) are not read from their environment variables.
+func SupportsEnvVar(opt *metadata.OptionMetadata) bool {
+	if opt.IsTextUnmarshaler || opt.UnderlyingKind == "string" {
+		return true
+	}
+	switch opt.TypeName {
+	case "string", "int", "bool", "*string", "*int", "*bool", "[]string":
+		return true
+	}
+	if _, ok := mapValueTypeOf(opt); ok {
+		return true
+	}
+	if _, ok := sliceElemOf(opt); ok {
+		return true
+	}
+	_, _, ok := numericTypeOf(opt)
+	return ok
+}
+
+// writeEnvOverrides writes the overrides from environment variables (step 2).
+func writeEnvOverrides(sb *strings.Builder, l *optionsLayer) {
+	sb.WriteString(fmt.Sprintf(`
+	// 2. Override with environment variable values.
+	// This section assumes '%s' is already initialized.
+`, l.VarName))
+	for _, opt := range l.Options {
+		if opt.EnvVar == "" || opt.Positional != nil || !SupportsEnvVar(opt) {
+			continue
+		}
+		ref := l.ref(opt)
+		sb.WriteString(fmt.Sprintf(`
 	if val, ok := os.LookupEnv(%q); ok {
 `, opt.EnvVar))
-			if opt.IsTextUnmarshaler {
-				if opt.IsPointer {
-					sb.WriteString(fmt.Sprintf(`
-		if options.%s == nil {
-			options.%s = new(%s)
+		if opt.IsTextUnmarshaler {
+			if opt.IsPointer {
+				sb.WriteString(fmt.Sprintf(`
+		if %s == nil {
+			%s = new(%s)
 		}
-		err := options.%s.UnmarshalText([]byte(val))
+		err := %s.UnmarshalText([]byte(val))
 		if err != nil {
 			slog.WarnContext(ctx, "Could not parse environment variable for TextUnmarshaler option; using default or previously set value.", "envVar", %q, "option", %q, "value", val, "error", err)
 		}
-`, opt.Name, opt.Name, strings.TrimPrefix(opt.TypeName, "*"), opt.Name, opt.EnvVar, opt.CliName))
-				} else {
-					sb.WriteString(fmt.Sprintf(`
-		err := (&options.%s).UnmarshalText([]byte(val))
+`, ref, ref, strings.TrimPrefix(opt.TypeName, "*"), ref, opt.EnvVar, opt.CliName))
+			} else {
+				sb.WriteString(fmt.Sprintf(`
+		err := (&%s).UnmarshalText([]byte(val))
 		if err != nil {
 			slog.WarnContext(ctx, "Could not parse environment variable for TextUnmarshaler option; using default or previously set value.", "envVar", %q, "option", %q, "value", val, "error", err)
 		}
-`, opt.Name, opt.EnvVar, opt.CliName))
-				}
-			} else if opt.IsPointer && opt.UnderlyingKind == "string" {
-				sb.WriteString(fmt.Sprintf(`
+`, ref, opt.EnvVar, opt.CliName))
+			}
+		} else if opt.IsPointer && opt.UnderlyingKind == "string" {
+			sb.WriteString(fmt.Sprintf(`
 		typedVal := %s(val)
-		options.%s = &typedVal
-`, strings.TrimPrefix(opt.TypeName, "*"), opt.Name))
-			} else if opt.UnderlyingKind == "string" {
+		%s = &typedVal
+`, strings.TrimPrefix(opt.TypeName, "*"), ref))
+		} else if opt.UnderlyingKind == "string" {
+			sb.WriteString(fmt.Sprintf(`
+		%s = %s(val)
+`, ref, opt.TypeName))
+		} else {
+			switch opt.TypeName {
+			case "string":
+				sb.WriteString(fmt.Sprintf("		%s = val\n", ref))
+			case "int":
 				sb.WriteString(fmt.Sprintf(`
-		options.%s = %s(val)
-`, opt.Name, opt.TypeName))
-			} else {
-				switch opt.TypeName {
-				case "string":
-					sb.WriteString(fmt.Sprintf("		options.%s = val\n", opt.Name))
-				case "int":
-					sb.WriteString(fmt.Sprintf(`
 		if v, err := strconv.Atoi(val); err == nil {
-			options.%s = v
+			%s = v
 		} else {
 			slog.WarnContext(ctx, "Could not parse environment variable as int for option", "envVar", %q, "option", %q, "value", val, "error", err)
 		}
-`, opt.Name, opt.EnvVar, opt.Name))
-				case "bool":
-					sb.WriteString(fmt.Sprintf(`
+`, ref, opt.EnvVar, opt.Name))
+			case "bool":
+				sb.WriteString(fmt.Sprintf(`
 		if v, err := strconv.ParseBool(val); err == nil {
-			options.%s = v
+			%s = v
 		} else {
 			slog.WarnContext(ctx, "Could not parse environment variable as bool for option", "envVar", %q, "option", %q, "value", val, "error", err)
 		}
-`, opt.Name, opt.EnvVar, opt.Name))
-				case "*string":
-					sb.WriteString(fmt.Sprintf(`
-		if options.%s == nil { options.%s = new(string) }
-		*options.%s = val
-`, opt.Name, opt.Name, opt.Name))
-				case "*int":
-					sb.WriteString(fmt.Sprintf(`
-		if options.%s == nil { options.%s = new(int) }
+`, ref, opt.EnvVar, opt.Name))
+			case "*string":
+				sb.WriteString(fmt.Sprintf(`
+		if %s == nil { %s = new(string) }
+		*%s = val
+`, ref, ref, ref))
+			case "*int":
+				sb.WriteString(fmt.Sprintf(`
+		if %s == nil { %s = new(int) }
 		if v, err := strconv.Atoi(val); err == nil {
-			*options.%s = v
+			*%s = v
 		} else {
 			slog.WarnContext(ctx, "Could not parse environment variable as *int for option", "envVar", %q, "option", %q, "value", val, "error", err)
 		}
-`, opt.Name, opt.Name, opt.Name, opt.EnvVar, opt.Name))
-				case "*bool":
-					sb.WriteString(fmt.Sprintf(`
-		if options.%s == nil { options.%s = new(bool) }
-		if v, err := strconv.ParseBool(val); err == nil {
-			*options.%s = v
-		} else {
-			slog.WarnContext(ctx, "Could not parse environment variable as *bool for option", "envVar", %q, "option", %q, "value", val, "error", err)
+`, ref, ref, ref, opt.EnvVar, opt.Name))
+			case "*bool":
+				sb.WriteString(fmt.Sprintf(`
+		if %s == nil { %s = new(bool) }
+		if v, err := strconv.ParseBool(val); err == nil {
+			*%s = v
+		} else {
+			slog.WarnContext(ctx, "Could not parse environment variable as *bool for option", "envVar", %q, "option", %q, "value", val, "error", err)
+		}
+`, ref, ref, ref, opt.EnvVar, opt.Name))
+			case "[]string":
+				sb.WriteString(fmt.Sprintf("		%s = strings.Split(val, \",\")\n", ref))
+			default:
+				if valueType, ok := mapValueTypeOf(opt); ok {
+					// Like slices, the value is assigned only if all the comma-separated items can be parsed.
+					sb.WriteString(fmt.Sprintf(`		parsed%s := make(%s)
+		var parseErr error
+		for _, item := range strings.Split(val, ",") {
+			%s
+			parsed%s[key] = v
+		}
+		if parseErr == nil {
+			%s = parsed%s
+		} else {
+			slog.WarnContext(ctx, "Could not parse environment variable as %s for option", "envVar", %q, "option", %q, "value", val, "error", parseErr)
+		}
+`, l.ident(opt), opt.TypeName, mapItemParse(valueType, "parseErr = err\nbreak"), l.ident(opt), ref, l.ident(opt), opt.TypeName, opt.EnvVar, opt.Name))
+					break
+				}
+				if elemType, ok := sliceElemOf(opt); ok {
+					// The value is assigned only if all the comma-separated items can be parsed.
+					sb.WriteString(fmt.Sprintf(`		var parsed%s %s
+		var parseErr error
+		for _, item := range strings.Split(val, ",") {
+			%s
+			parsed%s = append(parsed%s, v)
+		}
+		if parseErr == nil {
+			%s = parsed%s
+		} else {
+			slog.WarnContext(ctx, "Could not parse environment variable as %s for option", "envVar", %q, "option", %q, "value", val, "error", parseErr)
+		}
+`, l.ident(opt), opt.TypeName, sliceItemParse(opt, elemType, "parseErr = err\nbreak"), l.ident(opt), l.ident(opt), ref, l.ident(opt), opt.TypeName, opt.EnvVar, opt.Name))
+					break
+				}
+				if baseType, nt, ok := numericTypeOf(opt); ok {
+					target := ref
+					if opt.IsPointer {
+						sb.WriteString(fmt.Sprintf("		if %s == nil { %s = new(%s) }\n", ref, ref, baseType))
+						target = "*" + ref
+					}
+					sb.WriteString(fmt.Sprintf(`		if v, err := %s; err == nil {
+			%s = %s
+		} else {
+			slog.WarnContext(ctx, "Could not parse environment variable as %s for option", "envVar", %q, "option", %q, "value", val, "error", err)
+		}
+`, fmt.Sprintf(nt.Parse, "val"), target, nt.parsedValue(baseType, "v"), opt.TypeName, opt.EnvVar, opt.Name))
+				}
+			}
+		}
+		if opt.IsSecret && opt.EnvVar != "" {
+			sb.WriteString(fmt.Sprintf(`	} else if path, ok := os.LookupEnv(%q); ok {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			slog.ErrorContext(ctx, "Could not read the secret file", "error", err, "envVar", %q, "option", %q)
+			os.Exit(1)
+		}
+%s	}
+`, opt.SecretFileEnvVar(), opt.SecretFileEnvVar(), opt.Name, secretFileAssignment(opt, ref)))
+		} else {
+			sb.WriteString("	}\n") // close if val, ok
+		}
+	}
+}
+
+// secretFileAssignment returns the statements assigning the content of a secret file (in data) to the secret option opt,
+// without the trailing newline.
+func secretFileAssignment(opt *metadata.OptionMetadata, ref string) string {
+	if opt.IsPointer {
+		return fmt.Sprintf("		value := strings.TrimRight(string(data), \"\\r\\n\")\n		%s = &value\n", ref)
+	}
+	return fmt.Sprintf("		%s = strings.TrimRight(string(data), \"\\r\\n\")\n", ref)
+}
+
+// writeFlagRegistrations writes the flag registrations of the layer (step 3).
+// fs is the expression used to register flags (e.g. "flag" or a *flag.FlagSet variable).
+func writeFlagRegistrations(sb *strings.Builder, l *optionsLayer, fs string) {
+	for _, opt := range l.Options {
+		if opt.Positional != nil {
+			continue // Assigned from the positional arguments, after parsing.
+		}
+		ref := l.ref(opt)
+		id := l.ident(opt)
+		kebabCaseName := cliNameOf(opt)
+		helpComment := ""
+		if opt.DefaultValue != nil && opt.IsSecret {
+			helpComment = fmt.Sprintf("/* Original Default: %s, Env: %s */", metadata.RedactedValue, opt.EnvVar)
+		} else if opt.DefaultValue != nil {
+			// If there's a default value, always include "Original Default:"
+			// and include "Env:" part, even if EnvVar is empty.
+			helpComment = fmt.Sprintf("/* Original Default: %v, Env: %s */", opt.DefaultValue, opt.EnvVar)
+		} else if opt.EnvVar != "" {
+			// Only EnvVar is present
+			helpComment = fmt.Sprintf("/* Env: %s */", opt.EnvVar)
+		}
+		// If neither DefaultValue nor EnvVar is present, helpComment remains ""
+
+		registeredName := kebabCaseName // Name of the registered flag, or "" if the type has no flag support
+		switch opt.TypeName {
+		case "string":
+			sb.WriteString(fmt.Sprintf("	%s.StringVar(&%s, %q, %s, %s %s)\n", fs, ref, kebabCaseName, ref, formatHelpText(opt.HelpText), helpComment))
+		case "int":
+			sb.WriteString(fmt.Sprintf("	%s.IntVar(&%s, %q, %s, %s %s)\n", fs, ref, kebabCaseName, ref, formatHelpText(opt.HelpText), helpComment))
+		case "bool":
+			if opt.IsRequired && fmt.Sprintf("%v", opt.DefaultValue) == "true" {
+				registeredName = "no-" + kebabCaseName
+				sb.WriteString(fmt.Sprintf("	var %s_NoFlagIsPresent bool\n", id))
+				sb.WriteString(fmt.Sprintf("	%s.BoolVar(&%s_NoFlagIsPresent, \"no-%s\", false, %q)\n", fs, id, kebabCaseName, "Set "+kebabCaseName+" to false"))
+			} else {
+				sb.WriteString(fmt.Sprintf("	%s.BoolVar(&%s, %q, %s, %s %s)\n", fs, ref, kebabCaseName, ref, formatHelpText(opt.HelpText), helpComment))
+			}
+		case "*string":
+			sb.WriteString(fmt.Sprintf("	is%sNilInitially := %s == nil\n", id, ref))
+			sb.WriteString(fmt.Sprintf("	var temp%sVal %s\n", id, strings.TrimPrefix(opt.TypeName, "*")))
+			sb.WriteString(fmt.Sprintf("	var default%sValForFlag string\n", id))
+			sb.WriteString(fmt.Sprintf("	if %s != nil { default%sValForFlag = *%s }\n", ref, id, ref))
+			sb.WriteString(fmt.Sprintf("	if is%sNilInitially {\n", id))
+			sb.WriteString(fmt.Sprintf("		%s.StringVar(&temp%sVal, %q, \"\", %s %s)\n", fs, id, kebabCaseName, formatHelpText(opt.HelpText), helpComment))
+			sb.WriteString("	} else {\n")
+			sb.WriteString(fmt.Sprintf("		%s.StringVar(%s, %q, default%sValForFlag, %s %s)\n", fs, ref, kebabCaseName, id, formatHelpText(opt.HelpText), helpComment))
+			sb.WriteString("	}\n")
+		case "*int":
+			sb.WriteString(fmt.Sprintf("	is%sNilInitially := %s == nil\n", id, ref))
+			sb.WriteString(fmt.Sprintf("	var temp%sVal %s\n", id, strings.TrimPrefix(opt.TypeName, "*")))
+			sb.WriteString(fmt.Sprintf("	var default%sValForFlag int\n", id))
+			sb.WriteString(fmt.Sprintf("	if %s != nil { default%sValForFlag = *%s }\n", ref, id, ref))
+			sb.WriteString(fmt.Sprintf("	if is%sNilInitially {\n", id))
+			sb.WriteString(fmt.Sprintf("		%s.IntVar(&temp%sVal, %q, 0, %s %s)\n", fs, id, kebabCaseName, formatHelpText(opt.HelpText), helpComment))
+			sb.WriteString("	} else {\n")
+			sb.WriteString(fmt.Sprintf("		%s.IntVar(%s, %q, default%sValForFlag, %s %s)\n", fs, ref, kebabCaseName, id, formatHelpText(opt.HelpText), helpComment))
+			sb.WriteString("	}\n")
+		case "*bool":
+			sb.WriteString(fmt.Sprintf("	is%sNilInitially := %s == nil\n", id, ref))
+			sb.WriteString(fmt.Sprintf("	var temp%sVal %s\n", id, strings.TrimPrefix(opt.TypeName, "*")))
+			sb.WriteString(fmt.Sprintf("	var default%sValForFlag bool\n", id))
+			sb.WriteString(fmt.Sprintf("	if %s != nil { default%sValForFlag = *%s }\n", ref, id, ref))
+			sb.WriteString(fmt.Sprintf("	if is%sNilInitially {\n", id))
+			sb.WriteString(fmt.Sprintf("		%s.BoolVar(&temp%sVal, %q, false, %s %s)\n", fs, id, kebabCaseName, formatHelpText(opt.HelpText), helpComment))
+			sb.WriteString("	} else {\n")
+			sb.WriteString(fmt.Sprintf("		%s.BoolVar(%s, %q, default%sValForFlag, %s %s)\n", fs, ref, kebabCaseName, id, formatHelpText(opt.HelpText), helpComment))
+			sb.WriteString("	}\n")
+		default:
+			if opt.IsTextUnmarshaler && opt.IsTextMarshaler {
+				if opt.IsPointer {
+					sb.WriteString(fmt.Sprintf("	is%sNilInitially := %s == nil\n", id, ref))
+					sb.WriteString(fmt.Sprintf("	var temp%sVal %s\n", id, strings.TrimPrefix(opt.TypeName, "*")))
+					sb.WriteString(fmt.Sprintf("	if is%sNilInitially {\n", id))
+					sb.WriteString(fmt.Sprintf("		%s.TextVar(&temp%sVal, %q, &temp%sVal, %s %s)\n", fs, id, opt.CliName, id, formatHelpText(opt.HelpText), helpComment))
+					sb.WriteString("	} else {\n")
+					sb.WriteString(fmt.Sprintf("		%s.TextVar(%s, %q, %s, %s %s)\n", fs, ref, opt.CliName, ref, formatHelpText(opt.HelpText), helpComment))
+					sb.WriteString("	}\n")
+				} else {
+					sb.WriteString(fmt.Sprintf("	%s.TextVar(&%s, %q, %s, %s %s)\n", fs, ref, opt.CliName, ref, formatHelpText(opt.HelpText), helpComment))
+				}
+			} else if opt.IsPointer && len(opt.EnumValues) > 0 && !opt.IsTextUnmarshaler {
+				sb.WriteString(fmt.Sprintf(`
+	if %s == nil {
+		%s = new(%s)
+	}
+	%s.Var(%s, %q, %s)
+`, ref, ref, strings.TrimPrefix(opt.TypeName, "*"), fs, ref, kebabCaseName, formatHelpText(opt.HelpText)))
+			} else if baseType, nt, ok := numericTypeOf(opt); ok {
+				writeNumericFlagRegistration(sb, opt, ref, id, fs, baseType, nt, formatHelpText(opt.HelpText), helpComment)
+			} else if elemType, ok := sliceElemOf(opt); ok {
+				writeSliceFlagRegistration(sb, opt, ref, id, fs, elemType, formatHelpText(opt.HelpText), helpComment)
+			} else if valueType, ok := mapValueTypeOf(opt); ok {
+				writeMapFlagRegistration(sb, opt, ref, id, fs, valueType, formatHelpText(opt.HelpText), helpComment)
+			} else {
+				registeredName = ""
+			}
+		}
+
+		// Short names and aliases share the flag.Value of the registered flag.
+		if registeredName != "" {
+			prefix := strings.TrimSuffix(registeredName, kebabCaseName) // "no-" for --no-<flag>
+			for _, alias := range aliasesOf(opt) {
+				sb.WriteString(fmt.Sprintf("	%s.Var(%s.Lookup(%q).Value, %q, %q)\n", fs, fs, registeredName, prefix+alias, "Alias for --"+registeredName))
+			}
+		}
+		if opt.IsSecret {
+			sb.WriteString(fmt.Sprintf("	var secretFile%s string\n", id))
+			sb.WriteString(fmt.Sprintf("	%s.StringVar(&secretFile%s, %q, \"\", %q)\n", fs, id, opt.SecretFileFlag(), "Read the value of --"+kebabCaseName+" from the file"))
+		}
+	}
+}
+
+// writeSecretFileReadings writes the reading of the files given by the --<flag>-file flags of the secret options of the layer
+// (after step 6, so that a nil pointer is not assigned again). The flag of the option itself takes precedence.
+// The value read from the file counts as given by a flag.
+func writeSecretFileReadings(sb *strings.Builder, l *optionsLayer) {
+	for _, opt := range l.Options {
+		if !opt.IsSecret || opt.Positional != nil {
+			continue
+		}
+		id := l.ident(opt)
+		kebabCaseName := cliNameOf(opt)
+		sb.WriteString(fmt.Sprintf(`
+	if secretFile%s != "" && !isFlagExplicitlySet[%q] {
+		data, err := os.ReadFile(secretFile%s)
+		if err != nil {
+			slog.ErrorContext(ctx, "Could not read the secret file", "error", err, "flag", %q, "option", %q)
+			os.Exit(1)
+		}
+%s		isFlagExplicitlySet[%q] = true
+	}
+`, id, kebabCaseName, id, opt.SecretFileFlag(), opt.Name, secretFileAssignment(opt, l.ref(opt)), kebabCaseName))
+	}
+}
+
+// writeNumericFlagRegistration writes the flag registration of an option of one of numericTypes (or a pointer to it).
+// Pointers are handled like *int: if the field is nil initially, the flag is bound to a temporary variable,
+// which is assigned by writePointerAssignments if the flag is set.
+func writeNumericFlagRegistration(sb *strings.Builder, opt *metadata.OptionMetadata, ref string, id string, fs string, baseType string, nt numericType, helpText string, helpComment string) {
+	kebabCaseName := cliNameOf(opt)
+	if !opt.IsPointer {
+		if nt.FlagFunc != "" {
+			sb.WriteString(fmt.Sprintf("	%s.%s(&%s, %q, %s, %s %s)\n", fs, nt.FlagFunc, ref, kebabCaseName, ref, helpText, helpComment))
+			return
+		}
+		sb.WriteString(fmt.Sprintf(`	%s.Func(%q, %s, func(s string) error {
+		v, err := %s
+		if err != nil {
+			return err
+		}
+		%s = %s
+		return nil
+	})
+`, fs, kebabCaseName, helpText, fmt.Sprintf(nt.Parse, "s"), ref, nt.parsedValue(baseType, "v")))
+		return
+	}
+
+	sb.WriteString(fmt.Sprintf("	is%sNilInitially := %s == nil\n", id, ref))
+	sb.WriteString(fmt.Sprintf("	var temp%sVal %s\n", id, baseType))
+	if nt.FlagFunc != "" {
+		sb.WriteString(fmt.Sprintf("	if is%sNilInitially {\n", id))
+		sb.WriteString(fmt.Sprintf("		%s.%s(&temp%sVal, %q, 0, %s %s)\n", fs, nt.FlagFunc, id, kebabCaseName, helpText, helpComment))
+		sb.WriteString("	} else {\n")
+		sb.WriteString(fmt.Sprintf("		%s.%s(%s, %q, *%s, %s %s)\n", fs, nt.FlagFunc, ref, kebabCaseName, ref, helpText, helpComment))
+		sb.WriteString("	}\n")
+		return
+	}
+	sb.WriteString(fmt.Sprintf(`	%s.Func(%q, %s, func(s string) error {
+		v, err := %s
+		if err != nil {
+			return err
+		}
+		if is%sNilInitially {
+			temp%sVal = %s
+		} else {
+			*%s = %s
+		}
+		return nil
+	})
+`, fs, kebabCaseName, helpText, fmt.Sprintf(nt.Parse, "s"), id, id, nt.parsedValue(baseType, "v"), ref, nt.parsedValue(baseType, "v")))
+}
+
+// writeSliceFlagRegistration writes the flag registration of a slice option.
+// The flag can be repeated (--tag a --tag b) and takes comma-separated items (--tag a,b).
+// The first occurrence of the flag replaces the default value, and the following ones append to it.
+func writeSliceFlagRegistration(sb *strings.Builder, opt *metadata.OptionMetadata, ref string, id string, fs string, elemType string, helpText string, helpComment string) {
+	sb.WriteString(fmt.Sprintf(`	var is%sSetByFlag bool
+	%s.Func(%q, %s %s, func(s string) error {
+		if !is%sSetByFlag {
+			is%sSetByFlag = true
+			%s = nil // Replaces the default value (or the value from the environment variable).
+		}
+		for _, item := range strings.Split(s, ",") {
+			%s
+			%s = append(%s, v)
+		}
+		return nil
+	})
+`, id, fs, cliNameOf(opt), helpText, helpComment, id, id, ref, sliceItemParse(opt, elemType, "return err"), ref, ref))
+}
+
+// writeMapFlagRegistration writes the flag registration of a map option.
+// The flag is repeated for each entry (--label key=value). Like slices, the first occurrence replaces the default value.
+func writeMapFlagRegistration(sb *strings.Builder, opt *metadata.OptionMetadata, ref string, id string, fs string, valueType string, helpText string, helpComment string) {
+	sb.WriteString(fmt.Sprintf(`	var is%sSetByFlag bool
+	%s.Func(%q, %s %s, func(item string) error {
+		if !is%sSetByFlag {
+			is%sSetByFlag = true
+			%s = make(%s) // Replaces the default value (or the value from the environment variable).
+		}
+		%s
+		%s[key] = v
+		return nil
+	})
+`, id, fs, cliNameOf(opt), helpText, helpComment, id, id, ref, opt.TypeName, mapItemParse(valueType, "return err"), ref))
+}
+
+// aliasesOf returns the flag names of opt other than its CLI name (the short name first, then the aliases).
+func aliasesOf(opt *metadata.OptionMetadata) []string {
+	var names []string
+	if opt.ShortName != "" {
+		names = append(names, opt.ShortName)
+	}
+	return append(names, opt.Aliases...)
+}
+
+// writeAliasNormalization writes the statements that record the flags set via a short name or an alias
+// as explicitly set under their CLI name (after step 4).
+func writeAliasNormalization(sb *strings.Builder, l *optionsLayer) {
+	for _, opt := range l.Options {
+		if opt.Positional != nil {
+			continue
+		}
+		name := cliNameOf(opt)
+		for _, alias := range aliasesOf(opt) {
+			sb.WriteString(fmt.Sprintf("	isFlagExplicitlySet[%q] = isFlagExplicitlySet[%q] || isFlagExplicitlySet[%q]\n", name, name, alias))
+		}
+	}
+}
+
+// writeNoFlagAssignments writes the handling of --no-<flag> for required booleans defaulting to true (after step 4).
+func writeNoFlagAssignments(sb *strings.Builder, l *optionsLayer) {
+	for _, opt := range l.Options {
+		if opt.TypeName == "bool" && opt.IsRequired && fmt.Sprintf("%v", opt.DefaultValue) == "true" {
+			sb.WriteString(fmt.Sprintf(`
+	if %s_NoFlagIsPresent {
+		%s = false
+	}
+`, l.ident(opt), l.ref(opt)))
+		}
+	}
+}
+
+// writePositionalArgs writes the arity checks of the positional arguments and their assignments (after step 4).
+// Nothing is written if no option of the layer receives positional arguments.
+func writePositionalArgs(sb *strings.Builder, l *optionsLayer, fs string) {
+	var singles []*metadata.OptionMetadata
+	var rest *metadata.OptionMetadata
+	for _, opt := range l.Options {
+		switch {
+		case opt.Positional == nil:
+			// A flag
+		case opt.Positional.Arity != "":
+			rest = opt
+		default:
+			singles = append(singles, opt)
+		}
+	}
+	if len(singles) == 0 && rest == nil {
+		return
+	}
+	slices.SortStableFunc(singles, func(x, y *metadata.OptionMetadata) int { return x.Positional.Index - y.Positional.Index })
+
+	minArgs, maxArgs := 0, len(singles) // maxArgs < 0 means unbounded
+	for _, opt := range singles {
+		if !opt.IsOptionalPositional() {
+			minArgs = opt.Positional.Index + 1
+		}
+	}
+	if rest != nil {
+		switch rest.Positional.Arity {
+		case "*":
+			maxArgs = -1
+		case "+":
+			minArgs, maxArgs = len(singles)+1, -1
+		default:
+			n, _ := strconv.Atoi(rest.Positional.Arity) // Validated by the analyzer
+			minArgs, maxArgs = len(singles)+n, len(singles)+n
+		}
+	}
+
+	sb.WriteString(fmt.Sprintf(`
+	// Assign positional arguments.
+	positionalArgs := %s.Args()
+`, fs))
+	if minArgs > 0 {
+		sb.WriteString(fmt.Sprintf(`	if len(positionalArgs) < %d {
+		slog.ErrorContext(ctx, "Missing required positional arguments", "error", errors.New("Missing required positional arguments"), "min", %d, "got", len(positionalArgs))
+		os.Exit(1)
+	}
+`, minArgs, minArgs))
+	}
+	if maxArgs >= 0 {
+		sb.WriteString(fmt.Sprintf(`	if len(positionalArgs) > %d {
+		slog.ErrorContext(ctx, "Too many positional arguments", "error", errors.New("Too many positional arguments"), "max", %d, "got", len(positionalArgs))
+		os.Exit(1)
+	}
+`, maxArgs, maxArgs))
+	}
+
+	for _, opt := range singles {
+		ref := l.ref(opt)
+		i := opt.Positional.Index
+		baseType := strings.TrimPrefix(opt.TypeName, "*")
+		value := fmt.Sprintf("positionalArgs[%d]", i)
+		if baseType != "string" {
+			value = fmt.Sprintf("%s(%s)", baseType, value) // Named string type
+		}
+		if opt.IsPointer { // Optional
+			sb.WriteString(fmt.Sprintf(`	if len(positionalArgs) > %d {
+		v := %s
+		%s = &v
+	}
+`, i, value, ref))
+		} else { // Required, so it is guaranteed by the check above
+			sb.WriteString(fmt.Sprintf("	%s = %s\n", ref, value))
 		}
-`, opt.Name, opt.Name, opt.Name, opt.EnvVar, opt.Name))
-				case "[]string":
-					sb.WriteString(fmt.Sprintf("		options.%s = strings.Split(val, \",\")\n", opt.Name))
-				}
+	}
+	if rest != nil {
+		// The remaining arguments replace the default value only if given.
+		sb.WriteString(fmt.Sprintf(`	if len(positionalArgs) > %d {
+		%s = positionalArgs[%d:]
+	}
+`, rest.Positional.Index, l.ref(rest), rest.Positional.Index))
+	}
+}
+
+// writePointerAssignments writes the assignments for initially nil pointers whose flags were explicitly set (step 6).
+func writePointerAssignments(sb *strings.Builder, l *optionsLayer) {
+	for _, opt := range l.Options {
+		isRelevantPointer := false
+		if opt.Positional != nil {
+			isRelevantPointer = false // Assigned by writePositionalArgs
+		} else if opt.IsPointer && opt.IsTextUnmarshaler {
+			isRelevantPointer = true
+		} else {
+			switch opt.TypeName {
+			case "*string", "*int", "*bool":
+				isRelevantPointer = true
+			default:
+				_, _, isNumeric := numericTypeOf(opt)
+				isRelevantPointer = isNumeric && opt.IsPointer
 			}
-			sb.WriteString("	}\n") // close if val, ok
 		}
 
-		// Flag setup
-		sb.WriteString(`
-	// 3. Set flags.
-`)
-		for _, opt := range cmdMeta.Options {
-			kebabCaseName := stringutils.ToKebabCase(opt.Name)
-			helpComment := ""
-			if opt.DefaultValue != nil {
-				// If there's a default value, always include "Original Default:"
-				// and include "Env:" part, even if EnvVar is empty.
-				helpComment = fmt.Sprintf("/* Original Default: %v, Env: %s */", opt.DefaultValue, opt.EnvVar)
-			} else if opt.EnvVar != "" {
-				// Only EnvVar is present
-				helpComment = fmt.Sprintf("/* Env: %s */", opt.EnvVar)
-			}
-			// If neither DefaultValue nor EnvVar is present, helpComment remains ""
+		if isRelevantPointer {
+			// Ensure is%sNilInitially and temp%sVal are in scope from flag setup
+			id := l.ident(opt)
+			sb.WriteString(fmt.Sprintf("	if is%sNilInitially && isFlagExplicitlySet[%q] {\n", id, cliNameOf(opt)))
+			sb.WriteString(fmt.Sprintf("		%s = &temp%sVal\n", l.ref(opt), id))
+			sb.WriteString("	}\n")
+		}
+	}
+}
 
-			switch opt.TypeName {
-			case "string":
-				sb.WriteString(fmt.Sprintf("	flag.StringVar(&options.%s, %q, options.%s, %s %s)\n", opt.Name, kebabCaseName, opt.Name, formatHelpText(opt.HelpText), helpComment))
-			case "int":
-				sb.WriteString(fmt.Sprintf("	flag.IntVar(&options.%s, %q, options.%s, %s %s)\n", opt.Name, kebabCaseName, opt.Name, formatHelpText(opt.HelpText), helpComment))
-			case "bool":
-				if opt.IsRequired && fmt.Sprintf("%v", opt.DefaultValue) == "true" {
-					sb.WriteString(fmt.Sprintf("	var %s_NoFlagIsPresent bool\n", opt.Name))
-					sb.WriteString(fmt.Sprintf("	flag.BoolVar(&%s_NoFlagIsPresent, \"no-%s\", false, %q)\n", opt.Name, kebabCaseName, "Set "+kebabCaseName+" to false"))
-				} else {
-					sb.WriteString(fmt.Sprintf("	flag.BoolVar(&options.%s, %q, options.%s, %s %s)\n", opt.Name, kebabCaseName, opt.Name, formatHelpText(opt.HelpText), helpComment))
-				}
-			case "*string":
-				sb.WriteString(fmt.Sprintf("	is%sNilInitially := options.%s == nil\n", opt.Name, opt.Name))
-				sb.WriteString(fmt.Sprintf("	var temp%sVal %s\n", opt.Name, strings.TrimPrefix(opt.TypeName, "*")))
-				sb.WriteString(fmt.Sprintf("	var default%sValForFlag string\n", opt.Name))
-				sb.WriteString(fmt.Sprintf("	if options.%s != nil { default%sValForFlag = *options.%s }\n", opt.Name, opt.Name, opt.Name))
-				// Line removed: sb.WriteString(fmt.Sprintf("	if options.%s == nil { options.%s = new(string) }\n", opt.Name, opt.Name))
-				sb.WriteString(fmt.Sprintf("	if is%sNilInitially {\n", opt.Name))
-				sb.WriteString(fmt.Sprintf("		flag.StringVar(&temp%sVal, %q, \"\", %s %s)\n", opt.Name, kebabCaseName, formatHelpText(opt.HelpText), helpComment))
-				sb.WriteString(fmt.Sprintf("	} else {\n"))
-				sb.WriteString(fmt.Sprintf("		flag.StringVar(options.%s, %q, default%sValForFlag, %s %s)\n", opt.Name, kebabCaseName, opt.Name, formatHelpText(opt.HelpText), helpComment))
-				sb.WriteString(fmt.Sprintf("	}\n"))
-			case "*int":
-				sb.WriteString(fmt.Sprintf("	is%sNilInitially := options.%s == nil\n", opt.Name, opt.Name))
-				sb.WriteString(fmt.Sprintf("	var temp%sVal %s\n", opt.Name, strings.TrimPrefix(opt.TypeName, "*")))
-				sb.WriteString(fmt.Sprintf("	var default%sValForFlag int\n", opt.Name))
-				sb.WriteString(fmt.Sprintf("	if options.%s != nil { default%sValForFlag = *options.%s }\n", opt.Name, opt.Name, opt.Name))
-				// Line removed: sb.WriteString(fmt.Sprintf("	if options.%s == nil { options.%s = new(int) }\n", opt.Name, opt.Name))
-				sb.WriteString(fmt.Sprintf("	if is%sNilInitially {\n", opt.Name))
-				sb.WriteString(fmt.Sprintf("		flag.IntVar(&temp%sVal, %q, 0, %s %s)\n", opt.Name, kebabCaseName, formatHelpText(opt.HelpText), helpComment))
-				sb.WriteString(fmt.Sprintf("	} else {\n"))
-				sb.WriteString(fmt.Sprintf("		flag.IntVar(options.%s, %q, default%sValForFlag, %s %s)\n", opt.Name, kebabCaseName, opt.Name, formatHelpText(opt.HelpText), helpComment))
-				sb.WriteString(fmt.Sprintf("	}\n"))
-			case "*bool":
-				sb.WriteString(fmt.Sprintf("	is%sNilInitially := options.%s == nil\n", opt.Name, opt.Name))
-				sb.WriteString(fmt.Sprintf("	var temp%sVal %s\n", opt.Name, strings.TrimPrefix(opt.TypeName, "*")))
-				sb.WriteString(fmt.Sprintf("	var default%sValForFlag bool\n", opt.Name))
-				sb.WriteString(fmt.Sprintf("	if options.%s != nil { default%sValForFlag = *options.%s }\n", opt.Name, opt.Name, opt.Name))
-				// Line removed: sb.WriteString(fmt.Sprintf("	if options.%s == nil { options.%s = new(bool) }\n", opt.Name, opt.Name))
-				sb.WriteString(fmt.Sprintf("	if is%sNilInitially {\n", opt.Name))
-				sb.WriteString(fmt.Sprintf("		flag.BoolVar(&temp%sVal, %q, false, %s %s)\n", opt.Name, kebabCaseName, formatHelpText(opt.HelpText), helpComment))
-				sb.WriteString(fmt.Sprintf("	} else {\n"))
-				sb.WriteString(fmt.Sprintf("		flag.BoolVar(options.%s, %q, default%sValForFlag, %s %s)\n", opt.Name, kebabCaseName, opt.Name, formatHelpText(opt.HelpText), helpComment))
-				sb.WriteString(fmt.Sprintf("	}\n"))
-			default:
-				if opt.IsTextUnmarshaler && opt.IsTextMarshaler {
-					if opt.IsPointer {
-						sb.WriteString(fmt.Sprintf("	is%sNilInitially := options.%s == nil\n", opt.Name, opt.Name))
-						sb.WriteString(fmt.Sprintf("	var temp%sVal %s\n", opt.Name, strings.TrimPrefix(opt.TypeName, "*")))
-						// Old block removed
-						sb.WriteString(fmt.Sprintf("	if is%sNilInitially {\n", opt.Name))
-						sb.WriteString(fmt.Sprintf("		flag.TextVar(&temp%sVal, %q, &temp%sVal, %s %s)\n", opt.Name, opt.CliName, opt.Name, formatHelpText(opt.HelpText), helpComment))
-						sb.WriteString(fmt.Sprintf("	} else {\n"))
-						sb.WriteString(fmt.Sprintf("		flag.TextVar(options.%s, %q, options.%s, %s %s)\n", opt.Name, opt.CliName, opt.Name, formatHelpText(opt.HelpText), helpComment))
-						sb.WriteString(fmt.Sprintf("	}\n"))
-					} else {
-						sb.WriteString(fmt.Sprintf("	flag.TextVar(&options.%s, %q, options.%s, %s %s)\n", opt.Name, opt.CliName, opt.Name, formatHelpText(opt.HelpText), helpComment))
-					}
-				} else if opt.IsPointer && len(opt.EnumValues) > 0 && !opt.IsTextUnmarshaler {
-					sb.WriteString(fmt.Sprintf(`
-	if options.%s == nil {
-		options.%s = new(%s)
+// givenCondition returns the condition that opt is given by its environment variable or flag
+// (including --no-<flag>, and <ENV>_FILE for a secret option).
+func givenCondition(opt *metadata.OptionMetadata) string {
+	kebabCaseName := cliNameOf(opt)
+	condition := fmt.Sprintf("isFlagExplicitlySet[%q]", kebabCaseName)
+	if opt.TypeName == "bool" && opt.IsRequired && fmt.Sprintf("%v", opt.DefaultValue) == "true" {
+		condition += fmt.Sprintf(" || isFlagExplicitlySet[%q]", "no-"+kebabCaseName)
 	}
-	flag.Var(options.%s, %q, %s)
-`, opt.Name, opt.Name, strings.TrimPrefix(opt.TypeName, "*"), opt.Name, kebabCaseName, formatHelpText(opt.HelpText)))
-				}
+	if opt.IsSecret && opt.EnvVar != "" {
+		condition += fmt.Sprintf(" || os.Getenv(%q) != \"\"", opt.SecretFileEnvVar())
+	}
+	if opt.EnvVar != "" {
+		condition = fmt.Sprintf("_, ok := os.LookupEnv(%q); ok || %s", opt.EnvVar, condition)
+	}
+	return condition
+}
+
+// writeConfigFileLoading writes the loading of the JSON config file given by the `goat:"config"` option of the layers (after step 6).
+// The values of the config file override the defaults, but not the values of environment variables and flags.
+// A value in the config file is marked in isFlagExplicitlySet, so that it counts as given in the required and group checks.
+// If trackSources is true, the options set by the config file are recorded for --print-config.
+// Nothing is written if no option of the layers is the config file.
+func writeConfigFileLoading(sb *strings.Builder, layers []*optionsLayer, trackSources bool) {
+	var configLayer *optionsLayer
+	var configOpt *metadata.OptionMetadata
+	for _, l := range layers {
+		for _, opt := range l.Options {
+			if opt.IsConfigFile && configOpt == nil {
+				configLayer, configOpt = l, opt
 			}
 		}
+	}
+	if configOpt == nil {
+		return
+	}
 
-		sb.WriteString(`
-	// 4. Parse.
-	flag.Parse()
-	flag.Visit(func(f *flag.Flag) { isFlagExplicitlySet[f.Name] = true })
+	sb.WriteString(`
+	// Override with the values of the config file, except for the options given by environment variables or flags.
 `)
-		for _, opt := range cmdMeta.Options {
-			if opt.TypeName == "bool" && opt.IsRequired && fmt.Sprintf("%v", opt.DefaultValue) == "true" {
-				sb.WriteString(fmt.Sprintf(`
-	if %s_NoFlagIsPresent {
-		options.%s = false
+	ref := configLayer.ref(configOpt)
+	if configOpt.IsPointer {
+		sb.WriteString(fmt.Sprintf(`	var configPath string
+	if %s != nil {
+		configPath = *%s
 	}
-`, opt.Name, opt.Name))
-			}
+`, ref, ref))
+	} else {
+		sb.WriteString(fmt.Sprintf("	configPath := %s\n", ref))
+	}
+	sb.WriteString(fmt.Sprintf(`	if configPath != "" {
+		isConfigPathGiven := false
+		if %s {
+			isConfigPathGiven = true
+		}
+		var configValues map[string]json.RawMessage
+		configData, err := os.ReadFile(configPath)
+		if err == nil {
+			err = json.Unmarshal(configData, &configValues)
+		} else if os.IsNotExist(err) && !isConfigPathGiven {
+			err = nil // The config file of the default path is optional.
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "Could not read the config file", "error", err, "path", configPath)
+			os.Exit(1)
 		}
 
-		sb.WriteString(`
-
-	// 6. Assign values for initially nil pointers if flags were explicitly set
-`)
-		// This loop iterates through cmdMeta.Options to generate the assignment logic
-		for _, opt := range cmdMeta.Options {
-			flagKeyForCheck := ""
-			isRelevantPointer := false
-
-			if opt.IsPointer && opt.IsTextUnmarshaler {
-				flagKeyForCheck = opt.CliName
-				if flagKeyForCheck == "" { // Fallback if CliName was empty
-					flagKeyForCheck = stringutils.ToKebabCase(opt.Name)
+		var unknownConfigKeys []string
+		for key, value := range configValues {
+			var err error
+			switch key {
+`, givenCondition(configOpt)))
+	for _, l := range layers {
+		for _, opt := range l.Options {
+			key := opt.ConfigFileKey()
+			if key == "" {
+				continue
+			}
+			ref := l.ref(opt)
+			sb.WriteString(fmt.Sprintf(`			case %q:
+				if %s {
+					continue // Environment variables and flags take precedence.
 				}
-				isRelevantPointer = true
+`, key, givenCondition(opt)))
+			if strings.TrimPrefix(opt.TypeName, "*") == "time.Duration" {
+				target := ref
+				if opt.IsPointer {
+					target = "*" + ref
+					sb.WriteString(fmt.Sprintf("				%s = new(time.Duration)\n", ref))
+				}
+				sb.WriteString(fmt.Sprintf(`				var s string
+				if err = json.Unmarshal(value, &s); err == nil {
+					%s, err = time.ParseDuration(s)
+				}
+`, target))
 			} else {
-				switch opt.TypeName {
-				case "*string", "*int", "*bool":
-					flagKeyForCheck = stringutils.ToKebabCase(opt.Name)
-					isRelevantPointer = true
-				default:
-					// Not a pointer type this logic handles
-					isRelevantPointer = false // Explicitly
+				// Decoded into a new value, so that the value replaces the default (e.g. of a map) instead of being merged into it.
+				sb.WriteString(fmt.Sprintf(`				var v %s
+				if err = json.Unmarshal(value, &v); err == nil {
+					%s = v
 				}
+`, opt.TypeName, ref))
 			}
-
-			if isRelevantPointer { // Check isRelevantPointer
-				// Ensure is%sNilInitially and temp%sVal are in scope from flag setup
-				sb.WriteString(fmt.Sprintf("	if is%sNilInitially && isFlagExplicitlySet[%q] {\n", opt.Name, flagKeyForCheck))
-				sb.WriteString(fmt.Sprintf("		options.%s = &temp%sVal\n", opt.Name, opt.Name))
-				sb.WriteString(fmt.Sprintf("	}\n"))
+			sb.WriteString(fmt.Sprintf("				isFlagExplicitlySet[%q] = true\n", cliNameOf(opt)))
+			if trackSources {
+				sb.WriteString(fmt.Sprintf("				configSources[%q] = configPath\n", cliNameOf(opt)))
 			}
 		}
-
-		sb.WriteString(`
-	// 5. Perform required checks (excluding booleans).
+	}
+	sb.WriteString(`			default:
+				unknownConfigKeys = append(unknownConfigKeys, key)
+			}
+			if err != nil {
+				slog.ErrorContext(ctx, "Invalid value in the config file", "error", err, "path", configPath, "key", key)
+				os.Exit(1)
+			}
+		}
+		if len(unknownConfigKeys) > 0 {
+			slices.Sort(unknownConfigKeys)
+			slog.ErrorContext(ctx, "Unknown keys in the config file", "error", errors.New("Unknown keys in the config file"), "path", configPath, "keys", strings.Join(unknownConfigKeys, ", "))
+			os.Exit(1)
+		}
+	}
 `)
-		for _, opt := range cmdMeta.Options {
-			kebabCaseName := stringutils.ToKebabCase(opt.Name) // Define kebabCaseName at the top of the loop
-
-			// Required check logic will be inserted here
-			if opt.IsRequired && opt.TypeName == "string" {
-				// kebabCaseName is already defined above
-				defaultValStr := `""`
-				if opt.DefaultValue != nil {
-					if dv, ok := opt.DefaultValue.(string); ok {
-						defaultValStr = fmt.Sprintf("%q", dv)
-					} else { // Should not happen with correct metadata
-						defaultValStr = fmt.Sprintf("%q", fmt.Sprintf("%v", opt.DefaultValue))
-					}
-				}
-				sb.WriteString(fmt.Sprintf("\n	initialDefault%s := %s\n", opt.Name, defaultValStr))
+}
 
-				envVarWasSetVar := fmt.Sprintf("env%sWasSet", opt.Name)
-				sb.WriteString(fmt.Sprintf("	%s := false\n", envVarWasSetVar))
+// writeRequiredCheck writes the check that the required option opt (of a non-pointer type) is given by a flag
+// or an environment variable, when its value is left as initialValue (the Go expression of its default value).
+func writeRequiredCheck(sb *strings.Builder, l *optionsLayer, opt *metadata.OptionMetadata, initialValue string) {
+	id := l.ident(opt)
+	kebabCaseName := cliNameOf(opt)
+	sb.WriteString(fmt.Sprintf("\n	initialDefault%s := %s\n", id, initialValue))
 
-				envVarLogIfPresent := ""
-				if opt.EnvVar != "" {
-					sb.WriteString(fmt.Sprintf("	if _, ok := os.LookupEnv(%q); ok { %s = true }\n", opt.EnvVar, envVarWasSetVar))
-					envVarLogIfPresent = fmt.Sprintf(`, "envVar", %q`, opt.EnvVar)
-				}
+	envVarWasSetVar := fmt.Sprintf("env%sWasSet", id)
+	sb.WriteString(fmt.Sprintf("	%s := false\n", envVarWasSetVar))
 
-				condition := fmt.Sprintf("options.%s == initialDefault%s && !isFlagExplicitlySet[%q] && !%s",
-					opt.Name, opt.Name, kebabCaseName, envVarWasSetVar)
-
-				sb.WriteString(fmt.Sprintf("	if %s {\n", condition))
-				sb.WriteString(fmt.Sprintf("		slog.ErrorContext(ctx, \"Missing required flag or environment variable not set\", errors.New(\"Missing required flag or environment variable not set\"), \"flag\", %q%s, \"option\", %q)\n",
-					kebabCaseName, envVarLogIfPresent, opt.Name))
-				sb.WriteString("		os.Exit(1)\n")
-				sb.WriteString("	}\n")
-			} else if opt.IsRequired && opt.TypeName == "int" {
-				kebabCaseName := stringutils.ToKebabCase(opt.Name) // Already defined at top of loop, but ensure it's used if this block was separate
-
-				defaultValStr := "0"
-				if opt.DefaultValue != nil {
-					if dv, ok := opt.DefaultValue.(int); ok {
-						defaultValStr = fmt.Sprintf("%d", dv)
-					} else { // Should not happen
-						defaultValStr = fmt.Sprintf("%v", opt.DefaultValue)
-					}
-				}
-				sb.WriteString(fmt.Sprintf("\n	initialDefault%s := %s\n", opt.Name, defaultValStr))
+	envVarLogIfPresent := ""
+	if opt.EnvVar != "" {
+		sb.WriteString(fmt.Sprintf("	if _, ok := os.LookupEnv(%q); ok { %s = true }\n", opt.EnvVar, envVarWasSetVar))
+		envVarLogIfPresent = fmt.Sprintf(`, "envVar", %q`, opt.EnvVar)
+	}
 
-				envVarWasSetVar := fmt.Sprintf("env%sWasSet", opt.Name)
-				sb.WriteString(fmt.Sprintf("	%s := false\n", envVarWasSetVar))
+	condition := fmt.Sprintf("%s == initialDefault%s && !isFlagExplicitlySet[%q] && !%s",
+		l.ref(opt), id, kebabCaseName, envVarWasSetVar)
 
-				envVarLogIfPresent := ""
-				if opt.EnvVar != "" {
-					sb.WriteString(fmt.Sprintf("	if _, ok := os.LookupEnv(%q); ok { %s = true }\n", opt.EnvVar, envVarWasSetVar))
-					envVarLogIfPresent = fmt.Sprintf(`, "envVar", %q`, opt.EnvVar)
-				}
+	sb.WriteString(fmt.Sprintf("	if %s {\n", condition))
+	sb.WriteString(fmt.Sprintf("		slog.ErrorContext(ctx, \"Missing required flag or environment variable not set\", \"error\", errors.New(\"Missing required flag or environment variable not set\"), \"flag\", %q%s, \"option\", %q)\n",
+		kebabCaseName, envVarLogIfPresent, opt.Name))
+	sb.WriteString("		os.Exit(1)\n")
+	sb.WriteString("	}\n")
+}
 
-				condition := fmt.Sprintf("options.%s == initialDefault%s && !isFlagExplicitlySet[%q] && !%s",
-					opt.Name, opt.Name, kebabCaseName, envVarWasSetVar)
-
-				sb.WriteString(fmt.Sprintf("	if %s {\n", condition))
-				sb.WriteString(fmt.Sprintf("		slog.ErrorContext(ctx, \"Missing required flag or environment variable not set\", errors.New(\"Missing required flag or environment variable not set\"), \"flag\", %q%s, \"option\", %q)\n",
-					kebabCaseName, envVarLogIfPresent, opt.Name))
-				sb.WriteString("		os.Exit(1)\n")
-				sb.WriteString("	}\n")
-			} else if opt.IsRequired && opt.TypeName == "*string" {
-				kebabCaseName := stringutils.ToKebabCase(opt.Name)
-				envVarWasSetVar := fmt.Sprintf("env%sWasSet", opt.Name)
-				envVarLogIfPresent := ""
-
-				sb.WriteString(fmt.Sprintf("	%s := false\n", envVarWasSetVar))
-				if opt.EnvVar != "" {
-					sb.WriteString(fmt.Sprintf("	if _, ok := os.LookupEnv(%q); ok { %s = true }\n", opt.EnvVar, envVarWasSetVar))
-					envVarLogIfPresent = fmt.Sprintf(`, "envVar", %q`, opt.EnvVar)
+// writeValidations writes the required checks and the enum validation of the layer (step 5).
+func writeValidations(sb *strings.Builder, l *optionsLayer) {
+	for _, opt := range l.Options {
+		ref := l.ref(opt)
+		id := l.ident(opt)
+		kebabCaseName := cliNameOf(opt)
+
+		baseType, _, isNumeric := numericTypeOf(opt)
+		if opt.Positional != nil {
+			// The number of positional arguments is checked by writePositionalArgs.
+		} else if opt.IsRequired && opt.TypeName == "string" {
+			defaultValStr := `""`
+			if opt.DefaultValue != nil {
+				if dv, ok := opt.DefaultValue.(string); ok {
+					defaultValStr = fmt.Sprintf("%q", dv)
+				} else { // Should not happen with correct metadata
+					defaultValStr = fmt.Sprintf("%q", fmt.Sprintf("%v", opt.DefaultValue))
+				}
+			}
+			writeRequiredCheck(sb, l, opt, defaultValStr)
+		} else if opt.IsRequired && opt.TypeName == "int" {
+			defaultValStr := "0"
+			if opt.DefaultValue != nil {
+				if dv, ok := opt.DefaultValue.(int); ok {
+					defaultValStr = fmt.Sprintf("%d", dv)
+				} else { // Should not happen
+					defaultValStr = fmt.Sprintf("%v", opt.DefaultValue)
 				}
+			}
+			writeRequiredCheck(sb, l, opt, defaultValStr)
+		} else if opt.IsRequired && isNumeric && !opt.IsPointer && opt.DefaultValue == nil {
+			// Like the help message, an option of the other numeric types with a default value is not required to be given.
+			writeRequiredCheck(sb, l, opt, numericLiteral(baseType, nil))
+		} else if opt.IsRequired && opt.TypeName == "*string" {
+			envVarWasSetVar := fmt.Sprintf("env%sWasSet", id)
+			envVarLogIfPresent := ""
 
-				sb.WriteString(fmt.Sprintf(`
+			sb.WriteString(fmt.Sprintf("	%s := false\n", envVarWasSetVar))
+			if opt.EnvVar != "" {
+				sb.WriteString(fmt.Sprintf("	if _, ok := os.LookupEnv(%q); ok { %s = true }\n", opt.EnvVar, envVarWasSetVar))
+				envVarLogIfPresent = fmt.Sprintf(`, "envVar", %q`, opt.EnvVar)
+			}
+
+			sb.WriteString(fmt.Sprintf(`
 	if !isFlagExplicitlySet[%q] && !%s { // If not set by flag or env
 `, kebabCaseName, envVarWasSetVar))
-				if opt.DefaultValue == nil { // No default value from struct tag
-					sb.WriteString(fmt.Sprintf(`
-		if options.%s == nil || *options.%s == "" {
-			slog.ErrorContext(ctx, "Missing required flag or environment variable, and no default provided", errors.New("Missing required flag or environment variable, and no default provided"), "flag", %q%s, "option", %q)
+			if opt.DefaultValue == nil { // No default value from struct tag
+				sb.WriteString(fmt.Sprintf(`
+		if %s == nil || *%s == "" {
+			slog.ErrorContext(ctx, "Missing required flag or environment variable, and no default provided", "error", errors.New("Missing required flag or environment variable, and no default provided"), "flag", %q%s, "option", %q)
 			os.Exit(1)
 		}
-`, opt.Name, opt.Name, kebabCaseName, envVarLogIfPresent, opt.Name))
-				} else {
-					// If DefaultValue IS present, it's assumed to be set during options initialization.
-					// The original template implicitly means if a default tag exists, the field is "provided" unless overridden.
-					// So, if not set by flag/env, and a default tag was there, it's fine.
-				}
-				sb.WriteString(fmt.Sprintf(`
-	} else if options.%s == nil || *options.%s == "" { // Explicitly set (by flag or env) to empty or nil
-		slog.ErrorContext(ctx, "Required flag was set to an empty value", errors.New("Required flag was set to an empty value"), "flag", %q%s, "option", %q)
+`, ref, ref, kebabCaseName, envVarLogIfPresent, opt.Name))
+			}
+			// If DefaultValue IS present, it's assumed to be set during options initialization,
+			// so if not set by flag/env, the default is fine.
+			sb.WriteString(fmt.Sprintf(`
+	} else if %s == nil || *%s == "" { // Explicitly set (by flag or env) to empty or nil
+		slog.ErrorContext(ctx, "Required flag was set to an empty value", "error", errors.New("Required flag was set to an empty value"), "flag", %q%s, "option", %q)
 		os.Exit(1)
 	}
-`, opt.Name, opt.Name, kebabCaseName, envVarLogIfPresent, opt.Name))
+`, ref, ref, kebabCaseName, envVarLogIfPresent, opt.Name))
 
-			} else if opt.IsRequired && opt.TypeName == "*int" {
-				kebabCaseName := stringutils.ToKebabCase(opt.Name)
-				envVarWasSetVar := fmt.Sprintf("env%sWasSet", opt.Name)
-				envVarLogIfPresent := ""
+		} else if opt.IsRequired && (opt.TypeName == "*int" || isNumeric && opt.IsPointer) {
+			envVarWasSetVar := fmt.Sprintf("env%sWasSet", id)
+			envVarLogIfPresent := ""
 
-				sb.WriteString(fmt.Sprintf("	%s := false\n", envVarWasSetVar))
-				if opt.EnvVar != "" {
-					sb.WriteString(fmt.Sprintf("	if _, ok := os.LookupEnv(%q); ok { %s = true }\n", opt.EnvVar, envVarWasSetVar))
-					envVarLogIfPresent = fmt.Sprintf(`, "envVar", %q`, opt.EnvVar)
-				}
+			sb.WriteString(fmt.Sprintf("	%s := false\n", envVarWasSetVar))
+			if opt.EnvVar != "" {
+				sb.WriteString(fmt.Sprintf("	if _, ok := os.LookupEnv(%q); ok { %s = true }\n", opt.EnvVar, envVarWasSetVar))
+				envVarLogIfPresent = fmt.Sprintf(`, "envVar", %q`, opt.EnvVar)
+			}
 
-				sb.WriteString(fmt.Sprintf(`
+			sb.WriteString(fmt.Sprintf(`
 	if !isFlagExplicitlySet[%q] && !%s { // If not set by flag or env
 `, kebabCaseName, envVarWasSetVar))
-				if opt.DefaultValue == nil { // No default value from struct tag
-					sb.WriteString(fmt.Sprintf(`
-		if options.%s == nil { // For *int, just being nil is enough if no default tag
-			slog.ErrorContext(ctx, "Missing required flag or environment variable, and no default provided", errors.New("Missing required flag or environment variable, and no default provided"), "flag", %q%s, "option", %q)
+			if opt.DefaultValue == nil { // No default value from struct tag
+				sb.WriteString(fmt.Sprintf(`
+		if %s == nil { // For *int (and other numeric pointers), just being nil is enough if no default tag
+			slog.ErrorContext(ctx, "Missing required flag or environment variable, and no default provided", "error", errors.New("Missing required flag or environment variable, and no default provided"), "flag", %q%s, "option", %q)
 			os.Exit(1)
 		}
-`, opt.Name, kebabCaseName, envVarLogIfPresent, opt.Name))
-				}
-				sb.WriteString(fmt.Sprintf(`
-	} else if options.%s == nil { // Explicitly set (by flag or env) to nil
-		slog.ErrorContext(ctx, "Required flag was not provided or set to nil", errors.New("Required flag was not provided or set to nil"), "flag", %q%s, "option", %q)
+`, ref, kebabCaseName, envVarLogIfPresent, opt.Name))
+			}
+			sb.WriteString(fmt.Sprintf(`
+	} else if %s == nil { // Explicitly set (by flag or env) to nil
+		slog.ErrorContext(ctx, "Required flag was not provided or set to nil", "error", errors.New("Required flag was not provided or set to nil"), "flag", %q%s, "option", %q)
 		os.Exit(1)
 	}
-`, opt.Name, kebabCaseName, envVarLogIfPresent, opt.Name))
+`, ref, kebabCaseName, envVarLogIfPresent, opt.Name))
+		}
 
-			} else if opt.IsRequired {
-				// Placeholder for other required types like *bool
-				// sb.WriteString(fmt.Sprintf("\n	// TODO: Add required check for %s (type %s)\n", opt.Name, opt.TypeName))
+		// Enum validation
+		effectiveEnums := GetEffectiveEnumValues(opt)
+		if len(effectiveEnums) > 0 {
+			allowedChoicesStr := make([]string, len(effectiveEnums))
+			for i, e := range effectiveEnums {
+				allowedChoicesStr[i] = fmt.Sprintf("%q", e)
 			}
-
-			// Enum validation (currently to be implemented in a future step)
-			effectiveEnums := GetEffectiveEnumValues(opt)
-			if len(effectiveEnums) > 0 {
-				// sb.WriteString(fmt.Sprintf("\n	// TODO: Add enum validation for %s\n", opt.Name))
-				allowedChoicesStr := make([]string, len(effectiveEnums))
-				for i, e := range effectiveEnums {
-					allowedChoicesStr[i] = fmt.Sprintf("%q", e)
-				}
-				sb.WriteString(fmt.Sprintf(`
+			sb.WriteString(fmt.Sprintf(`
 	isValidChoice_%s := false
 	allowedChoices_%s := []string{%s}
-`, opt.Name, opt.Name, strings.Join(allowedChoicesStr, ", ")))
+`, id, id, strings.Join(allowedChoicesStr, ", ")))
 
-				pointerCheckPrefix := ""
-				pointerCheckSuffix := ""
-				// isPointerBasicType := false // Removed
-				if opt.IsPointer {
-					switch opt.TypeName {
-					case "*string", "*int", "*bool":
-						// isPointerBasicType = true // Removed
-						currentValueStrVar := fmt.Sprintf("currentValue_%sStr", opt.Name)
-						allowedChoicesVar := fmt.Sprintf("allowedChoices_%s", opt.Name)
-						isValidChoiceVar := fmt.Sprintf("isValidChoice_%s", opt.Name)
-						pointerCheckPrefix = fmt.Sprintf(`
-	if options.%s != nil {
-		%s := fmt.Sprintf("%%%%v", *options.%s)
+			currentValueStrVar := fmt.Sprintf("currentValue_%sStr", id)
+			allowedChoicesVar := fmt.Sprintf("allowedChoices_%s", id)
+			isValidChoiceVar := fmt.Sprintf("isValidChoice_%s", id)
+			if opt.IsPointer {
+				sb.WriteString(fmt.Sprintf(`
+	if %s != nil {
+		%s := fmt.Sprintf("%%v", *%s)
 		%s = slices.Contains(%s, %s)
 	} else {
-`, opt.Name, currentValueStrVar, opt.Name, isValidChoiceVar, allowedChoicesVar, currentValueStrVar)
-					default:
-						currentValueStrVar := fmt.Sprintf("currentValue_%sStr", opt.Name)
-						allowedChoicesVar := fmt.Sprintf("allowedChoices_%s", opt.Name)
-						isValidChoiceVar := fmt.Sprintf("isValidChoice_%s", opt.Name)
-						pointerCheckPrefix = fmt.Sprintf(`
-	if options.%s != nil {
-		%s := fmt.Sprintf("%%%%v", *options.%s)
-		%s = slices.Contains(%s, %s)
-	} else { // Field is nil
-`, opt.Name, currentValueStrVar, opt.Name, isValidChoiceVar, allowedChoicesVar, currentValueStrVar)
-					}
-					if opt.IsRequired {
-						pointerCheckSuffix = fmt.Sprintf(`
-		slog.ErrorContext(ctx, "Required enum flag is nil", errors.New("Required enum flag is nil"), "flag", %q, "option", %q)
+`, ref, currentValueStrVar, ref, isValidChoiceVar, allowedChoicesVar, currentValueStrVar))
+				if opt.IsRequired {
+					sb.WriteString(fmt.Sprintf(`
+		slog.ErrorContext(ctx, "Required enum flag is nil", "error", errors.New("Required enum flag is nil"), "flag", %q, "option", %q)
 		os.Exit(1)
 	}
-`, kebabCaseName, opt.Name)
-					} else {
-						pointerCheckSuffix = `
-		isValidChoice_` + opt.Name + ` = true
+`, kebabCaseName, opt.Name))
+				} else {
+					sb.WriteString(fmt.Sprintf(`
+		%s = true
 	}
-`
-					}
-					sb.WriteString(pointerCheckPrefix + pointerCheckSuffix)
-				} else { // Non-pointer type
-					currentValueStrVar := fmt.Sprintf("currentValue_%sStr", opt.Name)
-					isValidChoiceVar := fmt.Sprintf("isValidChoice_%s", opt.Name)
-					allowedChoicesVar := fmt.Sprintf("allowedChoices_%s", opt.Name)
-					escapedOptName := strings.ReplaceAll(opt.Name, "%", "%%") // Escape % in opt.Name for options.%s
-
-					sb.WriteString(fmt.Sprintf("\n\t%s := fmt.Sprintf(\"%%v\", options.%s)\n\t%s = slices.Contains(%s, %s)\n", // Corrected: %%%%v to %%v
-						currentValueStrVar,
-						escapedOptName, // Use escaped opt.Name here
-						isValidChoiceVar,
-						allowedChoicesVar,
-						currentValueStrVar,
-					))
+`, isValidChoiceVar))
 				}
+			} else { // Non-pointer type
+				sb.WriteString(fmt.Sprintf("\n\t%s := fmt.Sprintf(\"%%v\", %s)\n\t%s = slices.Contains(%s, %s)\n",
+					currentValueStrVar,
+					ref,
+					isValidChoiceVar,
+					allowedChoicesVar,
+					currentValueStrVar,
+				))
+			}
 
-				// Invalid choice message generation
-				kebabCaseName := stringutils.ToKebabCase(opt.Name) // Ensure kebabCaseName is available
-				currentValueForMsgAccessor := fmt.Sprintf("options.%s", opt.Name)
-
-				if opt.IsPointer { // Covers all pointer types including basic and custom enum pointers
-					// currentValueForMsgAccessor is options.OptName (pointer itself)
-					slogLineFormat := `slog.ErrorContext(ctx, "Invalid value for flag", errors.New("Invalid value for flag"), "flag", %q, "value", currentValueForMsg, "allowedChoices", strings.Join(allowedChoices_%s, ", "))`
-					slogLine := fmt.Sprintf(slogLineFormat, kebabCaseName, opt.Name)
-
-					sb.WriteString(fmt.Sprintf(`
+			// Invalid choice message generation
+			if opt.IsSecret { // The value is not logged
+				sb.WriteString(fmt.Sprintf(`
+	if !isValidChoice_%s {
+		slog.ErrorContext(ctx, "Invalid value for flag", "error", errors.New("Invalid value for flag"), "flag", %q, "value", %q, "allowedChoices", strings.Join(allowedChoices_%s, ", "))
+		os.Exit(1)
+	}
+`, id, kebabCaseName, metadata.RedactedValue, id))
+			} else if opt.IsPointer { // Covers all pointer types including basic and custom enum pointers
+				sb.WriteString(fmt.Sprintf(`
 	if !isValidChoice_%s {
 		var currentValueForMsg interface{} = %s
-		if options.%s != nil {
-			currentValueForMsg = *options.%s
+		if %s != nil {
+			currentValueForMsg = *%s
 		}
-		%s
+		slog.ErrorContext(ctx, "Invalid value for flag", "error", errors.New("Invalid value for flag"), "flag", %q, "value", currentValueForMsg, "allowedChoices", strings.Join(allowedChoices_%s, ", "))
 		os.Exit(1)
 	}
-`, opt.Name, currentValueForMsgAccessor, opt.Name, opt.Name, slogLine))
-				} else { // Non-pointer enum
-					sb.WriteString(fmt.Sprintf(`
+`, id, ref, ref, ref, kebabCaseName, id))
+			} else { // Non-pointer enum
+				sb.WriteString(fmt.Sprintf(`
 	if !isValidChoice_%s {
 		var currentValueForMsg interface{} = %s // options.OptName
-		slog.ErrorContext(ctx, "Invalid value for flag", errors.New("Invalid value for flag"), "flag", %q, "value", currentValueForMsg, "allowedChoices", strings.Join(allowedChoices_%s, ", "))
+		slog.ErrorContext(ctx, "Invalid value for flag", "error", errors.New("Invalid value for flag"), "flag", %q, "value", currentValueForMsg, "allowedChoices", strings.Join(allowedChoices_%s, ", "))
 		os.Exit(1)
 	}
-`, opt.Name, currentValueForMsgAccessor, kebabCaseName, opt.Name))
-				}
+`, id, ref, kebabCaseName, id))
 			}
 		}
+
+		writeFileChecks(sb, opt, ref, id, kebabCaseName)
+		writeConstraintChecks(sb, opt, ref, kebabCaseName)
 	}
+}
 
-	runFuncCall := ""
-	if cmdMeta.RunFunc.ContextArgName != "" {
-		if cmdMeta.RunFunc.OptionsArgTypeNameStripped != "" {
-			optionsAccessor := "options"
-			if !cmdMeta.RunFunc.OptionsArgIsPointer {
-				optionsAccessor = "*options"
-			}
-			runFuncCall = fmt.Sprintf("err = %s(ctx, %s)", cmdMeta.RunFunc.Name, optionsAccessor)
-		} else {
-			runFuncCall = fmt.Sprintf("err = %s(ctx)", cmdMeta.RunFunc.Name)
+// writeFileChecks writes the glob expansion and the existence checks of a file option (goat.GlobPattern, goat.MustExist),
+// and the existence check of a directory option (goat.Dir(goat.MustExist())).
+// "-" (stdin or stdout) and empty values are not checked.
+func writeFileChecks(sb *strings.Builder, opt *metadata.OptionMetadata, ref string, id string, kebabCaseName string) {
+	if opt.DirMustExist {
+		value, nilGuard := ref, ""
+		if opt.IsPointer {
+			value, nilGuard = "*"+ref, ref+" != nil && "
+		}
+		sb.WriteString(fmt.Sprintf(`
+	if %s%s != "" {
+		info, err := os.Stat(%s)
+		if err != nil {
+			slog.ErrorContext(ctx, "Directory does not exist", "error", err, "flag", %q, "path", %s)
+			os.Exit(1)
+		}
+		if !info.IsDir() {
+			slog.ErrorContext(ctx, "Not a directory", "error", errors.New("Not a directory"), "flag", %q, "path", %s)
+			os.Exit(1)
+		}
+	}
+`, nilGuard, value, value, kebabCaseName, value, kebabCaseName, value))
+	}
+	if !opt.FileMustExist && !opt.FileGlobPattern {
+		return
+	}
+
+	if strings.HasPrefix(opt.TypeName, "[]") {
+		if opt.FileGlobPattern {
+			sb.WriteString(fmt.Sprintf(`
+	var expanded%s []string
+	for _, pattern := range %s {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			slog.ErrorContext(ctx, "Invalid glob pattern for flag", "error", err, "flag", %q, "pattern", pattern)
+			os.Exit(1)
+		}
+		if len(matches) == 0 {
+			matches = []string{pattern} // Kept as is, like a shell
+		}
+		expanded%s = append(expanded%s, matches...)
+	}
+	%s = expanded%s
+`, id, ref, kebabCaseName, id, id, ref, id))
+		}
+		if opt.FileMustExist {
+			sb.WriteString(fmt.Sprintf(`
+	for _, path := range %s {
+		if path == "-" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			slog.ErrorContext(ctx, "File does not exist", "error", err, "flag", %q, "path", path)
+			os.Exit(1)
+		}
+	}
+`, ref, kebabCaseName))
+		}
+		return
+	}
+
+	value, nilGuard := ref, ""
+	if opt.IsPointer {
+		value, nilGuard = "*"+ref, ref+" != nil && "
+	}
+	sb.WriteString(fmt.Sprintf(`
+	if %s%s != "" && %s != "-" {
+`, nilGuard, value, value))
+	if opt.FileGlobPattern && opt.FileMustExist {
+		// The pattern itself is passed to the run function.
+		sb.WriteString(fmt.Sprintf(`		matches, err := filepath.Glob(%s)
+		if err != nil {
+			slog.ErrorContext(ctx, "Invalid glob pattern for flag", "error", err, "flag", %q, "pattern", %s)
+			os.Exit(1)
+		}
+		if len(matches) == 0 {
+			slog.ErrorContext(ctx, "No files match the glob pattern for flag", "error", errors.New("No files match the glob pattern for flag"), "flag", %q, "pattern", %s)
+			os.Exit(1)
 		}
+`, value, kebabCaseName, value, kebabCaseName, value))
+	} else if opt.FileGlobPattern {
+		sb.WriteString(fmt.Sprintf(`		if _, err := filepath.Match(%s, ""); err != nil {
+			slog.ErrorContext(ctx, "Invalid glob pattern for flag", "error", err, "flag", %q, "pattern", %s)
+			os.Exit(1)
+		}
+`, value, kebabCaseName, value))
 	} else {
-		if cmdMeta.RunFunc.OptionsArgTypeNameStripped != "" {
-			optionsAccessor := "options"
-			if !cmdMeta.RunFunc.OptionsArgIsPointer {
-				optionsAccessor = "*options"
+		sb.WriteString(fmt.Sprintf(`		if _, err := os.Stat(%s); err != nil {
+			slog.ErrorContext(ctx, "File does not exist", "error", err, "flag", %q, "path", %s)
+			os.Exit(1)
+		}
+`, value, kebabCaseName, value))
+	}
+	sb.WriteString("	}\n")
+}
+
+// writeConstraintChecks writes the range, length and pattern checks of opt
+// (goat.Min, goat.Max, goat.Range, goat.MinLen, goat.MaxLen, goat.Pattern).
+// A nil pointer is not checked.
+func writeConstraintChecks(sb *strings.Builder, opt *metadata.OptionMetadata, ref string, kebabCaseName string) {
+	value, nilGuard := ref, ""
+	if opt.IsPointer {
+		value, nilGuard = "*"+ref, ref+" != nil && "
+	}
+
+	if valueRange := opt.ValueRange(); valueRange != "" {
+		var conds []string
+		if opt.MinValue != nil {
+			conds = append(conds, fmt.Sprintf("%s < %v", value, opt.MinValue))
+		}
+		if opt.MaxValue != nil {
+			conds = append(conds, fmt.Sprintf("%s > %v", value, opt.MaxValue))
+		}
+		sb.WriteString(fmt.Sprintf(`
+	if %s(%s) {
+		slog.ErrorContext(ctx, "Value out of range for flag", "error", errors.New("Value out of range for flag"), "flag", %q, "value", %s, "range", %q)
+		os.Exit(1)
+	}
+`, nilGuard, strings.Join(conds, " || "), kebabCaseName, value, valueRange))
+	}
+
+	if lengthRange := opt.LengthRange(); lengthRange != "" {
+		length := fmt.Sprintf("len(%s)", value)
+		if opt.LengthInCharacters() { // The length of a string is the number of characters, not bytes
+			if strings.TrimPrefix(opt.TypeName, "*") == "string" {
+				length = fmt.Sprintf("utf8.RuneCountInString(%s)", value)
+			} else {
+				length = fmt.Sprintf("utf8.RuneCountInString(string(%s))", value)
 			}
-			runFuncCall = fmt.Sprintf("err = %s(%s)", cmdMeta.RunFunc.Name, optionsAccessor)
-		} else {
-			runFuncCall = fmt.Sprintf("err = %s()", cmdMeta.RunFunc.Name)
 		}
+		var conds []string
+		if opt.MinLen != nil {
+			conds = append(conds, fmt.Sprintf("%s < %d", length, *opt.MinLen))
+		}
+		if opt.MaxLen != nil {
+			conds = append(conds, fmt.Sprintf("%s > %d", length, *opt.MaxLen))
+		}
+		sb.WriteString(fmt.Sprintf(`
+	if %s(%s) {
+		slog.ErrorContext(ctx, "Invalid length of value for flag", "error", errors.New("Invalid length of value for flag"), "flag", %q, "length", %s, "allowed", %q)
+		os.Exit(1)
+	}
+`, nilGuard, strings.Join(conds, " || "), kebabCaseName, length, lengthRange))
+	}
+
+	if opt.Pattern != "" {
+		envVarLogIfPresent := ""
+		if opt.EnvVar != "" {
+			envVarLogIfPresent = fmt.Sprintf(`, "envVar", %q`, opt.EnvVar)
+		}
+		loggedValue := value
+		if opt.IsSecret {
+			loggedValue = strconv.Quote(metadata.RedactedValue)
+		}
+		sb.WriteString(fmt.Sprintf(`
+	if %s!regexp.MustCompile(%q).MatchString(%s) {
+		slog.ErrorContext(ctx, "Value does not match the pattern for flag", "error", errors.New("Value does not match the pattern for flag"), "flag", %q%s, "value", %s, "pattern", %q)
+		os.Exit(1)
+	}
+`, nilGuard, opt.Pattern, value, kebabCaseName, envVarLogIfPresent, loggedValue, opt.Pattern))
+	}
+}
+
+// writeGroupChecks writes the checks of the option groups of the layer (goat.OneOf, goat.Together).
+// An option counts as given if its flag is explicitly set or its environment variable is set.
+func writeGroupChecks(sb *strings.Builder, l *optionsLayer) {
+	optionsByName := make(map[string]*metadata.OptionMetadata, len(l.Options))
+	for _, opt := range l.Options {
+		optionsByName[opt.Name] = opt
+	}
+	for i, group := range l.Groups {
+		givenVar := fmt.Sprintf("given%sGroup%d", l.IdentPrefix, i)
+		var flagNames []string
+		sb.WriteString(fmt.Sprintf("\n	var %s []string\n", givenVar))
+		for _, name := range group.Options {
+			opt, ok := optionsByName[name]
+			if !ok {
+				continue // Checked by the interpreter
+			}
+			kebabCaseName := cliNameOf(opt)
+			flagNames = append(flagNames, "--"+kebabCaseName)
+			sb.WriteString(fmt.Sprintf("	if %s {\n		%s = append(%s, %q)\n	}\n", givenCondition(opt), givenVar, givenVar, "--"+kebabCaseName))
+		}
+		switch group.Kind {
+		case metadata.OptionGroupOneOf:
+			sb.WriteString(fmt.Sprintf(`	if len(%s) > 1 {
+		slog.ErrorContext(ctx, "Mutually exclusive flags were given together", "error", errors.New("Mutually exclusive flags were given together"), "flags", strings.Join(%s, ", "))
+		os.Exit(1)
+	}
+`, givenVar, givenVar))
+		case metadata.OptionGroupTogether:
+			sb.WriteString(fmt.Sprintf(`	if len(%s) > 0 && len(%s) < %d {
+		slog.ErrorContext(ctx, "Flags must be given together", "error", errors.New("Flags must be given together"), "flags", %q, "given", strings.Join(%s, ", "))
+		os.Exit(1)
+	}
+`, givenVar, givenVar, len(flagNames), strings.Join(flagNames, ", "), givenVar))
+		}
+	}
+}
+
+// writeValidateCall writes the call of the Validate() method of the options struct, if any.
+// It is called after the other checks, and a failure is reported like a usage error (exit code 2).
+func writeValidateCall(sb *strings.Builder, l *optionsLayer, fs string) {
+	if !l.HasValidate {
+		return
+	}
+	sb.WriteString(fmt.Sprintf(`
+	if err := %s.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid options: %%v\n\n", err)
+		%s.Usage()
+		os.Exit(2)
+	}
+`, l.VarName, fs))
+}
+
+// writeDirCreations writes the creation of the directories of the layer (goat.Dir(goat.CreateIfMissing(perm))).
+// It is written after all the checks, so that nothing is created for an invalid command line.
+func writeDirCreations(sb *strings.Builder, l *optionsLayer) {
+	for _, opt := range l.Options {
+		if !opt.DirCreateIfMissing {
+			continue
+		}
+		ref := l.ref(opt)
+		value, nilGuard := ref, ""
+		if opt.IsPointer {
+			value, nilGuard = "*"+ref, ref+" != nil && "
+		}
+		sb.WriteString(fmt.Sprintf(`
+	if %s%s != "" {
+		if err := os.MkdirAll(%s, 0o%o); err != nil {
+			slog.ErrorContext(ctx, "Could not create directory", "error", err, "flag", %q, "path", %s)
+			os.Exit(1)
+		}
+	}
+`, nilGuard, value, value, opt.DirPerm, cliNameOf(opt), value))
+	}
+}
+
+// writeRunFuncCall writes the call of the run function and its error handling (step 7).
+func writeRunFuncCall(sb *strings.Builder, runFunc *metadata.RunFuncInfo) {
+	var args []string
+	if runFunc.ContextArgName != "" {
+		args = append(args, "ctx")
+	}
+	if runFunc.GlobalOptionsArgTypeNameStripped != "" {
+		args = append(args, Ternary(runFunc.GlobalOptionsArgIsPointer, "globalOptions", "*globalOptions"))
 	}
-	// The \n was removed from the format string as the if statement will be on the same line.
-	// The duplicated "err =" is removed from the line below.
-	sb.WriteString(fmt.Sprintf("	if err := %s; err != nil {\n", strings.TrimPrefix(runFuncCall, "err = ")))
+	if runFunc.OptionsArgTypeNameStripped != "" {
+		args = append(args, Ternary(runFunc.OptionsArgIsPointer, "options", "*options"))
+	}
+	sb.WriteString(fmt.Sprintf("	if err := %s(%s); err != nil {\n", runFunc.Name, strings.Join(args, ", ")))
 
 	sb.WriteString(`
 		slog.ErrorContext(ctx, "Runtime error", "error", err)
 		os.Exit(1)
 	}
-}
 `)
-	return sb.String(), nil
 }
 
 // Ternary is a helper function to mimic ternary operator for string selection
@@ -670,14 +1998,42 @@ func Ternary(condition bool, trueVal, falseVal string) string {
 	return falseVal
 }
 
+// GenerateMain creates the Go code string for the new main() function
+// based on the extracted command metadata.
+// If generateFullFile is true, it returns a complete Go file content including package and imports.
+// Otherwise, it returns only the main function body.
 func GenerateMain(cmdMeta *metadata.CommandMetadata, helpText string, generateFullFile bool) (string, error) {
-	if len(cmdMeta.Options) > 0 && cmdMeta.RunFunc.OptionsArgTypeNameStripped == "" {
-		return "", fmt.Errorf("OptionsArgTypeNameStripped is empty for command %s, but options are present. This indicates an issue with parsing the run function's options struct type", cmdMeta.Name)
-	}
+	return GenerateSubcommandsMain(cmdMeta, helpText, nil, generateFullFile)
+}
 
-	mainContent, err := generateMainContent(cmdMeta, helpText)
-	if err != nil {
-		return "", fmt.Errorf("generating main function content: %w", err)
+// GenerateSubcommandsMain is like GenerateMain, but also accepts the help texts of
+// cmdMeta.Subcommands (keyed by subcommand name).
+// If cmdMeta has no subcommands, subcommandHelpTexts is ignored and the result is the same as GenerateMain.
+func GenerateSubcommandsMain(cmdMeta *metadata.CommandMetadata, helpText string, subcommandHelpTexts map[string]string, generateFullFile bool) (string, error) {
+	var mainContent string
+	if len(cmdMeta.Subcommands) > 0 {
+		for _, sub := range cmdMeta.Subcommands {
+			if sub.RunFunc == nil {
+				return "", fmt.Errorf("subcommand %s of command %s has no run function", sub.Name, cmdMeta.Name)
+			}
+			if len(sub.Options) > 0 && sub.RunFunc.OptionsArgTypeNameStripped == "" {
+				return "", fmt.Errorf("OptionsArgTypeNameStripped is empty for subcommand %s, but options are present. This indicates an issue with parsing the run function's options struct type", sub.Name)
+			}
+		}
+		content, err := generateSubcommandsMainContent(cmdMeta, helpText, subcommandHelpTexts)
+		if err != nil {
+			return "", fmt.Errorf("generating main function content: %w", err)
+		}
+		mainContent = content
+	} else {
+		if len(cmdMeta.Options) > 0 && cmdMeta.RunFunc.OptionsArgTypeNameStripped == "" {
+			return "", fmt.Errorf("OptionsArgTypeNameStripped is empty for command %s, but options are present. This indicates an issue with parsing the run function's options struct type", cmdMeta.Name)
+		}
+		content, err := generateMainContent(cmdMeta, helpText)
+		if err != nil {
+			return "", fmt.Errorf("generating main function content: %w", err)
+		}
+		mainContent = content
 	}
 
 	if generateFullFile {
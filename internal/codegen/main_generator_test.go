@@ -3,11 +3,15 @@ package codegen // Changed from codegen_test
 import (
 	"fmt"
 	"go/format"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"testing"
 
 	"github.com/podhmo/goat/internal/metadata"
+	"golang.org/x/tools/imports"
 )
 
 var (
@@ -164,6 +168,71 @@ func assertCodeNotContains(t *testing.T, actualGeneratedCode, unexpectedSnippet
 	}
 }
 
+// assertCompiles checks that the generated main file compiles and passes go vet (e.g. the key-value pairs of slog),
+// together with the declarations of the options struct and the run function in decls, in a temporary module.
+// The imports are fixed like in WriteMain.
+func assertCompiles(t *testing.T, generatedCode string, decls string) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("Skipping the compilation of the generated code in short mode")
+	}
+	dir := t.TempDir()
+	mainCode, err := imports.Process(filepath.Join(dir, "main.go"), []byte(generatedCode), nil)
+	if err != nil {
+		t.Fatalf("Failed to process the imports of the generated code: %v\n%s", err, generatedCode)
+	}
+	files := map[string]string{
+		"go.mod":   "module example.com/app\n\ngo 1.23\n",
+		"main.go":  string(mainCode),
+		"decls.go": "package main\n\n" + decls,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	cmd := exec.Command("go", "vet", ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOWORK=off", "GOFLAGS=-mod=mod")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("The generated code does not compile or pass go vet: %v\n%s\nGenerated code:\n%s", err, out, generatedCode)
+	}
+}
+
+func TestGenerateMain_PassesVet(t *testing.T) {
+	minLen := 1
+	cmdMeta := &metadata.CommandMetadata{
+		RunFunc: &metadata.RunFuncInfo{
+			Name:                       "run",
+			PackageName:                "main",
+			OptionsArgTypeNameStripped: "Options",
+		},
+		Options: []*metadata.OptionMetadata{
+			{Name: "Name", CliName: "name", TypeName: "string", IsRequired: true, MinLen: &minLen, Pattern: "^[a-z]+$"},
+			{Name: "Token", CliName: "token", TypeName: "*string", IsPointer: true, IsRequired: true},
+			{Name: "Retries", CliName: "retries", TypeName: "*int", IsPointer: true, IsRequired: true},
+			{Name: "Mode", CliName: "mode", TypeName: "string", EnumValues: []any{"fast", "slow"}, DefaultValue: "fast"},
+			{Name: "Input", CliName: "input", TypeName: "string", IsFile: true, FileMustExist: true},
+			{Name: "OutputDir", CliName: "output-dir", TypeName: "string", IsDir: true, DirCreateIfMissing: true, DirPerm: 0o755},
+		},
+	}
+	actualCode, err := GenerateMain(cmdMeta, "", true)
+	if err != nil {
+		t.Fatalf("GenerateMain failed: %v", err)
+	}
+	assertCompiles(t, actualCode, `type Options struct {
+	Name      string
+	Token     *string
+	Retries   *int
+	Mode      string
+	Input     string
+	OutputDir string
+}
+
+func run(opts Options) error { return nil }
+`)
+}
+
 func TestGenerateMain_BasicCase(t *testing.T) {
 	cmdMeta := &metadata.CommandMetadata{
 		Name: "github.com/podhmo/goat/cmd/goat",
@@ -312,15 +381,41 @@ func TestGenerateMain_RequiredFlags(t *testing.T) {
 	assertCodeContains(t, actualCode, `initialDefaultConfigFile := ""`)
 	assertCodeContains(t, actualCode, `envConfigFileWasSet := false`)
 	assertCodeContains(t, actualCode, `if options.ConfigFile == initialDefaultConfigFile && !isFlagExplicitlySet["config-file"] && !envConfigFileWasSet {`)
-	assertCodeContains(t, actualCode, `slog.ErrorContext(ctx, "Missing required flag or environment variable not set", errors.New("Missing required flag or environment variable not set"), "flag", "config-file", "option", "ConfigFile")`)
+	assertCodeContains(t, actualCode, `slog.ErrorContext(ctx, "Missing required flag or environment variable not set", "error", errors.New("Missing required flag or environment variable not set"), "flag", "config-file", "option", "ConfigFile")`)
 	assertCodeContains(t, actualCode, `initialDefaultRetries := 0`)
 	assertCodeContains(t, actualCode, `envRetriesWasSet := false`)
 	assertCodeContains(t, actualCode, `if options.Retries == initialDefaultRetries && !isFlagExplicitlySet["retries"] && !envRetriesWasSet {`)
-	assertCodeContains(t, actualCode, `slog.ErrorContext(ctx, "Missing required flag or environment variable not set", errors.New("Missing required flag or environment variable not set"), "flag", "retries", "option", "Retries")`)
+	assertCodeContains(t, actualCode, `slog.ErrorContext(ctx, "Missing required flag or environment variable not set", "error", errors.New("Missing required flag or environment variable not set"), "flag", "retries", "option", "Retries")`)
 	assertCodeNotContains(t, actualCode, "var err error")
 	assertCodeContains(t, actualCode, "if err := DoSomething(*options); err != nil {")
 }
 
+func TestGenerateMain_RequiredWithDefaultValue(t *testing.T) {
+	cmdMeta := &metadata.CommandMetadata{
+		RunFunc: &metadata.RunFuncInfo{
+			Name:                       "run",
+			PackageName:                "main",
+			OptionsArgTypeNameStripped: "Options",
+		},
+		Options: []*metadata.OptionMetadata{
+			{Name: "Direction", CliName: "direction", TypeName: "string", IsRequired: true, DefaultValue: "up"},
+			{Name: "Port", CliName: "port", TypeName: "int", IsRequired: true, DefaultValue: 8080},
+			{Name: "Limit", CliName: "limit", TypeName: "int64", IsRequired: true, DefaultValue: int64(100)},
+			{Name: "Workers", CliName: "workers", TypeName: "int64", IsRequired: true},
+		},
+	}
+	actualCode, err := GenerateMain(cmdMeta, "", true)
+	if err != nil {
+		t.Fatalf("GenerateMain failed: %v", err)
+	}
+	// string and int options must be changed from their default values.
+	assertCodeContains(t, actualCode, `initialDefaultDirection := "up"`)
+	assertCodeContains(t, actualCode, `initialDefaultPort := 8080`)
+	// The other numeric types are not required to be given with a default value, like in the help message.
+	assertCodeNotContains(t, actualCode, `initialDefaultLimit`)
+	assertCodeContains(t, actualCode, `initialDefaultWorkers := int64(0)`)
+}
+
 func TestGenerateMain_EnumValidation(t *testing.T) {
 	cmdMeta := &metadata.CommandMetadata{
 		RunFunc: &metadata.RunFuncInfo{
@@ -350,7 +445,7 @@ func TestGenerateMain_EnumValidation(t *testing.T) {
 
 	if !isValidChoice_Mode {
 		var currentValueForMsg interface{} = options.Mode
-		slog.ErrorContext(ctx, "Invalid value for flag", errors.New("Invalid value for flag"), "flag", "mode", "value", currentValueForMsg, "allowedChoices", strings.Join(allowedChoices_Mode, ", "))
+		slog.ErrorContext(ctx, "Invalid value for flag", "error", errors.New("Invalid value for flag"), "flag", "mode", "value", currentValueForMsg, "allowedChoices", strings.Join(allowedChoices_Mode, ", "))
 		os.Exit(1)
 	}
 `
@@ -600,7 +695,7 @@ func TestGenerateMain_RequiredIntWithEnvVar(t *testing.T) {
 	envUserIdWasSet := false
 	if _, ok := os.LookupEnv("USER_ID"); ok { envUserIdWasSet = true }
 	if options.UserId == initialDefaultUserId && !isFlagExplicitlySet["user-id"] && !envUserIdWasSet {
-		slog.ErrorContext(ctx, "Missing required flag or environment variable not set", errors.New("Missing required flag or environment variable not set"), "flag", "user-id", "envVar", "USER_ID", "option", "UserId")
+		slog.ErrorContext(ctx, "Missing required flag or environment variable not set", "error", errors.New("Missing required flag or environment variable not set"), "flag", "user-id", "envVar", "USER_ID", "option", "UserId")
 		os.Exit(1)
 	}
 `
@@ -1088,3 +1183,1039 @@ func TestFormatHelpText(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerateSubcommandsMain(t *testing.T) {
+	cmdMeta := &metadata.CommandMetadata{
+		Name: "mytool",
+		Subcommands: []*metadata.CommandMetadata{
+			{
+				Name: "serve",
+				RunFunc: &metadata.RunFuncInfo{
+					Name:                       "runServe",
+					PackageName:                "main",
+					OptionsArgTypeNameStripped: "ServeOptions",
+					OptionsArgIsPointer:        true,
+					ContextArgName:             "ctx",
+					InitializerFunc:            "NewServeOptions",
+				},
+				Options: []*metadata.OptionMetadata{
+					{Name: "Port", CliName: "port", TypeName: "int", HelpText: "Port to listen on"},
+				},
+			},
+			{
+				Name: "migrate",
+				RunFunc: &metadata.RunFuncInfo{
+					Name:                       "runMigrate",
+					PackageName:                "main",
+					OptionsArgTypeNameStripped: "MigrateOptions",
+				},
+				Options: []*metadata.OptionMetadata{
+					{Name: "DryRun", CliName: "dry-run", TypeName: "bool", HelpText: "Dry run"},
+				},
+			},
+		},
+	}
+
+	actualCode, err := GenerateSubcommandsMain(cmdMeta, "mytool help", map[string]string{"serve": "mytool serve help"}, true)
+	if err != nil {
+		t.Fatalf("GenerateSubcommandsMain failed: %v", err)
+	}
+
+	assertCodeContains(t, actualCode, `flag.Usage = func() { fmt.Fprint(os.Stderr, "mytool help") }`)
+	assertCodeContains(t, actualCode, `if len(os.Args) < 2 { flag.Usage() os.Exit(2) }`)
+	assertCodeContains(t, actualCode, `switch os.Args[1] { case "-h", "--help", "help": flag.Usage() os.Exit(0)`)
+
+	// serve
+	assertCodeContains(t, actualCode, `case "serve": isFlagExplicitlySet := make(map[string]bool) fs := flag.NewFlagSet("serve", flag.ExitOnError) fs.Usage = func() { fmt.Fprint(os.Stderr, "mytool serve help") }`)
+	assertCodeContains(t, actualCode, `options := NewServeOptions()`)
+	assertCodeContains(t, actualCode, `fs.IntVar(&options.Port, "port", options.Port, "Port to listen on")`)
+	assertCodeContains(t, actualCode, `fs.Parse(os.Args[2:]) fs.Visit(func(f *flag.Flag) { isFlagExplicitlySet[f.Name] = true })`)
+	assertCodeContains(t, actualCode, `if err := runServe(ctx, options); err != nil {`)
+
+	// migrate (no help text is given, so the default usage of the flag set is kept)
+	assertCodeContains(t, actualCode, `case "migrate": isFlagExplicitlySet := make(map[string]bool) fs := flag.NewFlagSet("migrate", flag.ExitOnError)`)
+	assertCodeNotContains(t, actualCode, `fs := flag.NewFlagSet("migrate", flag.ExitOnError) fs.Usage`)
+	assertCodeContains(t, actualCode, `options := new(MigrateOptions)`)
+	assertCodeContains(t, actualCode, `fs.BoolVar(&options.DryRun, "dry-run", options.DryRun, "Dry run")`)
+	assertCodeContains(t, actualCode, `if err := runMigrate(*options); err != nil {`)
+
+	assertCodeContains(t, actualCode, `default: fmt.Fprintf(os.Stderr, "unknown command: %q\n\n", os.Args[1]) flag.Usage() os.Exit(2) }`)
+	assertCodeNotContains(t, actualCode, `flag.Parse()`)
+}
+
+func TestGenerateSubcommandsMain_MissingRunFunc(t *testing.T) {
+	cmdMeta := &metadata.CommandMetadata{
+		Name:        "mytool",
+		Subcommands: []*metadata.CommandMetadata{{Name: "serve"}},
+	}
+	if _, err := GenerateSubcommandsMain(cmdMeta, "", nil, true); err == nil {
+		t.Fatal("Expected an error for a subcommand without a run function")
+	}
+}
+
+func TestGenerateMain_WithGlobalOptions(t *testing.T) {
+	cmdMeta := &metadata.CommandMetadata{
+		Name: "globalcmd",
+		RunFunc: &metadata.RunFuncInfo{
+			Name:                             "run",
+			PackageName:                      "main",
+			ContextArgName:                   "ctx",
+			OptionsArgTypeNameStripped:       "ServeOptions",
+			GlobalOptionsArgTypeNameStripped: "GlobalOptions",
+			GlobalOptionsArgIsPointer:        true,
+			GlobalInitializerFunc:            "NewGlobalOptions",
+		},
+		Options: []*metadata.OptionMetadata{
+			{Name: "Port", CliName: "port", TypeName: "int", HelpText: "Port"},
+		},
+		GlobalOptions: []*metadata.OptionMetadata{
+			{Name: "Verbose", CliName: "verbose", TypeName: "bool", HelpText: "Verbose", EnvVar: "VERBOSE"},
+			{Name: "Config", CliName: "config", TypeName: "*string", HelpText: "Config", IsPointer: true},
+		},
+	}
+
+	actualCode, err := GenerateMain(cmdMeta, "", true)
+	if err != nil {
+		t.Fatalf("GenerateMain with global options failed: %v", err)
+	}
+
+	assertCodeContains(t, actualCode, `globalOptions := NewGlobalOptions()`)
+	assertCodeContains(t, actualCode, `options := new(ServeOptions)`)
+	assertCodeContains(t, actualCode, `if v, err := strconv.ParseBool(val); err == nil { globalOptions.Verbose = v }`)
+	assertCodeContains(t, actualCode, `flag.BoolVar(&globalOptions.Verbose, "verbose", globalOptions.Verbose, "Verbose" /* Env: VERBOSE */)`)
+	assertCodeContains(t, actualCode, `isGlobalConfigNilInitially := globalOptions.Config == nil`)
+	assertCodeContains(t, actualCode, `flag.StringVar(&tempGlobalConfigVal, "config", "", "Config")`)
+	assertCodeContains(t, actualCode, `flag.IntVar(&options.Port, "port", options.Port, "Port")`)
+	assertCodeContains(t, actualCode, `if isGlobalConfigNilInitially && isFlagExplicitlySet["config"] { globalOptions.Config = &tempGlobalConfigVal }`)
+	assertCodeContains(t, actualCode, `if err := run(ctx, globalOptions, *options); err != nil {`)
+}
+
+func TestGenerateSubcommandsMain_WithGlobalOptions(t *testing.T) {
+	cmdMeta := &metadata.CommandMetadata{
+		Name: "mytool",
+		GlobalOptions: []*metadata.OptionMetadata{
+			{Name: "Verbose", CliName: "verbose", TypeName: "bool", HelpText: "Verbose"},
+		},
+		Subcommands: []*metadata.CommandMetadata{
+			{
+				Name: "serve",
+				RunFunc: &metadata.RunFuncInfo{
+					Name:                             "runServe",
+					PackageName:                      "main",
+					OptionsArgTypeNameStripped:       "ServeOptions",
+					OptionsArgIsPointer:              true,
+					GlobalOptionsArgTypeNameStripped: "GlobalOptions",
+				},
+				Options: []*metadata.OptionMetadata{
+					{Name: "Port", CliName: "port", TypeName: "int", HelpText: "Port to listen on"},
+				},
+			},
+		},
+	}
+
+	actualCode, err := GenerateSubcommandsMain(cmdMeta, "mytool help", nil, true)
+	if err != nil {
+		t.Fatalf("GenerateSubcommandsMain with global options failed: %v", err)
+	}
+
+	// Global flags are parsed by the top-level flag set, before dispatching.
+	assertCodeContains(t, actualCode, `globalOptions := new(GlobalOptions)`)
+	assertCodeContains(t, actualCode, `flag.BoolVar(&globalOptions.Verbose, "verbose", globalOptions.Verbose, "Verbose")`)
+	assertCodeContains(t, actualCode, `flag.Parse() flag.Visit(func(f *flag.Flag) { isFlagExplicitlySet[f.Name] = true }) args := flag.Args()`)
+	assertCodeContains(t, actualCode, `if len(args) < 1 { flag.Usage() os.Exit(2) }`)
+	assertCodeContains(t, actualCode, `switch args[0] {`)
+	assertCodeContains(t, actualCode, `fs.Parse(args[1:])`)
+	assertCodeContains(t, actualCode, `if err := runServe(*globalOptions, options); err != nil {`)
+	assertCodeContains(t, actualCode, `fmt.Fprintf(os.Stderr, "unknown command: %q\n\n", args[0])`)
+	assertCodeNotContains(t, actualCode, `fs.BoolVar(&globalOptions.Verbose`)
+}
+
+func TestGenerateMain_PositionalArgs(t *testing.T) {
+	cmdMeta := &metadata.CommandMetadata{
+		Name: "cp",
+		RunFunc: &metadata.RunFuncInfo{
+			Name:                       "run",
+			PackageName:                "main",
+			OptionsArgTypeNameStripped: "Options",
+			OptionsArgIsPointer:        true,
+		},
+		Options: []*metadata.OptionMetadata{
+			{Name: "Src", CliName: "src", TypeName: "string", IsRequired: true, EnvVar: "SRC", Positional: &metadata.PositionalArg{Index: 0}},
+			{Name: "Mode", CliName: "mode", TypeName: "*Mode", IsPointer: true, UnderlyingKind: "string", Positional: &metadata.PositionalArg{Index: 1}},
+			{Name: "Verbose", CliName: "verbose", TypeName: "bool", HelpText: "Verbose"},
+		},
+	}
+
+	actualCode, err := GenerateMain(cmdMeta, "", true)
+	if err != nil {
+		t.Fatalf("GenerateMain with positional arguments failed: %v", err)
+	}
+
+	assertCodeContains(t, actualCode, `positionalArgs := flag.Args()`)
+	assertCodeContains(t, actualCode, `if len(positionalArgs) < 1 { slog.ErrorContext(ctx, "Missing required positional arguments", "error", errors.New("Missing required positional arguments"), "min", 1, "got", len(positionalArgs)) os.Exit(1) }`)
+	assertCodeContains(t, actualCode, `if len(positionalArgs) > 2 { slog.ErrorContext(ctx, "Too many positional arguments", "error", errors.New("Too many positional arguments"), "max", 2, "got", len(positionalArgs)) os.Exit(1) }`)
+	assertCodeContains(t, actualCode, `options.Src = positionalArgs[0]`)
+	assertCodeContains(t, actualCode, `if len(positionalArgs) > 1 { v := Mode(positionalArgs[1]) options.Mode = &v }`)
+	assertCodeContains(t, actualCode, `flag.BoolVar(&options.Verbose, "verbose", options.Verbose, "Verbose")`)
+
+	// Positional arguments are neither flags nor environment variables.
+	assertCodeNotContains(t, actualCode, `"src"`)
+	assertCodeNotContains(t, actualCode, `os.LookupEnv("SRC")`)
+	assertCodeNotContains(t, actualCode, `options.Mode = new(Mode)`)
+}
+
+func TestGenerateMain_PositionalArgs_Rest(t *testing.T) {
+	tests := []struct {
+		arity       string
+		contains    []string
+		notContains []string
+	}{
+		{arity: "*", notContains: []string{`"min"`, `"max"`}},
+		{arity: "+", contains: []string{`if len(positionalArgs) < 1 {`}, notContains: []string{`"max"`}},
+		{arity: "2", contains: []string{`if len(positionalArgs) < 2 {`, `if len(positionalArgs) > 2 {`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.arity, func(t *testing.T) {
+			cmdMeta := &metadata.CommandMetadata{
+				Name: "cat",
+				RunFunc: &metadata.RunFuncInfo{
+					Name:                       "run",
+					PackageName:                "main",
+					OptionsArgTypeNameStripped: "Options",
+				},
+				Options: []*metadata.OptionMetadata{
+					{Name: "Files", CliName: "files", TypeName: "[]string", IsRequired: true, Positional: &metadata.PositionalArg{Arity: tt.arity}},
+				},
+			}
+			actualCode, err := GenerateMain(cmdMeta, "", true)
+			if err != nil {
+				t.Fatalf("GenerateMain with positional arguments failed: %v", err)
+			}
+			assertCodeContains(t, actualCode, `if len(positionalArgs) > 0 { options.Files = positionalArgs[0:] }`)
+			for _, s := range tt.contains {
+				assertCodeContains(t, actualCode, s)
+			}
+			for _, s := range tt.notContains {
+				assertCodeNotContains(t, actualCode, s)
+			}
+		})
+	}
+}
+
+func TestGenerateMain_FlagAliases(t *testing.T) {
+	cmdMeta := &metadata.CommandMetadata{
+		Name: "app",
+		RunFunc: &metadata.RunFuncInfo{
+			Name:                       "run",
+			PackageName:                "main",
+			OptionsArgTypeNameStripped: "Options",
+		},
+		Options: []*metadata.OptionMetadata{
+			{Name: "Verbose", CliName: "verbose", TypeName: "bool", ShortName: "v"},
+			{Name: "OutputDir", CliName: "output-dir", TypeName: "*string", IsPointer: true, Aliases: []string{"out", "o-dir"}},
+			{Name: "Color", CliName: "color", TypeName: "bool", IsRequired: true, DefaultValue: true, ShortName: "c"},
+		},
+	}
+	actualCode, err := GenerateMain(cmdMeta, "", true)
+	if err != nil {
+		t.Fatalf("GenerateMain with flag aliases failed: %v", err)
+	}
+
+	assertCodeContains(t, actualCode, `flag.Var(flag.Lookup("verbose").Value, "v", "Alias for --verbose")`)
+	assertCodeContains(t, actualCode, `flag.Var(flag.Lookup("output-dir").Value, "out", "Alias for --output-dir")`)
+	assertCodeContains(t, actualCode, `flag.Var(flag.Lookup("output-dir").Value, "o-dir", "Alias for --output-dir")`)
+	assertCodeContains(t, actualCode, `flag.Var(flag.Lookup("no-color").Value, "no-c", "Alias for --no-color")`)
+	assertCodeContains(t, actualCode, `isFlagExplicitlySet["verbose"] = isFlagExplicitlySet["verbose"] || isFlagExplicitlySet["v"]`)
+	assertCodeContains(t, actualCode, `isFlagExplicitlySet["output-dir"] = isFlagExplicitlySet["output-dir"] || isFlagExplicitlySet["out"]`)
+	assertCodeContains(t, actualCode, `if isOutputDirNilInitially && isFlagExplicitlySet["output-dir"] {`)
+}
+
+func TestGenerateMain_ValueConstraints(t *testing.T) {
+	minLen, maxLen := 3, 20
+	cmdMeta := &metadata.CommandMetadata{
+		Name: "app",
+		RunFunc: &metadata.RunFuncInfo{
+			Name:                       "run",
+			PackageName:                "main",
+			OptionsArgTypeNameStripped: "Options",
+		},
+		Options: []*metadata.OptionMetadata{
+			{Name: "Port", CliName: "port", TypeName: "int", DefaultValue: 8080, MinValue: int64(1), MaxValue: int64(65535)},
+			{Name: "Workers", CliName: "workers", TypeName: "*int", IsPointer: true, MinValue: int64(1)},
+			{Name: "Name", CliName: "name", TypeName: "string", MinLen: &minLen, MaxLen: &maxLen},
+			{Name: "Label", CliName: "label", TypeName: "Label", UnderlyingKind: "string", MaxLen: &maxLen},
+			{Name: "Tags", CliName: "tags", TypeName: "[]string", MaxLen: &maxLen},
+		},
+	}
+	actualCode, err := GenerateMain(cmdMeta, "", true)
+	if err != nil {
+		t.Fatalf("GenerateMain with value constraints failed: %v", err)
+	}
+
+	assertCodeContains(t, actualCode, `if options.Port < 1 || options.Port > 65535 {
+		slog.ErrorContext(ctx, "Value out of range for flag", "error", errors.New("Value out of range for flag"), "flag", "port", "value", options.Port, "range", "1..65535")
+		os.Exit(1)
+	}`)
+	assertCodeContains(t, actualCode, `if options.Workers != nil && (*options.Workers < 1) {`)
+	// The length of a string is the number of characters, and the length of a slice is the number of items.
+	assertCodeContains(t, actualCode, `if utf8.RuneCountInString(options.Name) < 3 || utf8.RuneCountInString(options.Name) > 20 {
+		slog.ErrorContext(ctx, "Invalid length of value for flag", "error", errors.New("Invalid length of value for flag"), "flag", "name", "length", utf8.RuneCountInString(options.Name), "allowed", "3..20")`)
+	assertCodeContains(t, actualCode, `if utf8.RuneCountInString(string(options.Label)) > 20 {`)
+	assertCodeContains(t, actualCode, `if len(options.Tags) > 20 {`)
+	assertCompiles(t, actualCode, `type Label string
+
+type Options struct {
+	Port    int
+	Workers *int
+	Name    string
+	Label   Label
+	Tags    []string
+}
+
+func run(opts Options) error { return nil }
+`)
+}
+
+func TestGenerateMain_PatternConstraint(t *testing.T) {
+	cmdMeta := &metadata.CommandMetadata{
+		Name: "app",
+		RunFunc: &metadata.RunFuncInfo{
+			Name:                       "run",
+			PackageName:                "main",
+			OptionsArgTypeNameStripped: "Options",
+		},
+		Options: []*metadata.OptionMetadata{
+			{Name: "Name", CliName: "name", TypeName: "string", EnvVar: "APP_NAME", DefaultValue: "app", Pattern: "^[a-z0-9-]+$"},
+			{Name: "Tag", CliName: "tag", TypeName: "*string", IsPointer: true, Pattern: `^v\d+$`},
+		},
+	}
+	actualCode, err := GenerateMain(cmdMeta, "", true)
+	if err != nil {
+		t.Fatalf("GenerateMain with pattern constraints failed: %v", err)
+	}
+
+	assertCodeContains(t, actualCode, `if !regexp.MustCompile("^[a-z0-9-]+$").MatchString(options.Name) {
+		slog.ErrorContext(ctx, "Value does not match the pattern for flag", "error", errors.New("Value does not match the pattern for flag"), "flag", "name", "envVar", "APP_NAME", "value", options.Name, "pattern", "^[a-z0-9-]+$")
+		os.Exit(1)
+	}`)
+	assertCodeContains(t, actualCode, `if options.Tag != nil && !regexp.MustCompile("^v\\d+$").MatchString(*options.Tag) {`)
+}
+
+func TestGenerateMain_FileOptions(t *testing.T) {
+	cmdMeta := &metadata.CommandMetadata{
+		Name: "app",
+		RunFunc: &metadata.RunFuncInfo{
+			Name:                       "run",
+			PackageName:                "main",
+			OptionsArgTypeNameStripped: "Options",
+		},
+		Options: []*metadata.OptionMetadata{
+			{Name: "Config", CliName: "config", TypeName: "string", DefaultValue: "config.json", FileMustExist: true},
+			{Name: "Output", CliName: "output", TypeName: "*string", IsPointer: true, FileMustExist: true},
+			{Name: "Pattern", CliName: "pattern", TypeName: "string", DefaultValue: "*.go", FileGlobPattern: true},
+			{Name: "Inputs", CliName: "inputs", TypeName: "[]string", FileMustExist: true, FileGlobPattern: true, Positional: &metadata.PositionalArg{Arity: "*"}},
+		},
+	}
+	actualCode, err := GenerateMain(cmdMeta, "", true)
+	if err != nil {
+		t.Fatalf("GenerateMain with file options failed: %v", err)
+	}
+
+	assertCodeContains(t, actualCode, `if options.Config != "" && options.Config != "-" {
+		if _, err := os.Stat(options.Config); err != nil {
+			slog.ErrorContext(ctx, "File does not exist", "error", err, "flag", "config", "path", options.Config)
+			os.Exit(1)
+		}
+	}`)
+	assertCodeContains(t, actualCode, `if options.Output != nil && *options.Output != "" && *options.Output != "-" {
+		if _, err := os.Stat(*options.Output); err != nil {`)
+	assertCodeContains(t, actualCode, `if _, err := filepath.Match(options.Pattern, ""); err != nil {`)
+	assertCodeContains(t, actualCode, `var expandedInputs []string
+	for _, pattern := range options.Inputs {
+		matches, err := filepath.Glob(pattern)`)
+	assertCodeContains(t, actualCode, `options.Inputs = expandedInputs
+	for _, path := range options.Inputs {
+		if path == "-" {
+			continue
+		}`)
+}
+
+func TestGenerateMain_DirOptions(t *testing.T) {
+	cmdMeta := &metadata.CommandMetadata{
+		Name: "app",
+		RunFunc: &metadata.RunFuncInfo{
+			Name:                       "run",
+			PackageName:                "main",
+			OptionsArgTypeNameStripped: "Options",
+		},
+		Options: []*metadata.OptionMetadata{
+			{Name: "OutputDir", CliName: "output-dir", TypeName: "string", DefaultValue: "output", IsDir: true, DirCreateIfMissing: true, DirPerm: 0o755},
+			{Name: "InputDir", CliName: "input-dir", TypeName: "*string", IsPointer: true, IsDir: true, DirMustExist: true},
+		},
+	}
+	actualCode, err := GenerateMain(cmdMeta, "", true)
+	if err != nil {
+		t.Fatalf("GenerateMain with directory options failed: %v", err)
+	}
+
+	assertCodeContains(t, actualCode, `if options.InputDir != nil && *options.InputDir != "" {
+		info, err := os.Stat(*options.InputDir)
+		if err != nil {
+			slog.ErrorContext(ctx, "Directory does not exist", "error", err, "flag", "input-dir", "path", *options.InputDir)
+			os.Exit(1)
+		}
+		if !info.IsDir() {`)
+	assertCodeContains(t, actualCode, `if options.OutputDir != "" {
+		if err := os.MkdirAll(options.OutputDir, 0o755); err != nil {
+			slog.ErrorContext(ctx, "Could not create directory", "error", err, "flag", "output-dir", "path", options.OutputDir)
+			os.Exit(1)
+		}
+	}
+	if err := run(*options); err != nil {`)
+}
+
+func TestGenerateMain_OptionGroups(t *testing.T) {
+	cmdMeta := &metadata.CommandMetadata{
+		Name: "app",
+		RunFunc: &metadata.RunFuncInfo{
+			Name:                       "run",
+			PackageName:                "main",
+			OptionsArgTypeNameStripped: "Options",
+		},
+		Options: []*metadata.OptionMetadata{
+			{Name: "JSON", CliName: "json", TypeName: "bool"},
+			{Name: "YAML", CliName: "yaml", TypeName: "bool", EnvVar: "APP_YAML"},
+			{Name: "TLSCert", CliName: "tls-cert", TypeName: "string"},
+			{Name: "TLSKey", CliName: "tls-key", TypeName: "string"},
+		},
+		OptionGroups: []*metadata.OptionGroup{
+			{Kind: metadata.OptionGroupOneOf, Options: []string{"JSON", "YAML"}},
+			{Kind: metadata.OptionGroupTogether, Options: []string{"TLSCert", "TLSKey"}},
+		},
+	}
+	actualCode, err := GenerateMain(cmdMeta, "", true)
+	if err != nil {
+		t.Fatalf("GenerateMain with option groups failed: %v", err)
+	}
+
+	assertCodeContains(t, actualCode, `var givenGroup0 []string
+	if isFlagExplicitlySet["json"] {
+		givenGroup0 = append(givenGroup0, "--json")
+	}
+	if _, ok := os.LookupEnv("APP_YAML"); ok || isFlagExplicitlySet["yaml"] {
+		givenGroup0 = append(givenGroup0, "--yaml")
+	}
+	if len(givenGroup0) > 1 {
+		slog.ErrorContext(ctx, "Mutually exclusive flags were given together", "error", errors.New("Mutually exclusive flags were given together"), "flags", strings.Join(givenGroup0, ", "))
+		os.Exit(1)
+	}`)
+	assertCodeContains(t, actualCode, `if len(givenGroup1) > 0 && len(givenGroup1) < 2 {
+		slog.ErrorContext(ctx, "Flags must be given together", "error", errors.New("Flags must be given together"), "flags", "--tls-cert, --tls-key", "given", strings.Join(givenGroup1, ", "))
+		os.Exit(1)
+	}`)
+}
+
+func TestGenerateMain_ValidateMethod(t *testing.T) {
+	cmdMeta := &metadata.CommandMetadata{
+		Name: "app",
+		RunFunc: &metadata.RunFuncInfo{
+			Name:                       "run",
+			PackageName:                "main",
+			OptionsArgTypeNameStripped: "Options",
+			OptionsHasValidate:         true,
+		},
+		Options: []*metadata.OptionMetadata{
+			{Name: "Mode", CliName: "mode", TypeName: "string", EnumValues: []any{"a", "b"}},
+		},
+	}
+	actualCode, err := GenerateMain(cmdMeta, "", true)
+	if err != nil {
+		t.Fatalf("GenerateMain with Validate method failed: %v", err)
+	}
+
+	// Validate is called after the enum validation, and before the run function.
+	assertCodeContains(t, actualCode, `if !isValidChoice_Mode {
+		var currentValueForMsg interface{} = options.Mode // options.OptName
+		slog.ErrorContext(ctx, "Invalid value for flag", "error", errors.New("Invalid value for flag"), "flag", "mode", "value", currentValueForMsg, "allowedChoices", strings.Join(allowedChoices_Mode, ", "))
+		os.Exit(1)
+	}
+
+	if err := options.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid options: %v\n\n", err)
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := run(*options); err != nil {`)
+
+	cmdMeta.RunFunc.OptionsHasValidate = false
+	actualCode, err = GenerateMain(cmdMeta, "", true)
+	if err != nil {
+		t.Fatalf("GenerateMain without Validate method failed: %v", err)
+	}
+	assertCodeNotContains(t, actualCode, "options.Validate()")
+}
+
+func TestGenerateMain_NumericTypes(t *testing.T) {
+	cmdMeta := &metadata.CommandMetadata{
+		Name: "app",
+		RunFunc: &metadata.RunFuncInfo{
+			Name:                       "run",
+			PackageName:                "main",
+			OptionsArgTypeNameStripped: "Options",
+		},
+		Options: []*metadata.OptionMetadata{
+			{Name: "Timeout", CliName: "timeout", TypeName: "time.Duration", DefaultValue: "5s", EnvVar: "APP_TIMEOUT", IsRequired: true},
+			{Name: "Ratio", CliName: "ratio", TypeName: "float64", IsRequired: true},
+			{Name: "Small", CliName: "small", TypeName: "int8", EnvVar: "APP_SMALL"},
+			{Name: "Scale", CliName: "scale", TypeName: "*float64", IsPointer: true},
+			{Name: "Level", CliName: "level", TypeName: "*uint8", IsPointer: true},
+		},
+	}
+	actualCode, err := GenerateMain(cmdMeta, "", true)
+	if err != nil {
+		t.Fatalf("GenerateMain with numeric types failed: %v", err)
+	}
+
+	// Defaults, without initializer
+	assertCodeContains(t, actualCode, `options.Timeout = time.Duration(5000000000)`)
+	assertCodeContains(t, actualCode, `options.Scale = new(float64)`)
+
+	// Environment variables
+	assertCodeContains(t, actualCode, `if val, ok := os.LookupEnv("APP_TIMEOUT"); ok {
+		if v, err := time.ParseDuration(val); err == nil {
+			options.Timeout = v
+		} else {
+			slog.WarnContext(ctx, "Could not parse environment variable as time.Duration for option", "envVar", "APP_TIMEOUT", "option", "Timeout", "value", val, "error", err)
+		}
+	}`)
+	assertCodeContains(t, actualCode, `if v, err := strconv.ParseInt(val, 0, 8); err == nil {
+			options.Small = int8(v)`)
+
+	// Flags
+	assertCodeContains(t, actualCode, `flag.DurationVar(&options.Timeout, "timeout", options.Timeout, "" /* Original Default: 5s, Env: APP_TIMEOUT */)`)
+	assertCodeContains(t, actualCode, `flag.Float64Var(&options.Ratio, "ratio", options.Ratio, "")`)
+	assertCodeContains(t, actualCode, `flag.Func("small", "", func(s string) error {
+		v, err := strconv.ParseInt(s, 0, 8)
+		if err != nil {
+			return err
+		}
+		options.Small = int8(v)
+		return nil
+	})`)
+	assertCodeContains(t, actualCode, `if isScaleNilInitially {
+		flag.Float64Var(&tempScaleVal, "scale", 0, "")
+	} else {
+		flag.Float64Var(options.Scale, "scale", *options.Scale, "")
+	}`)
+	assertCodeContains(t, actualCode, `if isLevelNilInitially {
+			tempLevelVal = uint8(v)
+		} else {
+			*options.Level = uint8(v)
+		}`)
+	assertCodeContains(t, actualCode, `if isLevelNilInitially && isFlagExplicitlySet["level"] {
+		options.Level = &tempLevelVal
+	}`)
+
+	// Required checks: only for the option without a default value
+	assertCodeContains(t, actualCode, `initialDefaultRatio := float64(0)`)
+	assertCodeNotContains(t, actualCode, `initialDefaultTimeout`)
+}
+
+func TestGenerateMain_SliceOptions(t *testing.T) {
+	cmdMeta := &metadata.CommandMetadata{
+		Name: "app",
+		RunFunc: &metadata.RunFuncInfo{
+			Name:                       "run",
+			PackageName:                "main",
+			OptionsArgTypeNameStripped: "Options",
+			InitializerFunc:            "NewOptions",
+		},
+		Options: []*metadata.OptionMetadata{
+			{Name: "Tag", CliName: "tag", TypeName: "[]string", DefaultValue: []any{"a", "b"}, EnvVar: "APP_TAGS", Aliases: []string{"t"}},
+			{Name: "Port", CliName: "port", TypeName: "[]int", EnvVar: "APP_PORTS"},
+			{Name: "Level", CliName: "level", TypeName: "[]Level", ElemIsTextUnmarshaler: true},
+			{Name: "Ratio", CliName: "ratio", TypeName: "[]float32"},
+			{Name: "Point", CliName: "point", TypeName: "[]Point"}, // Not parsable: no flag
+		},
+	}
+	actualCode, err := GenerateMain(cmdMeta, "", true)
+	if err != nil {
+		t.Fatalf("GenerateMain with slice options failed: %v", err)
+	}
+
+	// Flags: the first occurrence replaces the default value
+	assertCodeContains(t, actualCode, `var isTagSetByFlag bool
+	flag.Func("tag", "" /* Original Default: [a b], Env: APP_TAGS */, func(s string) error {
+		if !isTagSetByFlag {
+			isTagSetByFlag = true
+			options.Tag = nil // Replaces the default value (or the value from the environment variable).
+		}
+		for _, item := range strings.Split(s, ",") {
+			v := item
+			options.Tag = append(options.Tag, v)
+		}
+		return nil
+	})`)
+	assertCodeContains(t, actualCode, `flag.Var(flag.Lookup("tag").Value, "t", "Alias for --tag")`)
+	assertCodeContains(t, actualCode, `v, err := strconv.Atoi(item)
+			if err != nil {
+				return err
+			}
+			options.Port = append(options.Port, v)`)
+	assertCodeContains(t, actualCode, `var v Level
+			if err := v.UnmarshalText([]byte(item)); err != nil {
+				return err
+			}
+			options.Level = append(options.Level, v)`)
+	assertCodeContains(t, actualCode, `parsed, err := strconv.ParseFloat(item, 32)
+			if err != nil {
+				return err
+			}
+			v := float32(parsed)`)
+	assertCodeNotContains(t, actualCode, `flag.Func("point"`)
+
+	// Environment variables: assigned only if all the items are parsed
+	assertCodeContains(t, actualCode, `options.Tag = strings.Split(val, ",")`)
+	assertCodeContains(t, actualCode, `var parsedPort []int
+		var parseErr error
+		for _, item := range strings.Split(val, ",") {
+			v, err := strconv.Atoi(item)
+			if err != nil {
+				parseErr = err
+				break
+			}
+			parsedPort = append(parsedPort, v)
+		}
+		if parseErr == nil {
+			options.Port = parsedPort
+		} else {
+			slog.WarnContext(ctx, "Could not parse environment variable as []int for option", "envVar", "APP_PORTS", "option", "Port", "value", val, "error", parseErr)
+		}`)
+}
+
+func TestGenerateMain_MapOptions(t *testing.T) {
+	cmdMeta := &metadata.CommandMetadata{
+		Name: "app",
+		RunFunc: &metadata.RunFuncInfo{
+			Name:                       "run",
+			PackageName:                "main",
+			OptionsArgTypeNameStripped: "Options",
+			InitializerFunc:            "NewOptions",
+		},
+		Options: []*metadata.OptionMetadata{
+			{Name: "Label", CliName: "label", TypeName: "Labels", UnderlyingKind: "map[string]string", EnvVar: "LABELS"},
+			{Name: "Limit", CliName: "limit", TypeName: "map[string]int", UnderlyingKind: "map[string]int"},
+			{Name: "Weight", CliName: "weight", TypeName: "map[string]float64", UnderlyingKind: "map[string]float64"}, // Not supported: no flag
+		},
+	}
+	actualCode, err := GenerateMain(cmdMeta, "", true)
+	if err != nil {
+		t.Fatalf("GenerateMain with map options failed: %v", err)
+	}
+
+	// Flags: one entry per occurrence, and the first occurrence replaces the default value
+	assertCodeContains(t, actualCode, `var isLabelSetByFlag bool
+	flag.Func("label", "" /* Env: LABELS */, func(item string) error {
+		if !isLabelSetByFlag {
+			isLabelSetByFlag = true
+			options.Label = make(Labels) // Replaces the default value (or the value from the environment variable).
+		}
+		key, value, ok := strings.Cut(item, "=")
+		if !ok {
+			err := fmt.Errorf("%q is not in the key=value form", item)
+			return err
+		}
+		v := value
+		options.Label[key] = v
+		return nil
+	})`)
+	assertCodeContains(t, actualCode, `v, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		options.Limit[key] = v`)
+	assertCodeNotContains(t, actualCode, `flag.Func("weight"`)
+
+	// Environment variables: comma-separated entries
+	assertCodeContains(t, actualCode, `parsedLabel := make(Labels)
+		var parseErr error
+		for _, item := range strings.Split(val, ",") {
+			key, value, ok := strings.Cut(item, "=")
+			if !ok {
+				err := fmt.Errorf("%q is not in the key=value form", item)
+				parseErr = err
+				break
+			}
+			v := value
+			parsedLabel[key] = v
+		}
+		if parseErr == nil {
+			options.Label = parsedLabel
+		} else {
+			slog.WarnContext(ctx, "Could not parse environment variable as Labels for option", "envVar", "LABELS", "option", "Label", "value", val, "error", parseErr)
+		}`)
+}
+
+func TestGenerateMain_NestedStructOptions(t *testing.T) {
+	cmdMeta := &metadata.CommandMetadata{
+		Name: "app",
+		RunFunc: &metadata.RunFuncInfo{
+			Name:                       "run",
+			PackageName:                "main",
+			OptionsArgTypeNameStripped: "Options",
+		},
+		Options: []*metadata.OptionMetadata{
+			{Name: "DB.Host", CliName: "db-host", TypeName: "string", DefaultValue: "localhost", EnvVar: "APP_DB_HOST", IsRequired: true, Section: "Database"},
+			{Name: "DB.TLS.Cert", CliName: "db-tls-cert", TypeName: "*string", IsPointer: true, Section: "Database"},
+		},
+	}
+	actualCode, err := GenerateMain(cmdMeta, "", true)
+	if err != nil {
+		t.Fatalf("GenerateMain with nested struct options failed: %v", err)
+	}
+
+	assertCodeContains(t, actualCode, `options.DB.Host = "localhost"`)
+	assertCodeContains(t, actualCode, `if val, ok := os.LookupEnv("APP_DB_HOST"); ok {
+		options.DB.Host = val
+	}`)
+	assertCodeContains(t, actualCode, `flag.StringVar(&options.DB.Host, "db-host", options.DB.Host, "" /* Original Default: localhost, Env: APP_DB_HOST */)`)
+	// The local identifiers join the field path
+	assertCodeContains(t, actualCode, `initialDefaultDBHost := "localhost"`)
+	assertCodeContains(t, actualCode, `if isDBTLSCertNilInitially && isFlagExplicitlySet["db-tls-cert"] {
+		options.DB.TLS.Cert = &tempDBTLSCertVal
+	}`)
+}
+
+func TestGenerateMain_TagDefaultsWithoutInitializer(t *testing.T) {
+	cmdMeta := &metadata.CommandMetadata{
+		Name: "app",
+		RunFunc: &metadata.RunFuncInfo{
+			Name:                       "run",
+			PackageName:                "main",
+			OptionsArgTypeNameStripped: "Options",
+		},
+		Options: []*metadata.OptionMetadata{
+			{Name: "Tags", CliName: "tags", TypeName: "[]string", DefaultValue: []any{"a", "b"}},
+			{Name: "Waits", CliName: "waits", TypeName: "[]time.Duration", DefaultValue: []any{"1s"}},
+			{Name: "Labels", CliName: "labels", TypeName: "map[string]int", UnderlyingKind: "map[string]int", DefaultValue: map[string]any{"y": 2, "x": 1}},
+			{Name: "Mode", CliName: "mode", TypeName: "Mode", UnderlyingKind: "string", DefaultValue: "fast"},
+			{Name: "Host", CliName: "host", TypeName: "net.IP", IsTextUnmarshaler: true, IsTextMarshaler: true, DefaultValue: "127.0.0.1"},
+		},
+	}
+	actualCode, err := GenerateMain(cmdMeta, "", true)
+	if err != nil {
+		t.Fatalf("GenerateMain with tag defaults failed: %v", err)
+	}
+
+	assertCodeContains(t, actualCode, `options.Tags = []string{"a", "b"}`)
+	assertCodeContains(t, actualCode, `options.Waits = []time.Duration{time.Duration(1000000000)}`)
+	assertCodeContains(t, actualCode, `options.Labels = map[string]int{"x": 1, "y": 2}`)
+	assertCodeContains(t, actualCode, `options.Mode = Mode("fast")`)
+	assertCodeContains(t, actualCode, `if err := (&options.Host).UnmarshalText([]byte("127.0.0.1")); err != nil {
+		slog.ErrorContext(ctx, "Invalid default value for option", "error", err, "option", "Host")
+		os.Exit(1)
+	}`)
+}
+
+func TestGenerateMain_ConfigFile(t *testing.T) {
+	cmdMeta := &metadata.CommandMetadata{
+		Name: "app",
+		RunFunc: &metadata.RunFuncInfo{
+			Name:                       "run",
+			PackageName:                "main",
+			OptionsArgTypeNameStripped: "Options",
+		},
+		Options: []*metadata.OptionMetadata{
+			{Name: "Config", CliName: "config", TypeName: "string", EnvVar: "APP_CONFIG", IsConfigFile: true, DefaultValue: "app.json"},
+			{Name: "Name", CliName: "name", TypeName: "string", EnvVar: "APP_NAME", IsRequired: true, ConfigKey: "userName"},
+			{Name: "Token", CliName: "token", TypeName: "string", ConfigKey: "-"},
+			{Name: "Timeout", CliName: "timeout", TypeName: "*time.Duration", IsPointer: true},
+			{Name: "DB.Port", CliName: "db-port", TypeName: "int"},
+		},
+	}
+	actualCode, err := GenerateMain(cmdMeta, "", true)
+	if err != nil {
+		t.Fatalf("GenerateMain with a config file failed: %v", err)
+	}
+
+	assertCodeContains(t, actualCode, `configPath := options.Config
+	if configPath != "" {
+		isConfigPathGiven := false
+		if _, ok := os.LookupEnv("APP_CONFIG"); ok || isFlagExplicitlySet["config"] {
+			isConfigPathGiven = true
+		}`)
+	assertCodeContains(t, actualCode, `} else if os.IsNotExist(err) && !isConfigPathGiven {
+			err = nil // The config file of the default path is optional.
+		}`)
+	assertCodeContains(t, actualCode, `case "userName":
+				if _, ok := os.LookupEnv("APP_NAME"); ok || isFlagExplicitlySet["name"] {
+					continue // Environment variables and flags take precedence.
+				}
+				var v string
+				if err = json.Unmarshal(value, &v); err == nil {
+					options.Name = v
+				}
+				isFlagExplicitlySet["name"] = true`)
+	assertCodeContains(t, actualCode, `case "timeout":
+				if isFlagExplicitlySet["timeout"] {
+					continue // Environment variables and flags take precedence.
+				}
+				options.Timeout = new(time.Duration)
+				var s string
+				if err = json.Unmarshal(value, &s); err == nil {
+					*options.Timeout, err = time.ParseDuration(s)
+				}`)
+	assertCodeContains(t, actualCode, `case "db-port":`)
+	assertCodeNotContains(t, actualCode, `case "token":`)
+	assertCodeNotContains(t, actualCode, `case "config":`)
+	assertCodeContains(t, actualCode, `default:
+				unknownConfigKeys = append(unknownConfigKeys, key)`)
+
+	// The config file is loaded before the required checks, which count its values as given.
+	if strings.Index(actualCode, "configPath := options.Config") > strings.Index(actualCode, "initialDefaultName :=") {
+		t.Errorf("Expected the config file to be loaded before the required checks:\n%s", actualCode)
+	}
+}
+
+func TestGenerateMain_ConfigFileMapWithDefault(t *testing.T) {
+	cmdMeta := &metadata.CommandMetadata{
+		Name: "app",
+		RunFunc: &metadata.RunFuncInfo{
+			Name:                       "run",
+			PackageName:                "main",
+			OptionsArgTypeNameStripped: "Options",
+		},
+		Options: []*metadata.OptionMetadata{
+			{Name: "Config", CliName: "config", TypeName: "string", IsConfigFile: true, DefaultValue: "app.json"},
+			{Name: "Labels", CliName: "labels", TypeName: "map[string]string", UnderlyingKind: "map[string]string", DefaultValue: map[string]any{"a": "1"}},
+		},
+	}
+	actualCode, err := GenerateMain(cmdMeta, "", true)
+	if err != nil {
+		t.Fatalf("GenerateMain with a config file failed: %v", err)
+	}
+
+	assertCodeContains(t, actualCode, `options.Labels = map[string]string{"a": "1"}`)
+	// The value of the config file replaces the default, like flags and environment variables.
+	assertCodeContains(t, actualCode, `case "labels":
+				if isFlagExplicitlySet["labels"] {
+					continue // Environment variables and flags take precedence.
+				}
+				var v map[string]string
+				if err = json.Unmarshal(value, &v); err == nil {
+					options.Labels = v
+				}`)
+	assertCodeNotContains(t, actualCode, `json.Unmarshal(value, &options.Labels)`)
+	assertCompiles(t, actualCode, `type Options struct {
+	Config string
+	Labels map[string]string
+}
+
+func run(opts Options) error { return nil }
+`)
+}
+
+func TestGenerateMain_DotEnv(t *testing.T) {
+	cmdMeta := &metadata.CommandMetadata{
+		Name: "app",
+		RunFunc: &metadata.RunFuncInfo{
+			Name:                       "run",
+			PackageName:                "main",
+			OptionsArgTypeNameStripped: "Options",
+		},
+		Options: []*metadata.OptionMetadata{
+			{Name: "Name", CliName: "name", TypeName: "string", EnvVar: "APP_NAME"},
+		},
+		DotEnv: true,
+	}
+	actualCode, err := GenerateMain(cmdMeta, "", true)
+	if err != nil {
+		t.Fatalf("GenerateMain with DotEnv failed: %v", err)
+	}
+
+	assertCodeContains(t, actualCode, `envFile, isEnvFileGiven := ".env", false`)
+	assertCodeContains(t, actualCode, `if _, exists := os.LookupEnv(key); !exists {
+				os.Setenv(key, value)
+			}`)
+	assertCodeContains(t, actualCode, `if err := loadDotEnv(envFile); err != nil && (isEnvFileGiven || !os.IsNotExist(err)) {
+		slog.ErrorContext(ctx, "Could not load the env file", "error", err, "path", envFile)
+		os.Exit(1)
+	}`)
+	assertCodeContains(t, actualCode, `flag.StringVar(&envFile, "env-file", envFile, "Load environment variables from the file")`)
+
+	// The .env file is loaded before the environment variables are read.
+	if strings.Index(actualCode, "loadDotEnv(envFile)") > strings.Index(actualCode, `os.LookupEnv("APP_NAME")`) {
+		t.Errorf("Expected the .env file to be loaded before reading the environment variables:\n%s", actualCode)
+	}
+
+	cmdMeta.DotEnv = false
+	actualCode, err = GenerateMain(cmdMeta, "", true)
+	if err != nil {
+		t.Fatalf("GenerateMain without DotEnv failed: %v", err)
+	}
+	assertCodeNotContains(t, actualCode, "loadDotEnv")
+	assertCodeNotContains(t, actualCode, `"env-file"`)
+}
+
+func TestGenerateMain_SecretOptions(t *testing.T) {
+	cmdMeta := &metadata.CommandMetadata{
+		Name: "app",
+		RunFunc: &metadata.RunFuncInfo{
+			Name:                       "run",
+			PackageName:                "main",
+			OptionsArgTypeNameStripped: "Options",
+		},
+		Options: []*metadata.OptionMetadata{
+			{Name: "Password", CliName: "password", TypeName: "string", EnvVar: "DB_PASSWORD", IsSecret: true, DefaultValue: "changeme", Pattern: "^[a-z]+$"},
+			{Name: "Token", CliName: "token", TypeName: "*string", IsPointer: true, IsSecret: true},
+		},
+	}
+	actualCode, err := GenerateMain(cmdMeta, "", true)
+	if err != nil {
+		t.Fatalf("GenerateMain with secret options failed: %v", err)
+	}
+
+	assertCodeContains(t, actualCode, `} else if path, ok := os.LookupEnv("DB_PASSWORD_FILE"); ok {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			slog.ErrorContext(ctx, "Could not read the secret file", "error", err, "envVar", "DB_PASSWORD_FILE", "option", "Password")
+			os.Exit(1)
+		}
+		options.Password = strings.TrimRight(string(data), "\r\n")
+	}`)
+	assertCodeContains(t, actualCode, `flag.StringVar(&secretFilePassword, "password-file", "", "Read the value of --password from the file")`)
+	assertCodeContains(t, actualCode, `if secretFilePassword != "" && !isFlagExplicitlySet["password"] {`)
+	assertCodeContains(t, actualCode, `isFlagExplicitlySet["password"] = true`)
+	assertCodeContains(t, actualCode, `flag.StringVar(&secretFileToken, "token-file", "", "Read the value of --token from the file")`)
+	assertCodeContains(t, actualCode, `options.Token = &value`)
+	assertCodeContains(t, actualCode, `"value", "[redacted]", "pattern", "^[a-z]+$")`)
+
+	// The secret value never appears in the generated help comments or log lines.
+	assertCodeContains(t, actualCode, `/* Original Default: [redacted], Env: DB_PASSWORD */`)
+	assertCodeNotContains(t, actualCode, `"value", options.Password`)
+}
+
+func TestGenerateMain_PrintConfig(t *testing.T) {
+	cmdMeta := &metadata.CommandMetadata{
+		Name: "app",
+		RunFunc: &metadata.RunFuncInfo{
+			Name:                       "run",
+			PackageName:                "main",
+			OptionsArgTypeNameStripped: "Options",
+			InitializerFunc:            "NewOptions",
+		},
+		Options: []*metadata.OptionMetadata{
+			{Name: "Config", CliName: "config", TypeName: "string", IsConfigFile: true},
+			{Name: "Name", CliName: "name", TypeName: "string", EnvVar: "APP_NAME", IsRequired: true},
+			{Name: "Wait", CliName: "wait", TypeName: "*time.Duration", IsPointer: true},
+			{Name: "Color", CliName: "color", TypeName: "bool", IsRequired: true, DefaultValue: true},
+			{Name: "Files", CliName: "files", TypeName: "[]string", Positional: &metadata.PositionalArg{Index: 0, Arity: "*"}},
+		},
+		PrintConfig: true,
+	}
+	actualCode, err := GenerateMain(cmdMeta, "", true)
+	if err != nil {
+		t.Fatalf("GenerateMain with PrintConfig failed: %v", err)
+	}
+
+	assertCodeContains(t, actualCode, `flag.BoolFunc("print-config", "Print the effective options and their sources (text or json), and exit", func(format string) error {`)
+	assertCodeContains(t, actualCode, `configSources["name"] = configPath`)
+	assertCodeContains(t, actualCode, `addEffectiveOption("name", options.Name, isFlagExplicitlySet["name"], "initializer", "APP_NAME")`)
+	assertCodeContains(t, actualCode, `if options.Wait != nil {
+		addEffectiveOption("wait", (*options.Wait).String(), isFlagExplicitlySet["wait"], "initializer")
+	} else {
+		addEffectiveOption("wait", nil, isFlagExplicitlySet["wait"], "initializer")
+	}`)
+	assertCodeContains(t, actualCode, `addEffectiveOption("color", options.Color, isFlagExplicitlySet["color"] || isFlagExplicitlySet["no-color"], "initializer")`)
+	assertCodeNotContains(t, actualCode, `addEffectiveOption("files"`)
+
+	// The effective options are printed before the required checks, so that the missing ones are shown too.
+	if strings.Index(actualCode, `if printConfigFormat == "json" {`) > strings.Index(actualCode, "initialDefaultName :=") {
+		t.Errorf("Expected the effective options to be printed before the required checks:\n%s", actualCode)
+	}
+
+	cmdMeta.PrintConfig = false
+	actualCode, err = GenerateMain(cmdMeta, "", true)
+	if err != nil {
+		t.Fatalf("GenerateMain without PrintConfig failed: %v", err)
+	}
+	assertCodeNotContains(t, actualCode, "print-config")
+	assertCodeNotContains(t, actualCode, "configSources")
+}
+
+func TestGenerateMain_EnvVarOfUnsupportedType(t *testing.T) {
+	cmdMeta := &metadata.CommandMetadata{
+		Name: "app",
+		RunFunc: &metadata.RunFuncInfo{
+			Name:                       "run",
+			PackageName:                "main",
+			OptionsArgName:             "opts",
+			OptionsArgType:             "Options",
+			OptionsArgTypeNameStripped: "Options",
+		},
+		Options: []*metadata.OptionMetadata{
+			{Name: "Name", CliName: "name", TypeName: "string", EnvVar: "APP_NAME", IsRequired: true, DefaultValue: "app"},
+			{Name: "Cache", CliName: "cache", TypeName: "*DBOptions", IsPointer: true, EnvVar: "APP_CACHE"},
+		},
+	}
+	actualCode, err := GenerateMain(cmdMeta, "", true)
+	if err != nil {
+		t.Fatalf("GenerateMain failed: %v", err)
+	}
+
+	assertCodeContains(t, actualCode, `if val, ok := os.LookupEnv("APP_NAME"); ok {`)
+	assertCodeNotContains(t, actualCode, `os.LookupEnv("APP_CACHE")`)
+	assertCompiles(t, actualCode, `type DBOptions struct {
+	Host string
+}
+
+type Options struct {
+	Name  string
+	Cache *DBOptions
+}
+
+func run(opts Options) error {
+	_ = opts
+	return nil
+}
+`)
+}
+
+func TestGenerateSubcommandsMain_DotEnv(t *testing.T) {
+	cmdMeta := &metadata.CommandMetadata{
+		Name:   "mytool",
+		DotEnv: true,
+		Subcommands: []*metadata.CommandMetadata{
+			{
+				Name: "serve",
+				RunFunc: &metadata.RunFuncInfo{
+					Name:                       "runServe",
+					PackageName:                "main",
+					OptionsArgTypeNameStripped: "ServeOptions",
+				},
+				Options: []*metadata.OptionMetadata{
+					{Name: "Port", CliName: "port", TypeName: "*int", IsPointer: true, HelpText: "Port"},
+				},
+				DotEnv: true,
+			},
+		},
+	}
+
+	actualCode, err := GenerateSubcommandsMain(cmdMeta, "", nil, true)
+	if err != nil {
+		t.Fatalf("GenerateSubcommandsMain with DotEnv failed: %v", err)
+	}
+
+	// --env-file is accepted before the subcommand (e.g. mytool --env-file x serve), as well as after it.
+	assertCodeContains(t, actualCode, `flag.StringVar(&envFile, "env-file", envFile, "Load environment variables from the file") // 4. Parse global flags. flag.Parse() args := flag.Args()`)
+	assertCodeContains(t, actualCode, `if len(args) < 1 { flag.Usage() os.Exit(2) }`)
+	assertCodeContains(t, actualCode, `switch args[0] {`)
+	assertCodeContains(t, actualCode, `fs.StringVar(&envFile, "env-file", envFile, "Load environment variables from the file")`)
+	assertCodeContains(t, actualCode, `fs.Parse(args[1:])`)
+	assertCompiles(t, actualCode, `type ServeOptions struct {
+	Port *int
+}
+
+func runServe(options ServeOptions) error { return nil }
+`)
+}
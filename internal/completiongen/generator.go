@@ -0,0 +1,500 @@
+package completiongen
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	helpgen "github.com/podhmo/goat/internal/helpgen"
+	"github.com/podhmo/goat/internal/metadata"
+)
+
+// Shells lists the shells supported by Generate.
+var Shells = []string{"bash", "zsh", "fish"}
+
+// Generate returns the completion script of the command for the shell ("bash", "zsh" or "fish").
+// The script completes the flag names, the enum values of the options, and file paths for the file and directory options.
+func Generate(cmdMeta *metadata.CommandMetadata, shell string) (string, error) {
+	if cmdMeta == nil {
+		return "", fmt.Errorf("command metadata is nil")
+	}
+	cmd := newCommandSpec(cmdMeta)
+	name := helpgen.CommandName(cmdMeta)
+
+	var sb strings.Builder
+	switch shell {
+	case "bash":
+		writeBash(&sb, name, cmd)
+	case "zsh":
+		writeZsh(&sb, name, cmd)
+	case "fish":
+		writeFish(&sb, name, cmd)
+	default:
+		return "", fmt.Errorf("unsupported shell %q, must be one of %s", shell, strings.Join(Shells, ", "))
+	}
+	return sb.String(), nil
+}
+
+// Kinds of the values of a flag, completed as file or directory paths.
+const (
+	valueFile = "file"
+	valueDir  = "dir"
+)
+
+// flagSpec describes a flag for completion.
+type flagSpec struct {
+	Long       []string // Long names without the dashes (e.g. "verbose", "loud")
+	Short      string   // One-letter short name without the dash (e.g. "v")
+	Help       string   // First line of the help text
+	TakesValue bool     // False for the boolean flags
+	Repeatable bool     // True for the slice and map flags
+	Values     []string // Enum values
+	ValueKind  string   // valueFile, valueDir, or empty
+}
+
+// names returns the flag names with their dashes, the short name first.
+func (f *flagSpec) names() []string {
+	var names []string
+	if f.Short != "" {
+		names = append(names, "-"+f.Short)
+	}
+	for _, long := range f.Long {
+		names = append(names, "--"+long)
+	}
+	return names
+}
+
+// commandSpec describes a command (or a subcommand) for completion.
+type commandSpec struct {
+	Name        string // Name of the subcommand, empty for the command itself
+	Help        string // First line of the description
+	Flags       []*flagSpec
+	FileArgs    bool // True if the positional arguments are file paths
+	Subcommands []*commandSpec
+}
+
+// newCommandSpec collects the flags of the command and its subcommands, in the order of the generated main().
+func newCommandSpec(cmdMeta *metadata.CommandMetadata) *commandSpec {
+	cmd := &commandSpec{}
+	if len(cmdMeta.Subcommands) == 0 {
+		cmd.Flags = append(flagSpecsOf(cmdMeta.Options), flagSpecsOf(cmdMeta.GlobalOptions)...)
+		cmd.Flags = append(cmd.Flags, builtinFlagSpecs(cmdMeta)...)
+		cmd.FileArgs = hasFileArgs(cmdMeta.Options)
+		return cmd
+	}
+
+	// The global flags (and --env-file and --print-config with them) are given before the subcommand.
+	hasGlobalOptions := len(cmdMeta.GlobalOptions) > 0
+	cmd.Flags = flagSpecsOf(cmdMeta.GlobalOptions)
+	if hasGlobalOptions {
+		cmd.Flags = append(cmd.Flags, builtinFlagSpecs(cmdMeta)...)
+	} else {
+		cmd.Flags = append(cmd.Flags, helpFlagSpec())
+	}
+	for _, subMeta := range cmdMeta.Subcommands {
+		sub := &commandSpec{Name: subMeta.Name, Help: firstLine(subMeta.Description)}
+		sub.Flags = flagSpecsOf(subMeta.Options)
+		sub.Flags = append(sub.Flags, builtinFlagSpecs(subMeta)...)
+		sub.FileArgs = hasFileArgs(subMeta.Options)
+		cmd.Subcommands = append(cmd.Subcommands, sub)
+	}
+	return cmd
+}
+
+// flagSpecsOf returns the flags of the options (the positional arguments are skipped).
+func flagSpecsOf(options []*metadata.OptionMetadata) []*flagSpec {
+	var flags []*flagSpec
+	for _, opt := range options {
+		if opt.Positional != nil {
+			continue
+		}
+		f := &flagSpec{Help: firstLine(opt.HelpText), TakesValue: true}
+		prefix := ""
+		switch opt.TypeName {
+		case "bool", "*bool":
+			f.TakesValue = false
+			if opt.TypeName == "bool" && opt.IsRequired && opt.DefaultValueAsBool() { // Given as --no-<flag>
+				prefix = "no-"
+			}
+		}
+		if opt.ShortName != "" {
+			f.Short = prefix + opt.ShortName
+			if prefix != "" { // -no-v is not a one-letter name
+				f.Long, f.Short = append(f.Long, f.Short), ""
+			}
+		}
+		f.Long = append(f.Long, prefix+cliNameOf(opt))
+		for _, alias := range opt.Aliases {
+			f.Long = append(f.Long, prefix+alias)
+		}
+		f.Repeatable = strings.HasPrefix(opt.TypeName, "[]") || strings.HasPrefix(opt.UnderlyingKind, "map[")
+		for _, v := range opt.EnumValues {
+			f.Values = append(f.Values, fmt.Sprintf("%v", v))
+		}
+		switch {
+		case opt.IsDir:
+			f.ValueKind = valueDir
+		case opt.IsFile || opt.FileMustExist || opt.FileGlobPattern || opt.IsConfigFile:
+			f.ValueKind = valueFile
+		}
+		flags = append(flags, f)
+
+		if opt.IsSecret {
+			flags = append(flags, &flagSpec{
+				Long:       []string{opt.SecretFileFlag()},
+				Help:       fmt.Sprintf("Read the value of --%s from the file", cliNameOf(opt)),
+				TakesValue: true,
+				ValueKind:  valueFile,
+			})
+		}
+	}
+	return flags
+}
+
+// builtinFlagSpecs returns the flags added by goat: --help, and --env-file and --print-config if enabled.
+func builtinFlagSpecs(cmdMeta *metadata.CommandMetadata) []*flagSpec {
+	flags := []*flagSpec{helpFlagSpec()}
+	if cmdMeta.DotEnv {
+		flags = append(flags, &flagSpec{Long: []string{"env-file"}, Help: "Load environment variables from the file", TakesValue: true, ValueKind: valueFile})
+	}
+	if cmdMeta.PrintConfig {
+		flags = append(flags, &flagSpec{Long: []string{"print-config"}, Help: "Print the effective options and their sources, and exit"})
+	}
+	return flags
+}
+
+func helpFlagSpec() *flagSpec {
+	return &flagSpec{Short: "h", Long: []string{"help"}, Help: "Show this help message and exit"}
+}
+
+// hasFileArgs checks if any positional argument of the options is a file path (goat.File).
+func hasFileArgs(options []*metadata.OptionMetadata) bool {
+	for _, opt := range options {
+		if opt.Positional != nil && (opt.IsFile || opt.FileMustExist || opt.FileGlobPattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// cliNameOf returns the CLI name of opt, as registered by the generated main().
+func cliNameOf(opt *metadata.OptionMetadata) string {
+	if opt.CliName != "" {
+		return opt.CliName
+	}
+	return strings.ToLower(opt.Name)
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(line)
+}
+
+var nonIdentChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// funcName returns the name of the shell function completing the command (e.g. "_my_app" for my-app).
+func funcName(name string) string {
+	return "_" + nonIdentChars.ReplaceAllString(name, "_")
+}
+
+// writeBash writes the bash completion script, registered with `complete -F`.
+func writeBash(sb *strings.Builder, name string, cmd *commandSpec) {
+	fn := funcName(name)
+	fmt.Fprintf(sb, "# bash completion for %s, generated by goat. DO NOT EDIT.\n\n", name)
+	fmt.Fprintf(sb, "%s() {\n", fn)
+	sb.WriteString("\tlocal cur=\"${COMP_WORDS[COMP_CWORD]}\" prev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n")
+	if len(cmd.Subcommands) == 0 {
+		writeBashCommand(sb, cmd, "\t")
+	} else {
+		var subNames []string
+		for _, sub := range cmd.Subcommands {
+			subNames = append(subNames, sub.Name)
+		}
+		sb.WriteString("\tlocal cmd=\"\" i\n")
+		sb.WriteString("\tfor ((i = 1; i < COMP_CWORD; i++)); do\n")
+		sb.WriteString("\t\tcase \"${COMP_WORDS[i]}\" in\n")
+		fmt.Fprintf(sb, "\t\t%s)\n", strings.Join(subNames, "|"))
+		sb.WriteString("\t\t\tcmd=\"${COMP_WORDS[i]}\"\n")
+		sb.WriteString("\t\t\tbreak\n")
+		sb.WriteString("\t\t\t;;\n")
+		sb.WriteString("\t\tesac\n")
+		sb.WriteString("\tdone\n\n")
+		sb.WriteString("\tcase \"$cmd\" in\n")
+		for _, sub := range cmd.Subcommands {
+			fmt.Fprintf(sb, "\t%s)\n", sub.Name)
+			writeBashCommand(sb, sub, "\t\t")
+			sb.WriteString("\t\t;;\n")
+		}
+		sb.WriteString("\t*)\n")
+		writeBashCommand(sb, cmd, "\t\t")
+		sb.WriteString("\t\t;;\n")
+		sb.WriteString("\tesac\n")
+	}
+	sb.WriteString("}\n\n")
+	fmt.Fprintf(sb, "complete -F %s %s\n", fn, name)
+}
+
+// writeBashCommand writes the completion of the values of the flags, the flag names,
+// and then the subcommands or the file arguments of cmd.
+func writeBashCommand(sb *strings.Builder, cmd *commandSpec, indent string) {
+	var fileFlags, dirFlags, otherFlags, allNames []string
+	var enumFlags []*flagSpec
+	for _, f := range cmd.Flags {
+		allNames = append(allNames, f.names()...)
+		if !f.TakesValue {
+			continue
+		}
+		switch {
+		case len(f.Values) > 0:
+			enumFlags = append(enumFlags, f)
+		case f.ValueKind == valueFile:
+			fileFlags = append(fileFlags, f.names()...)
+		case f.ValueKind == valueDir:
+			dirFlags = append(dirFlags, f.names()...)
+		default:
+			otherFlags = append(otherFlags, f.names()...)
+		}
+	}
+
+	if len(enumFlags)+len(fileFlags)+len(dirFlags)+len(otherFlags) > 0 {
+		fmt.Fprintf(sb, "%scase \"$prev\" in\n", indent)
+		for _, f := range enumFlags {
+			fmt.Fprintf(sb, "%s%s)\n", indent, strings.Join(f.names(), "|"))
+			fmt.Fprintf(sb, "%s\tCOMPREPLY=($(compgen -W %s -- \"$cur\"))\n", indent, bashQuote(strings.Join(f.Values, " ")))
+			fmt.Fprintf(sb, "%s\treturn\n", indent)
+			fmt.Fprintf(sb, "%s\t;;\n", indent)
+		}
+		if len(fileFlags) > 0 {
+			fmt.Fprintf(sb, "%s%s)\n", indent, strings.Join(fileFlags, "|"))
+			fmt.Fprintf(sb, "%s\tcompopt -o filenames\n", indent)
+			fmt.Fprintf(sb, "%s\tCOMPREPLY=($(compgen -f -- \"$cur\"))\n", indent)
+			fmt.Fprintf(sb, "%s\treturn\n", indent)
+			fmt.Fprintf(sb, "%s\t;;\n", indent)
+		}
+		if len(dirFlags) > 0 {
+			fmt.Fprintf(sb, "%s%s)\n", indent, strings.Join(dirFlags, "|"))
+			fmt.Fprintf(sb, "%s\tcompopt -o filenames\n", indent)
+			fmt.Fprintf(sb, "%s\tCOMPREPLY=($(compgen -d -- \"$cur\"))\n", indent)
+			fmt.Fprintf(sb, "%s\treturn\n", indent)
+			fmt.Fprintf(sb, "%s\t;;\n", indent)
+		}
+		if len(otherFlags) > 0 { // A value is expected, but there is nothing to complete
+			fmt.Fprintf(sb, "%s%s)\n", indent, strings.Join(otherFlags, "|"))
+			fmt.Fprintf(sb, "%s\treturn\n", indent)
+			fmt.Fprintf(sb, "%s\t;;\n", indent)
+		}
+		fmt.Fprintf(sb, "%sesac\n", indent)
+	}
+
+	fmt.Fprintf(sb, "%sif [[ \"$cur\" == -* ]]; then\n", indent)
+	fmt.Fprintf(sb, "%s\tCOMPREPLY=($(compgen -W %s -- \"$cur\"))\n", indent, bashQuote(strings.Join(allNames, " ")))
+	fmt.Fprintf(sb, "%s\treturn\n", indent)
+	fmt.Fprintf(sb, "%sfi\n", indent)
+	switch {
+	case len(cmd.Subcommands) > 0:
+		var subNames []string
+		for _, sub := range cmd.Subcommands {
+			subNames = append(subNames, sub.Name)
+		}
+		fmt.Fprintf(sb, "%sCOMPREPLY=($(compgen -W %s -- \"$cur\"))\n", indent, bashQuote(strings.Join(subNames, " ")))
+	case cmd.FileArgs:
+		fmt.Fprintf(sb, "%scompopt -o filenames\n", indent)
+		fmt.Fprintf(sb, "%sCOMPREPLY=($(compgen -f -- \"$cur\"))\n", indent)
+	}
+}
+
+// bashQuote quotes s in single quotes.
+func bashQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// writeZsh writes the zsh completion script, using _arguments.
+// It can be put in a directory of $fpath (as _<name>), or loaded with source.
+func writeZsh(sb *strings.Builder, name string, cmd *commandSpec) {
+	fn := funcName(name)
+	fmt.Fprintf(sb, "#compdef %s\n", name)
+	fmt.Fprintf(sb, "# zsh completion for %s, generated by goat. DO NOT EDIT.\n\n", name)
+	fmt.Fprintf(sb, "%s() {\n", fn)
+	if len(cmd.Subcommands) == 0 {
+		writeZshArguments(sb, cmd, "\t", "_arguments")
+	} else {
+		sb.WriteString("\tlocal context state state_descr line\n")
+		sb.WriteString("\ttypeset -A opt_args\n")
+		cmdWithState := *cmd
+		cmdWithState.FileArgs = false
+		writeZshArguments(sb, &cmdWithState, "\t", "_arguments -C", `'1: :->command'`, `'*:: :->args'`)
+		sb.WriteString("\n\tcase $state in\n")
+		sb.WriteString("\tcommand)\n")
+		sb.WriteString("\t\tlocal -a commands\n")
+		sb.WriteString("\t\tcommands=(\n")
+		for _, sub := range cmd.Subcommands {
+			fmt.Fprintf(sb, "\t\t\t%s\n", zshQuote(zshEscapeColon(sub.Name)+":"+sub.Help))
+		}
+		sb.WriteString("\t\t)\n")
+		sb.WriteString("\t\t_describe -t commands command commands\n")
+		sb.WriteString("\t\t;;\n")
+		sb.WriteString("\targs)\n")
+		sb.WriteString("\t\tcase $line[1] in\n")
+		for _, sub := range cmd.Subcommands {
+			fmt.Fprintf(sb, "\t\t%s)\n", sub.Name)
+			writeZshArguments(sb, sub, "\t\t\t", "_arguments")
+			sb.WriteString("\t\t\t;;\n")
+		}
+		sb.WriteString("\t\tesac\n")
+		sb.WriteString("\t\t;;\n")
+		sb.WriteString("\tesac\n")
+	}
+	sb.WriteString("}\n\n")
+	fmt.Fprintf(sb, "if [ \"$funcstack[1]\" = \"%s\" ]; then\n", fn)
+	fmt.Fprintf(sb, "\t%s \"$@\"\n", fn)
+	sb.WriteString("else\n")
+	fmt.Fprintf(sb, "\tcompdef %s %s\n", fn, name)
+	sb.WriteString("fi\n")
+}
+
+// writeZshArguments writes the _arguments call with a spec per flag, followed by the extra specs.
+func writeZshArguments(sb *strings.Builder, cmd *commandSpec, indent string, call string, extraSpecs ...string) {
+	var specs []string
+	for _, f := range cmd.Flags {
+		specs = append(specs, zshFlagSpec(f))
+	}
+	if cmd.FileArgs {
+		specs = append(specs, `'*:file:_files'`)
+	}
+	specs = append(specs, extraSpecs...)
+	sb.WriteString(indent + call)
+	for _, spec := range specs {
+		fmt.Fprintf(sb, " \\\n%s\t%s", indent, spec)
+	}
+	sb.WriteString("\n")
+}
+
+// zshFlagSpec returns the _arguments spec of the flag,
+// e.g. '(-v --verbose)'{-v,--verbose}'[Verbose output]' or '--level[Log level]:level:(debug info)'.
+func zshFlagSpec(f *flagSpec) string {
+	names := f.names()
+	var head string
+	switch {
+	case f.Short == "h" && f.Long[0] == "help":
+		head = "'(- *)'{" + strings.Join(names, ",") + "}'" // Nothing is completed after --help
+	case len(names) == 1 && f.Repeatable:
+		head = "'*" + names[0]
+	case len(names) == 1:
+		head = "'" + names[0]
+	case f.Repeatable:
+		head = "'*'{" + strings.Join(names, ",") + "}'"
+	default:
+		head = "'(" + strings.Join(names, " ") + ")'{" + strings.Join(names, ",") + "}'"
+	}
+
+	spec := head + "[" + zshEscapeBrackets(f.Help) + "]"
+	if f.TakesValue {
+		message := zshEscapeColon(f.Long[0])
+		var action string
+		switch {
+		case len(f.Values) > 0:
+			var values []string
+			for _, v := range f.Values {
+				values = append(values, zshEscapeValue(v))
+			}
+			action = "(" + strings.Join(values, " ") + ")"
+		case f.ValueKind == valueFile:
+			action = "_files"
+		case f.ValueKind == valueDir:
+			action = "_files -/"
+		}
+		spec += ":" + message + ":" + action
+	}
+	return spec + "'"
+}
+
+// zshQuote quotes s in single quotes.
+func zshQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// zshEscapeBrackets escapes the description of a flag in an _arguments spec (inside single quotes).
+func zshEscapeBrackets(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "[", `\[`)
+	s = strings.ReplaceAll(s, "]", `\]`)
+	return strings.ReplaceAll(s, "'", `'\''`)
+}
+
+// zshEscapeColon escapes the colons of a message or a name in an _arguments spec (inside single quotes).
+func zshEscapeColon(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, ":", `\:`), "'", `'\''`)
+}
+
+// zshEscapeValue escapes an enum value in the (value ...) action of an _arguments spec (inside single quotes).
+func zshEscapeValue(s string) string {
+	for _, c := range []string{`\`, " ", "(", ")", ":"} {
+		s = strings.ReplaceAll(s, c, `\`+c)
+	}
+	return strings.ReplaceAll(s, "'", `'\''`)
+}
+
+// writeFish writes the fish completion script, a `complete` command per flag.
+func writeFish(sb *strings.Builder, name string, cmd *commandSpec) {
+	fmt.Fprintf(sb, "# fish completion for %s, generated by goat. DO NOT EDIT.\n\n", name)
+	fmt.Fprintf(sb, "complete -c %s -f\n", name) // File paths are completed only where they are expected
+	if len(cmd.Subcommands) == 0 {
+		writeFishCommand(sb, name, cmd, "")
+		return
+	}
+
+	writeFishCommand(sb, name, cmd, "__fish_use_subcommand")
+	for _, sub := range cmd.Subcommands {
+		fmt.Fprintf(sb, "complete -c %s -n '__fish_use_subcommand' -a %s", name, fishQuote(sub.Name))
+		if sub.Help != "" {
+			fmt.Fprintf(sb, " -d %s", fishQuote(sub.Help))
+		}
+		sb.WriteString("\n")
+	}
+	for _, sub := range cmd.Subcommands {
+		sb.WriteString("\n")
+		writeFishCommand(sb, name, sub, "__fish_seen_subcommand_from "+sub.Name)
+	}
+}
+
+// writeFishCommand writes the completions of the flags (and the file arguments) of cmd, under the condition if not empty.
+func writeFishCommand(sb *strings.Builder, name string, cmd *commandSpec, condition string) {
+	prefix := "complete -c " + name
+	if condition != "" {
+		prefix += " -n " + fishQuote(condition)
+	}
+	for _, f := range cmd.Flags {
+		sb.WriteString(prefix)
+		if f.Short != "" {
+			fmt.Fprintf(sb, " -s %s", f.Short)
+		}
+		for _, long := range f.Long {
+			fmt.Fprintf(sb, " -l %s", long)
+		}
+		if f.TakesValue {
+			switch {
+			case len(f.Values) > 0:
+				fmt.Fprintf(sb, " -x -a %s", fishQuote(strings.Join(f.Values, " ")))
+			case f.ValueKind == valueFile:
+				sb.WriteString(" -r -F")
+			case f.ValueKind == valueDir:
+				sb.WriteString(" -x -a '(__fish_complete_directories)'")
+			default:
+				sb.WriteString(" -x")
+			}
+		}
+		if f.Help != "" {
+			fmt.Fprintf(sb, " -d %s", fishQuote(f.Help))
+		}
+		sb.WriteString("\n")
+	}
+	if cmd.FileArgs {
+		fmt.Fprintf(sb, "%s -F\n", prefix)
+	}
+}
+
+// fishQuote quotes s in single quotes.
+func fishQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return "'" + strings.ReplaceAll(s, "'", `\'`) + "'"
+}
@@ -0,0 +1,165 @@
+package completiongen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/podhmo/goat/internal/metadata"
+)
+
+func testCommand() *metadata.CommandMetadata {
+	return &metadata.CommandMetadata{
+		Name: "app",
+		Options: []*metadata.OptionMetadata{
+			{Name: "Verbose", CliName: "verbose", TypeName: "bool", HelpText: "Verbose output.", ShortName: "v"},
+			{Name: "Level", CliName: "level", TypeName: "string", HelpText: "Log level.\nMore details.", EnumValues: []any{"debug", "info"}},
+			{Name: "Config", CliName: "config", TypeName: "string", HelpText: "Config file.", IsConfigFile: true},
+			{Name: "OutputDir", CliName: "output-dir", TypeName: "string", HelpText: "Output directory.", IsDir: true, Aliases: []string{"out"}},
+			{Name: "Token", CliName: "token", TypeName: "string", HelpText: "API token.", IsSecret: true},
+			{Name: "Files", CliName: "files", TypeName: "[]string", IsFile: true, Positional: &metadata.PositionalArg{Index: 0, Arity: "*"}},
+		},
+	}
+}
+
+func TestGenerate_Bash(t *testing.T) {
+	script, err := Generate(testCommand(), "bash")
+	if err != nil {
+		t.Fatalf("Generate() unexpected error: %v", err)
+	}
+
+	expected := `# bash completion for app, generated by goat. DO NOT EDIT.
+
+_app() {
+	local cur="${COMP_WORDS[COMP_CWORD]}" prev="${COMP_WORDS[COMP_CWORD-1]}"
+	case "$prev" in
+	--level)
+		COMPREPLY=($(compgen -W 'debug info' -- "$cur"))
+		return
+		;;
+	--config|--token-file)
+		compopt -o filenames
+		COMPREPLY=($(compgen -f -- "$cur"))
+		return
+		;;
+	--output-dir|--out)
+		compopt -o filenames
+		COMPREPLY=($(compgen -d -- "$cur"))
+		return
+		;;
+	--token)
+		return
+		;;
+	esac
+	if [[ "$cur" == -* ]]; then
+		COMPREPLY=($(compgen -W '-v --verbose --level --config --output-dir --out --token --token-file -h --help' -- "$cur"))
+		return
+	fi
+	compopt -o filenames
+	COMPREPLY=($(compgen -f -- "$cur"))
+}
+
+complete -F _app app
+`
+	if script != expected {
+		t.Errorf("bash completion mismatch:\n---EXPECTED---\n%s\n\n---ACTUAL---\n%s", expected, script)
+	}
+}
+
+func TestGenerate_Zsh(t *testing.T) {
+	script, err := Generate(testCommand(), "zsh")
+	if err != nil {
+		t.Fatalf("Generate() unexpected error: %v", err)
+	}
+
+	for _, want := range []string{
+		"#compdef app\n",
+		`'(-v --verbose)'{-v,--verbose}'[Verbose output.]'`,
+		`'--level[Log level.]:level:(debug info)'`,
+		`'--config[Config file.]:config:_files'`,
+		`'(--output-dir --out)'{--output-dir,--out}'[Output directory.]:output-dir:_files -/'`,
+		`'--token-file[Read the value of --token from the file]:token-file:_files'`,
+		`'(- *)'{-h,--help}'[Show this help message and exit]'`,
+		`'*:file:_files'`,
+		"\tcompdef _app app\n",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("zsh completion does not contain %q:\n%s", want, script)
+		}
+	}
+}
+
+func TestGenerate_Fish(t *testing.T) {
+	script, err := Generate(testCommand(), "fish")
+	if err != nil {
+		t.Fatalf("Generate() unexpected error: %v", err)
+	}
+
+	expected := `# fish completion for app, generated by goat. DO NOT EDIT.
+
+complete -c app -f
+complete -c app -s v -l verbose -d 'Verbose output.'
+complete -c app -l level -x -a 'debug info' -d 'Log level.'
+complete -c app -l config -r -F -d 'Config file.'
+complete -c app -l output-dir -l out -x -a '(__fish_complete_directories)' -d 'Output directory.'
+complete -c app -l token -x -d 'API token.'
+complete -c app -l token-file -r -F -d 'Read the value of --token from the file'
+complete -c app -s h -l help -d 'Show this help message and exit'
+complete -c app -F
+`
+	if script != expected {
+		t.Errorf("fish completion mismatch:\n---EXPECTED---\n%s\n\n---ACTUAL---\n%s", expected, script)
+	}
+}
+
+func TestGenerate_Subcommands(t *testing.T) {
+	cmdMeta := &metadata.CommandMetadata{
+		Name:          "app",
+		GlobalOptions: []*metadata.OptionMetadata{{Name: "Debug", CliName: "debug", TypeName: "bool", HelpText: "Debug mode."}},
+		Subcommands: []*metadata.CommandMetadata{
+			{Name: "serve", Description: "Start the server.", Options: []*metadata.OptionMetadata{{Name: "Port", CliName: "port", TypeName: "int", HelpText: "Port."}}},
+			{Name: "migrate", Description: "Run the migrations.", Options: []*metadata.OptionMetadata{{Name: "Direction", CliName: "direction", TypeName: "string", EnumValues: []any{"up", "down"}}}},
+		},
+	}
+
+	tests := []struct {
+		shell string
+		want  []string
+	}{
+		{shell: "bash", want: []string{
+			"\t\tserve|migrate)\n\t\t\tcmd=\"${COMP_WORDS[i]}\"",
+			"\tmigrate)\n\t\tcase \"$prev\" in\n\t\t--direction)\n\t\t\tCOMPREPLY=($(compgen -W 'up down' -- \"$cur\"))",
+			"\t\t\tCOMPREPLY=($(compgen -W '--debug -h --help' -- \"$cur\"))",
+			"\t\tCOMPREPLY=($(compgen -W 'serve migrate' -- \"$cur\"))",
+		}},
+		{shell: "zsh", want: []string{
+			"\t_arguments -C \\\n\t\t'--debug[Debug mode.]' \\",
+			"\t\t\t'serve:Start the server.'\n\t\t\t'migrate:Run the migrations.'\n",
+			"\t\tserve)\n\t\t\t_arguments \\\n\t\t\t\t'--port[Port.]:port:' \\",
+		}},
+		{shell: "fish", want: []string{
+			"complete -c app -n '__fish_use_subcommand' -l debug -d 'Debug mode.'\n",
+			"complete -c app -n '__fish_use_subcommand' -a 'serve' -d 'Start the server.'\n",
+			"complete -c app -n '__fish_seen_subcommand_from migrate' -l direction -x -a 'up down'\n",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.shell, func(t *testing.T) {
+			script, err := Generate(cmdMeta, tt.shell)
+			if err != nil {
+				t.Fatalf("Generate() unexpected error: %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(script, want) {
+					t.Errorf("%s completion does not contain %q:\n%s", tt.shell, want, script)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerate_UnsupportedShell(t *testing.T) {
+	_, err := Generate(testCommand(), "powershell")
+	if err == nil || !strings.Contains(err.Error(), `unsupported shell "powershell"`) {
+		t.Errorf("Generate() error = %v, want an unsupported shell error", err)
+	}
+}
@@ -0,0 +1,330 @@
+package docgen
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	helpgen "github.com/podhmo/goat/internal/helpgen"
+	"github.com/podhmo/goat/internal/metadata"
+)
+
+// Marker comments of the region of a Markdown file updated by UpdateRegion.
+const (
+	RegionStart = "<!-- goat:docs:start -->"
+	RegionEnd   = "<!-- goat:docs:end -->"
+)
+
+// GenerateMarkdown returns the reference page of the command in Markdown: the description, the usage,
+// and the tables of the arguments, flags (with their defaults and allowed values) and environment variables.
+func GenerateMarkdown(cmdMeta *metadata.CommandMetadata) string {
+	if cmdMeta == nil {
+		return "<error>" // Handle nil case gracefully
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s\n\n", helpgen.CommandName(cmdMeta))
+	if cmdMeta.Description != "" {
+		fmt.Fprintf(&sb, "%s\n\n", strings.TrimSpace(cmdMeta.Description))
+	}
+	writeReference(&sb, cmdMeta, "##", true)
+	return strings.TrimRight(sb.String(), "\n") + "\n"
+}
+
+// GenerateMarkdownSection returns the reference of the command without the title, the description and the Usage heading,
+// with "###" headings, to be embedded in the Usage section of a README (see UpdateRegion).
+func GenerateMarkdownSection(cmdMeta *metadata.CommandMetadata) string {
+	if cmdMeta == nil {
+		return "<error>" // Handle nil case gracefully
+	}
+
+	var sb strings.Builder
+	writeReference(&sb, cmdMeta, "###", false)
+	return strings.TrimRight(sb.String(), "\n") + "\n"
+}
+
+// UpdateRegion replaces the lines between the RegionStart and RegionEnd marker comments of the Markdown content with docs.
+// The marker comments are kept, so that the region can be updated again.
+func UpdateRegion(content string, docs string) (string, error) {
+	start := strings.Index(content, RegionStart)
+	if start < 0 {
+		return "", fmt.Errorf("marker comment %s is not found", RegionStart)
+	}
+	end := strings.Index(content[start:], RegionEnd)
+	if end < 0 {
+		return "", fmt.Errorf("marker comment %s is not found after %s", RegionEnd, RegionStart)
+	}
+	end += start
+	if strings.Contains(content[end+len(RegionEnd):], RegionStart) {
+		return "", fmt.Errorf("marker comment %s is found more than once", RegionStart)
+	}
+	return content[:start+len(RegionStart)] + "\n" + strings.TrimRight(docs, "\n") + "\n" + content[end:], nil
+}
+
+// writeReference writes the usage (under a Usage heading if usageHeading is true) and the tables of the command,
+// with the headings at the level of heading (e.g. "##").
+func writeReference(w io.Writer, cmdMeta *metadata.CommandMetadata, heading string, usageHeading bool) {
+	name := helpgen.CommandName(cmdMeta)
+	if usageHeading {
+		fmt.Fprintf(w, "%s Usage\n\n", heading)
+	}
+	if len(cmdMeta.Subcommands) == 0 {
+		argOptions := positionalOptions(cmdMeta.Options)
+		fmt.Fprintf(w, "```\n%s\n```\n\n", strings.TrimSpace(name+" [flags] "+argsUsage(argOptions)))
+		writeArguments(w, argOptions, heading)
+
+		fmt.Fprintf(w, "%s Flags\n\n", heading)
+		writeFlagTable(w, flagOptions(cmdMeta.Options), cmdMeta)
+		if len(cmdMeta.GlobalOptions) > 0 {
+			fmt.Fprintf(w, "%s Global Flags\n\n", heading)
+			writeFlagTable(w, cmdMeta.GlobalOptions, nil)
+		}
+		writeEnvironmentTable(w, cmdMeta, heading)
+		return
+	}
+
+	// The global flags (and --env-file and --print-config with them) are given before the subcommand.
+	if len(cmdMeta.GlobalOptions) > 0 {
+		fmt.Fprintf(w, "```\n%s [global flags] <command> [flags]\n```\n\n", name)
+	} else {
+		fmt.Fprintf(w, "```\n%s <command> [flags]\n```\n\n", name)
+	}
+	fmt.Fprintf(w, "%s Commands\n\n", heading)
+	fmt.Fprintln(w, "| Command | Description |")
+	fmt.Fprintln(w, "|---------|-------------|")
+	for _, sub := range cmdMeta.Subcommands {
+		summary, _, _ := strings.Cut(strings.TrimSpace(sub.Description), "\n")
+		fmt.Fprintf(w, "| [`%s`](#%s) | %s |\n", sub.Name, anchorOf(name+" "+sub.Name), cell(summary))
+	}
+	fmt.Fprintln(w)
+	if len(cmdMeta.GlobalOptions) > 0 {
+		fmt.Fprintf(w, "%s Global Flags\n\n", heading)
+		writeFlagTable(w, cmdMeta.GlobalOptions, cmdMeta)
+	}
+
+	for _, sub := range cmdMeta.Subcommands {
+		argOptions := positionalOptions(sub.Options)
+		fmt.Fprintf(w, "%s %s %s\n\n", heading, name, sub.Name)
+		if sub.Description != "" {
+			fmt.Fprintf(w, "%s\n\n", strings.TrimSpace(sub.Description))
+		}
+		fmt.Fprintf(w, "```\n%s\n```\n\n", strings.TrimSpace(name+" "+sub.Name+" [flags] "+argsUsage(argOptions)))
+		writeArguments(w, argOptions, heading+"#")
+		fmt.Fprintf(w, "%s# Flags\n\n", heading)
+		writeFlagTable(w, flagOptions(sub.Options), sub)
+	}
+	writeEnvironmentTable(w, cmdMeta, heading)
+}
+
+func positionalOptions(options []*metadata.OptionMetadata) []*metadata.OptionMetadata {
+	var argOptions []*metadata.OptionMetadata
+	for _, opt := range options {
+		if opt.Positional != nil {
+			argOptions = append(argOptions, opt)
+		}
+	}
+	sort.SliceStable(argOptions, func(i, j int) bool { return argOptions[i].Positional.Index < argOptions[j].Positional.Index })
+	return argOptions
+}
+
+func flagOptions(options []*metadata.OptionMetadata) []*metadata.OptionMetadata {
+	var flags []*metadata.OptionMetadata
+	for _, opt := range options {
+		if opt.Positional == nil {
+			flags = append(flags, opt)
+		}
+	}
+	return flags
+}
+
+// argsUsage returns the positional arguments in the usage, like the help message (e.g. "<src> [files...]").
+func argsUsage(argOptions []*metadata.OptionMetadata) string {
+	parts := make([]string, 0, len(argOptions))
+	for _, opt := range argOptions {
+		switch {
+		case opt.Positional.Arity == "*":
+			parts = append(parts, fmt.Sprintf("[%s...]", opt.CliName))
+		case opt.Positional.Arity != "":
+			parts = append(parts, fmt.Sprintf("<%s>...", opt.CliName))
+		case opt.IsPointer:
+			parts = append(parts, fmt.Sprintf("[%s]", opt.CliName))
+		default:
+			parts = append(parts, fmt.Sprintf("<%s>", opt.CliName))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// writeArguments writes the table of the positional arguments, or nothing if there are none.
+func writeArguments(w io.Writer, argOptions []*metadata.OptionMetadata, heading string) {
+	if len(argOptions) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "%s Arguments\n\n", heading)
+	fmt.Fprintln(w, "| Argument | Arity | Description |")
+	fmt.Fprintln(w, "|----------|-------|-------------|")
+	for _, opt := range argOptions {
+		arity := "required"
+		switch opt.Positional.Arity {
+		case "":
+			if opt.IsPointer {
+				arity = "optional"
+			}
+		case "*":
+			arity = "zero or more"
+		case "+":
+			arity = "one or more"
+		default:
+			arity = "exactly " + opt.Positional.Arity
+		}
+		fmt.Fprintf(w, "| `%s` | %s | %s |\n", opt.CliName, arity, cell(opt.HelpText))
+	}
+	fmt.Fprintln(w)
+}
+
+// writeFlagTable writes the table of the flags, followed by the flags added by goat for the command
+// (--help, and --env-file and --print-config if enabled) unless cmdMeta is nil.
+func writeFlagTable(w io.Writer, options []*metadata.OptionMetadata, cmdMeta *metadata.CommandMetadata) {
+	fmt.Fprintln(w, "| Flag | Type | Default | Description |")
+	fmt.Fprintln(w, "|------|------|---------|-------------|")
+	for _, opt := range options {
+		var names []string
+		for _, name := range helpgen.FlagNames(opt) {
+			names = append(names, "`"+name+"`")
+		}
+		typeName := helpgen.TypeIndicator(opt)
+		if strings.HasSuffix(opt.TypeName, "bool") {
+			typeName = "bool"
+		}
+		defaultValue := ""
+		if defaultText := helpgen.DefaultText(opt); defaultText != "" {
+			defaultValue = "`" + defaultText + "`"
+		}
+
+		description := []string{strings.Join(strings.Fields(opt.HelpText), " ")}
+		if helpgen.IsRequiredFlag(opt) {
+			description = append(description, "Required.")
+		}
+		if len(opt.EnumValues) > 0 {
+			var values []string
+			for _, v := range opt.EnumValues {
+				values = append(values, fmt.Sprintf("`%v`", v))
+			}
+			description = append(description, fmt.Sprintf("Allowed: %s.", strings.Join(values, ", ")))
+		}
+		if valueRange := opt.ValueRange(); valueRange != "" {
+			description = append(description, fmt.Sprintf("Range: `%s`.", valueRange))
+		}
+		if lengthRange := opt.LengthRange(); lengthRange != "" {
+			if opt.LengthInCharacters() {
+				description = append(description, fmt.Sprintf("Length: `%s` characters.", lengthRange))
+			} else {
+				description = append(description, fmt.Sprintf("Length: `%s`.", lengthRange))
+			}
+		}
+		if opt.Pattern != "" {
+			description = append(description, fmt.Sprintf("Pattern: `%s`.", opt.Pattern))
+		}
+		if opt.FileMustExist {
+			description = append(description, "The file must exist.")
+		}
+		if opt.FileGlobPattern {
+			description = append(description, "Glob patterns are expanded.")
+		}
+		if policy := opt.DirPolicy(); opt.IsDir && policy != "" {
+			description = append(description, fmt.Sprintf("The directory %s.", strings.Replace(policy, "created", "is created", 1)))
+		}
+		if opt.IsSecret {
+			description = append(description, fmt.Sprintf("Secret, also read from the file given by `--%s`.", opt.SecretFileFlag()))
+		}
+		if opt.EnvVar != "" {
+			description = append(description, fmt.Sprintf("Env: `%s`.", opt.EnvVar))
+		}
+		fmt.Fprintf(w, "| %s | %s | %s | %s |\n", strings.Join(names, ", "), typeName, cell(defaultValue), cell(strings.Join(description, " ")))
+	}
+	if cmdMeta != nil {
+		fmt.Fprintln(w, "| `-h`, `--help` |  |  | Show this help message and exit. |")
+		if cmdMeta.DotEnv {
+			fmt.Fprintln(w, "| `--env-file` | string | `\".env\"` | Load environment variables from the file. |")
+		}
+		if cmdMeta.PrintConfig {
+			fmt.Fprintln(w, "| `--print-config` |  |  | Print the effective options and their sources (text, or json with `--print-config=json`), and exit. |")
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// writeEnvironmentTable writes the table of the environment variables of the options (and the subcommands),
+// or nothing if there are none.
+func writeEnvironmentTable(w io.Writer, cmdMeta *metadata.CommandMetadata, heading string) {
+	type envRow struct {
+		name        string
+		target      string
+		description string
+	}
+	var rows []envRow
+	seen := make(map[string]bool)
+	add := func(options []*metadata.OptionMetadata, subcommand string) {
+		for _, opt := range options {
+			if opt.EnvVar == "" || seen[opt.EnvVar] {
+				continue
+			}
+			seen[opt.EnvVar] = true
+			target := "`--" + opt.CliName + "`"
+			switch {
+			case opt.Positional != nil:
+				target = "`" + opt.CliName + "`"
+			case opt.TypeName == "bool" && opt.IsRequired && opt.DefaultValueAsBool(): // Given as --no-<flag>
+				target = "`--no-" + opt.CliName + "` (negated)"
+			}
+			if subcommand != "" {
+				target += " of `" + subcommand + "`"
+			}
+			rows = append(rows, envRow{name: opt.EnvVar, target: target, description: opt.HelpText})
+			if opt.IsSecret {
+				rows = append(rows, envRow{name: opt.SecretFileEnvVar(), target: target, description: "File to read the value from."})
+			}
+		}
+	}
+	add(cmdMeta.Options, "")
+	add(cmdMeta.GlobalOptions, "")
+	for _, sub := range cmdMeta.Subcommands {
+		add(sub.Options, sub.Name)
+	}
+	if len(rows) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "%s Environment Variables\n\n", heading)
+	fmt.Fprintln(w, "The flags take precedence over the environment variables.")
+	if cmdMeta.DotEnv {
+		fmt.Fprintln(w, "The variables are also loaded from the `.env` file (or the file given by `--env-file`), without overriding the environment.")
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "| Variable | Sets | Description |")
+	fmt.Fprintln(w, "|----------|------|-------------|")
+	for _, row := range rows {
+		fmt.Fprintf(w, "| `%s` | %s | %s |\n", row.name, row.target, cell(row.description))
+	}
+	fmt.Fprintln(w)
+}
+
+// cell formats the text as a table cell: a single line, with the pipes escaped.
+func cell(s string) string {
+	return strings.ReplaceAll(strings.Join(strings.Fields(s), " "), "|", `\|`)
+}
+
+// anchorOf returns the anchor of a heading on GitHub (e.g. "app-serve" for "app serve").
+func anchorOf(heading string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(heading) {
+		switch {
+		case r == ' ':
+			sb.WriteRune('-')
+		case r == '-' || r == '_' || ('a' <= r && r <= 'z') || ('0' <= r && r <= '9'):
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
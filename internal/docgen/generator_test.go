@@ -0,0 +1,135 @@
+package docgen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/podhmo/goat/internal/metadata"
+)
+
+func TestGenerateMarkdown(t *testing.T) {
+	cmdMeta := &metadata.CommandMetadata{
+		Name:        "app",
+		Description: "Copy the files.",
+		Options: []*metadata.OptionMetadata{
+			{Name: "Level", CliName: "level", TypeName: "string", HelpText: "Log level.", EnvVar: "APP_LEVEL", DefaultValue: "info", EnumValues: []any{"debug", "info"}, IsRequired: true},
+			{Name: "Verbose", CliName: "verbose", TypeName: "bool", ShortName: "v", HelpText: "Verbose output\n(repeatable | not really)."},
+			{Name: "Token", CliName: "token", TypeName: "*string", IsPointer: true, HelpText: "API token.", EnvVar: "APP_TOKEN", IsSecret: true},
+			{Name: "Port", CliName: "port", TypeName: "int", HelpText: "Port.", MinValue: int64(1), MaxValue: int64(65535), IsRequired: true},
+			{Name: "Files", CliName: "files", TypeName: "[]string", HelpText: "Files to copy.", Positional: &metadata.PositionalArg{Index: 0, Arity: "+"}},
+		},
+		PrintConfig: true,
+	}
+
+	docs := GenerateMarkdown(cmdMeta)
+
+	expected := "# app\n" +
+		"\n" +
+		"Copy the files.\n" +
+		"\n" +
+		"## Usage\n" +
+		"\n" +
+		"```\n" +
+		"app [flags] <files>...\n" +
+		"```\n" +
+		"\n" +
+		"## Arguments\n" +
+		"\n" +
+		"| Argument | Arity | Description |\n" +
+		"|----------|-------|-------------|\n" +
+		"| `files` | one or more | Files to copy. |\n" +
+		"\n" +
+		"## Flags\n" +
+		"\n" +
+		"| Flag | Type | Default | Description |\n" +
+		"|------|------|---------|-------------|\n" +
+		"| `--level` | string | `\"info\"` | Log level. Allowed: `debug`, `info`. Env: `APP_LEVEL`. |\n" +
+		"| `-v`, `--verbose` | bool |  | Verbose output (repeatable \\| not really). |\n" +
+		"| `--token` | string |  | API token. Secret, also read from the file given by `--token-file`. Env: `APP_TOKEN`. |\n" +
+		"| `--port` | int |  | Port. Required. Range: `1..65535`. |\n" +
+		"| `-h`, `--help` |  |  | Show this help message and exit. |\n" +
+		"| `--print-config` |  |  | Print the effective options and their sources (text, or json with `--print-config=json`), and exit. |\n" +
+		"\n" +
+		"## Environment Variables\n" +
+		"\n" +
+		"The flags take precedence over the environment variables.\n" +
+		"\n" +
+		"| Variable | Sets | Description |\n" +
+		"|----------|------|-------------|\n" +
+		"| `APP_LEVEL` | `--level` | Log level. |\n" +
+		"| `APP_TOKEN` | `--token` | API token. |\n" +
+		"| `APP_TOKEN_FILE` | `--token` | File to read the value from. |\n"
+	if docs != expected {
+		t.Errorf("markdown mismatch:\n---EXPECTED---\n%s\n\n---ACTUAL---\n%s", expected, docs)
+	}
+}
+
+func TestGenerateMarkdownSection_Subcommands(t *testing.T) {
+	cmdMeta := &metadata.CommandMetadata{
+		Name:          "app",
+		Description:   "Manage the server.",
+		GlobalOptions: []*metadata.OptionMetadata{{Name: "Debug", CliName: "debug", TypeName: "bool", HelpText: "Debug mode."}},
+		Subcommands: []*metadata.CommandMetadata{
+			{Name: "serve", Description: "Start the server.", Options: []*metadata.OptionMetadata{{Name: "Port", CliName: "port", TypeName: "int", HelpText: "Port.", EnvVar: "PORT", IsRequired: true, DefaultValue: 8080}}},
+			{Name: "db-migrate", Description: "Run the migrations."},
+		},
+	}
+
+	docs := GenerateMarkdownSection(cmdMeta)
+
+	if strings.Contains(docs, "Manage the server.") || !strings.HasPrefix(docs, "```\n") {
+		t.Errorf("Expected no title, description and Usage heading in the section:\n%s", docs)
+	}
+	for _, want := range []string{
+		"```\napp [global flags] <command> [flags]\n```\n\n### Commands\n",
+		"| [`serve`](#app-serve) | Start the server. |\n| [`db-migrate`](#app-db-migrate) | Run the migrations. |\n",
+		"### Global Flags\n\n| Flag | Type | Default | Description |\n|------|------|---------|-------------|\n| `--debug` | bool |  | Debug mode. |\n| `-h`, `--help` |  |  | Show this help message and exit. |\n",
+		"### app serve\n\nStart the server.\n\n```\napp serve [flags]\n```\n\n#### Flags\n",
+		"| `--port` | int | `8080` | Port. Env: `PORT`. |\n",
+		"| `PORT` | `--port` of `serve` | Port. |\n",
+	} {
+		if !strings.Contains(docs, want) {
+			t.Errorf("markdown does not contain %q:\n%s", want, docs)
+		}
+	}
+}
+
+func TestUpdateRegion(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr string
+	}{
+		{
+			name:    "replace",
+			content: "# app\n\n## Usage\n\n<!-- goat:docs:start -->\nold\n<!-- goat:docs:end -->\n\n## License\n",
+			want:    "# app\n\n## Usage\n\n<!-- goat:docs:start -->\nnew docs\n<!-- goat:docs:end -->\n\n## License\n",
+		},
+		{
+			name:    "empty region",
+			content: "<!-- goat:docs:start --><!-- goat:docs:end -->\n",
+			want:    "<!-- goat:docs:start -->\nnew docs\n<!-- goat:docs:end -->\n",
+		},
+		{name: "no start marker", content: "# app\n<!-- goat:docs:end -->\n", wantErr: "<!-- goat:docs:start --> is not found"},
+		{name: "no end marker", content: "<!-- goat:docs:end -->\n<!-- goat:docs:start -->\n", wantErr: "<!-- goat:docs:end --> is not found after"},
+		{name: "two regions", content: "<!-- goat:docs:start -->\n<!-- goat:docs:end -->\n<!-- goat:docs:start -->\n<!-- goat:docs:end -->\n", wantErr: "more than once"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := UpdateRegion(tt.content, "new docs\n")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("UpdateRegion() error = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UpdateRegion() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("UpdateRegion() mismatch:\n---EXPECTED---\n%s\n---ACTUAL---\n%s", tt.want, got)
+			}
+		})
+	}
+}
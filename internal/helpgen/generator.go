@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/podhmo/goat/internal/metadata"
@@ -16,96 +17,306 @@ func GenerateHelp(cmdMeta *metadata.CommandMetadata) string {
 	}
 
 	var sb strings.Builder
-	generateHelp(&sb, cmdMeta)
+	if len(cmdMeta.Subcommands) > 0 {
+		generateSubcommandsHelp(&sb, CommandName(cmdMeta), cmdMeta)
+	} else {
+		generateHelp(&sb, CommandName(cmdMeta), cmdMeta)
+	}
 	return sb.String()
 }
 
-func generateHelp(w io.Writer, cmdMeta *metadata.CommandMetadata) {
-	extractedCmdName := cmdMeta.Name
+// GenerateSubcommandHelp returns the help message of a subcommand (e.g. "mytool serve").
+func GenerateSubcommandHelp(parentMeta *metadata.CommandMetadata, subMeta *metadata.CommandMetadata) string {
+	if parentMeta == nil || subMeta == nil {
+		return "<error>" // Handle nil case gracefully
+	}
+
+	var sb strings.Builder
+	generateHelp(&sb, CommandName(parentMeta)+" "+subMeta.Name, subMeta)
+	return sb.String()
+}
+
+// CommandName returns the name used in help messages (and shell completions), derived from cmdMeta.Name.
+func CommandName(cmdMeta *metadata.CommandMetadata) string {
 	if strings.HasSuffix(cmdMeta.Name, "/main.go") {
-		extractedCmdName = filepath.Dir(cmdMeta.Name)
+		return filepath.Dir(cmdMeta.Name)
+	}
+	base := filepath.Base(cmdMeta.Name)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// generateSubcommandsHelp writes the top-level help message of a command that has subcommands.
+func generateSubcommandsHelp(w io.Writer, extractedCmdName string, cmdMeta *metadata.CommandMetadata) {
+	if cmdMeta.Description != "" {
+		fmt.Fprintf(w, "%s - %s\n\n", extractedCmdName, strings.ReplaceAll(cmdMeta.Description, "\n", "\n         "))
 	} else {
-		base := filepath.Base(cmdMeta.Name)
-		extractedCmdName = strings.TrimSuffix(base, filepath.Ext(base))
+		fmt.Fprintf(w, "%s\n\n", extractedCmdName)
+	}
+	if len(cmdMeta.GlobalOptions) > 0 {
+		fmt.Fprintf(w, "Usage:\n  %s [global flags] <command> [flags]\n\n", extractedCmdName)
+	} else {
+		fmt.Fprintf(w, "Usage:\n  %s <command> [flags]\n\n", extractedCmdName)
+	}
+	fmt.Fprintln(w, "Commands:")
+
+	maxNameLen := 0
+	for _, sub := range cmdMeta.Subcommands {
+		if l := len(sub.Name); l > maxNameLen {
+			maxNameLen = l
+		}
+	}
+	for _, sub := range cmdMeta.Subcommands {
+		summary, _, _ := strings.Cut(sub.Description, "\n")
+		fmt.Fprintln(w, strings.TrimRight(fmt.Sprintf("  %-*s  %s", maxNameLen, sub.Name, summary), " "))
 	}
 
+	if len(cmdMeta.GlobalOptions) > 0 {
+		fmt.Fprintln(w, "")
+		fmt.Fprintln(w, "Global Flags:")
+		writeOptionSections(w, cmdMeta.GlobalOptions, maxOptionNameLen(cmdMeta.GlobalOptions), maxTypeLen(cmdMeta.GlobalOptions))
+	}
+	if len(cmdMeta.GlobalOptionGroups) > 0 {
+		fmt.Fprintln(w, "")
+		fmt.Fprintln(w, "Constraints:")
+		writeConstraints(w, cmdMeta.GlobalOptionGroups, cmdMeta.GlobalOptions)
+	}
+
+	fmt.Fprintf(w, "\nUse \"%s <command> --help\" for more information about a command.\n", extractedCmdName)
+}
+
+func generateHelp(w io.Writer, extractedCmdName string, cmdMeta *metadata.CommandMetadata) {
 	fmt.Fprintf(w, "%s - %s\n\n", extractedCmdName, strings.ReplaceAll(cmdMeta.Description, "\n", "\n         "))
-	fmt.Fprintf(w, "Usage:\n  %s [flags]\n\n", extractedCmdName) // Removed CommandArgsPlaceholder and trailing space
+	var flagOptions, argOptions []*metadata.OptionMetadata
+	for _, opt := range cmdMeta.Options {
+		if opt.Positional != nil {
+			argOptions = append(argOptions, opt)
+		} else {
+			flagOptions = append(flagOptions, opt)
+		}
+	}
+	sort.SliceStable(argOptions, func(i, j int) bool { return argOptions[i].Positional.Index < argOptions[j].Positional.Index })
+
+	if len(argOptions) > 0 {
+		fmt.Fprintf(w, "Usage:\n  %s [flags] %s\n\n", extractedCmdName, argsUsage(argOptions))
+		writeArguments(w, argOptions)
+	} else {
+		fmt.Fprintf(w, "Usage:\n  %s [flags]\n\n", extractedCmdName) // Removed CommandArgsPlaceholder and trailing space
+	}
 	fmt.Fprintln(w, "Flags:")
 
-	// Find max length of option names for alignment (include -h, --help)
-	maxNameLen := len("h, --help") // Length of "h, --help"
-	for _, opt := range cmdMeta.Options {
-		currentCliName := opt.CliName
-		// Check if the flag is a boolean, required, and defaults to true
-		if opt.TypeName == "bool" && opt.IsRequired && opt.DefaultValueAsBool() {
-			currentCliName = "no-" + opt.CliName
+	// Find max length of option names for alignment (include -h, --help and the global flags)
+	maxNameLen := maxOptionNameLen(flagOptions, cmdMeta.GlobalOptions)
+	if cmdMeta.PrintConfig && len("print-config") > maxNameLen {
+		maxNameLen = len("print-config")
+	}
+	typeLen := maxTypeLen(flagOptions, cmdMeta.GlobalOptions)
+	writeOptionSections(w, flagOptions, maxNameLen, typeLen)
+
+	if len(cmdMeta.GlobalOptions) > 0 {
+		fmt.Fprintln(w, "")
+		fmt.Fprintln(w, "Global Flags:")
+		writeOptionSections(w, cmdMeta.GlobalOptions, maxNameLen, typeLen)
+	}
+
+	fmt.Fprintln(w, "")
+	helpName := "h, --help"
+	helpText := "Show this help message and exit"
+	fmt.Fprintf(w, "  -%-*s %-*s %s\n", maxNameLen, helpName, typeLen, "", helpText) // Added empty type indicator for alignment
+	if cmdMeta.DotEnv {
+		fmt.Fprintf(w, "  --%-*s %-*s %s\n", maxNameLen, "env-file", typeLen, "string", `Load environment variables from the file (default: ".env")`)
+	}
+	if cmdMeta.PrintConfig {
+		fmt.Fprintf(w, "  --%-*s %-*s %s\n", maxNameLen, "print-config", typeLen, "", "Print the effective options and their sources (text, or json with --print-config=json), and exit")
+	}
+
+	if len(cmdMeta.OptionGroups) > 0 || len(cmdMeta.GlobalOptionGroups) > 0 {
+		fmt.Fprintln(w, "")
+		fmt.Fprintln(w, "Constraints:")
+		writeConstraints(w, cmdMeta.OptionGroups, cmdMeta.Options)
+		writeConstraints(w, cmdMeta.GlobalOptionGroups, cmdMeta.GlobalOptions)
+	}
+}
+
+// writeConstraints writes the lines of the "Constraints:" section, a line per option group (goat.OneOf, goat.Together).
+func writeConstraints(w io.Writer, groups []*metadata.OptionGroup, options []*metadata.OptionMetadata) {
+	optionsByName := make(map[string]*metadata.OptionMetadata, len(options))
+	for _, opt := range options {
+		optionsByName[opt.Name] = opt
+	}
+	for _, group := range groups {
+		names := make([]string, 0, len(group.Options))
+		for _, name := range group.Options {
+			if opt, ok := optionsByName[name]; ok {
+				names = append(names, "--"+displayName(opt))
+			}
+		}
+		fmt.Fprintf(w, "  %s: %s\n", strings.Join(names, ", "), group.Description())
+	}
+}
+
+// argsUsage returns the positional arguments part of the usage line (e.g. "<src> [dst] <files>...").
+func argsUsage(argOptions []*metadata.OptionMetadata) string {
+	parts := make([]string, 0, len(argOptions))
+	for _, opt := range argOptions {
+		switch {
+		case opt.Positional.Arity == "*":
+			parts = append(parts, fmt.Sprintf("[%s...]", opt.CliName))
+		case opt.Positional.Arity != "":
+			parts = append(parts, fmt.Sprintf("<%s>...", opt.CliName))
+		case opt.IsPointer:
+			parts = append(parts, fmt.Sprintf("[%s]", opt.CliName))
+		default:
+			parts = append(parts, fmt.Sprintf("<%s>", opt.CliName))
 		}
-		if l := len(currentCliName); l > maxNameLen {
+	}
+	return strings.Join(parts, " ")
+}
+
+// writeArguments writes the "Arguments:" section, a line per positional argument.
+func writeArguments(w io.Writer, argOptions []*metadata.OptionMetadata) {
+	fmt.Fprintln(w, "Arguments:")
+	maxNameLen := 0
+	for _, opt := range argOptions {
+		if l := len(opt.CliName); l > maxNameLen {
 			maxNameLen = l
 		}
 	}
+	for _, opt := range argOptions {
+		arity := "(required)"
+		switch opt.Positional.Arity {
+		case "":
+			if opt.IsPointer {
+				arity = "(optional)"
+			}
+		case "*":
+			arity = "(zero or more)"
+		case "+":
+			arity = "(one or more)"
+		default:
+			arity = fmt.Sprintf("(exactly %s)", opt.Positional.Arity)
+		}
+		helpText := strings.ReplaceAll(opt.HelpText, "\n", "\n"+strings.Repeat(" ", 2+maxNameLen+2))
+		if helpText != "" {
+			helpText += " "
+		}
+		fmt.Fprintf(w, "  %-*s  %s%s\n", maxNameLen, opt.CliName, helpText, arity)
+	}
+	fmt.Fprintln(w, "")
+}
 
-	for _, opt := range cmdMeta.Options {
-		baseType := strings.TrimPrefix(opt.TypeName, "*")
-		baseType = strings.TrimPrefix(baseType, "[]")
-		parts := strings.Split(baseType, ".")
-		typeIndicator := strings.ToLower(parts[len(parts)-1])
-		if strings.HasPrefix(opt.TypeName, "[]") {
-			typeIndicator += "s"
+// displayName returns the flag name shown in help messages.
+func displayName(opt *metadata.OptionMetadata) string {
+	// Check if the flag is a boolean, required, and defaults to true for display
+	if opt.TypeName == "bool" && opt.IsRequired && opt.DefaultValueAsBool() {
+		return "no-" + opt.CliName
+	}
+	return opt.CliName
+}
+
+// flagLabel returns all the flag names of opt shown in help messages,
+// e.g. "--verbose", or "-v, --verbose, --loud" with a short name and an alias.
+func flagLabel(opt *metadata.OptionMetadata) string {
+	return strings.Join(FlagNames(opt), ", ")
+}
+
+// FlagNames returns the flag names of opt with their dashes, the short name first (e.g. "-v", "--verbose", "--loud").
+func FlagNames(opt *metadata.OptionMetadata) []string {
+	name := displayName(opt)
+	prefix := strings.TrimSuffix(name, opt.CliName) // "no-" for --no-<flag>
+	var names []string
+	if opt.ShortName != "" {
+		names = append(names, "-"+prefix+opt.ShortName)
+	}
+	names = append(names, "--"+name)
+	for _, alias := range opt.Aliases {
+		names = append(names, "--"+prefix+alias)
+	}
+	return names
+}
+
+// maxOptionNameLen returns the max length of the flag labels without the leading "--" for alignment (at least the length of "h, --help").
+func maxOptionNameLen(optionsList ...[]*metadata.OptionMetadata) int {
+	maxNameLen := len("h, --help") // Length of "h, --help"
+	for _, options := range optionsList {
+		for _, opt := range options {
+			if l := len(flagLabel(opt)) - len("--"); l > maxNameLen {
+				maxNameLen = l
+			}
+		}
+	}
+	return maxNameLen
+}
+
+// maxTypeLen returns the max length of the type indicators for alignment (at least 8, e.g. for "key=value" of a map option).
+func maxTypeLen(optionsList ...[]*metadata.OptionMetadata) int {
+	maxLen := 8
+	for _, options := range optionsList {
+		for _, opt := range options {
+			if l := len(TypeIndicator(opt)); l > maxLen {
+				maxLen = l
+			}
 		}
+	}
+	return maxLen
+}
 
-		displayName := opt.CliName
-		// Check if the flag is a boolean, required, and defaults to true for display
-		if opt.TypeName == "bool" && opt.IsRequired && opt.DefaultValueAsBool() {
-			displayName = "no-" + opt.CliName
+// writeOptionSections writes the options, those of each nested struct (with a Section) in their own section
+// after the others, under a heading taken from the doc comment of the struct field (e.g. "Database:").
+func writeOptionSections(w io.Writer, options []*metadata.OptionMetadata, maxNameLen int, typeLen int) {
+	var sections []string
+	optionsBySection := make(map[string][]*metadata.OptionMetadata)
+	for _, opt := range options {
+		if _, exists := optionsBySection[opt.Section]; !exists && opt.Section != "" {
+			sections = append(sections, opt.Section)
 		}
+		optionsBySection[opt.Section] = append(optionsBySection[opt.Section], opt)
+	}
+	writeOptions(w, optionsBySection[""], maxNameLen, typeLen)
+	for _, section := range sections {
+		heading, _, _ := strings.Cut(section, "\n") // The first line of the doc comment
+		fmt.Fprintln(w, "")
+		fmt.Fprintf(w, "%s:\n", strings.TrimSuffix(heading, "."))
+		writeOptions(w, optionsBySection[section], maxNameLen, typeLen)
+	}
+}
 
-		// Indentation for multi-line help text: "  " + maxNameLen + " " + 8 (type width) + " "
-		helpTextIndent := strings.Repeat(" ", 2+maxNameLen+1+8+1)
+// writeOptions writes a line per option, aligned to maxNameLen and typeLen.
+func writeOptions(w io.Writer, options []*metadata.OptionMetadata, maxNameLen int, typeLen int) {
+	for _, opt := range options {
+		// Indentation for multi-line help text: "  " + maxNameLen + " " + typeLen + " "
+		helpTextIndent := strings.Repeat(" ", 2+maxNameLen+1+typeLen+1)
 		helpText := strings.ReplaceAll(opt.HelpText, "\n", "\n"+helpTextIndent)
-		fmt.Fprintf(w, "  --%-*s %-8s %s", maxNameLen, displayName, typeIndicator, helpText)
-		if opt.IsRequired && (opt.DefaultValue == nil || opt.DefaultValue == "") && !(opt.TypeName == "bool" || opt.TypeName == "*bool") {
+		fmt.Fprintf(w, "  %-*s %-*s %s", len("--")+maxNameLen, flagLabel(opt), typeLen, TypeIndicator(opt), helpText)
+		if IsRequiredFlag(opt) {
 			fmt.Fprint(w, " (required)")
 		}
 
-		// Default value printing logic
-		shouldPrintDefault := opt.DefaultValue != nil && opt.DefaultValue != "" // Initial state
-		if strings.HasSuffix(opt.TypeName, "bool") {                            // Covers "bool" and "*bool"
-			isDefaultTrue := opt.DefaultValueAsBool() // Correctly handles nil, non-bool, *bool
-			if !isDefaultTrue {
-				// If default is false (or nil for *bool), don't print default.
-				shouldPrintDefault = false
-			} else { // Default is true
-				if opt.IsRequired {
-					// If required and default is true, it's displayed as --no-flag, so don't print default.
-					shouldPrintDefault = false
-				}
-				// Else (not required and default is true), shouldPrintDefault remains true to print (default: true)
-			}
+		if defaultText := DefaultText(opt); defaultText != "" {
+			fmt.Fprintf(w, " (default: %s)", defaultText)
 		}
 
-		if shouldPrintDefault {
-			if s, ok := opt.DefaultValue.(string); ok {
-				fmt.Fprintf(w, " (default: %q)", s)
-			} else {
-				fmt.Fprintf(w, " (default: %v)", opt.DefaultValue)
-			}
-		}
-
-		if opt.EnvVar != "" {
+		if opt.IsSecret && opt.EnvVar != "" {
+			fmt.Fprintf(w, " (env: %s or %s)", opt.EnvVar, opt.SecretFileEnvVar())
+		} else if opt.EnvVar != "" {
 			fmt.Fprintf(w, " (env: %s)", opt.EnvVar)
 		}
-		if len(opt.EnumValues) > 0 {
-			var enumStrs []string
-			for _, v := range opt.EnumValues {
-				if s, ok := v.(string); ok {
-					enumStrs = append(enumStrs, fmt.Sprintf("%q", s))
-				} else {
-					enumStrs = append(enumStrs, fmt.Sprintf("%v", v))
-				}
+		if opt.IsSecret {
+			fmt.Fprintf(w, " (secret, file: --%s)", opt.SecretFileFlag())
+		}
+		if allowed := AllowedValues(opt); allowed != "" {
+			fmt.Fprintf(w, " (allowed: %s)", allowed)
+		}
+		if valueRange := opt.ValueRange(); valueRange != "" {
+			fmt.Fprintf(w, " (range: %s)", valueRange)
+		}
+		if lengthRange := opt.LengthRange(); lengthRange != "" {
+			if opt.LengthInCharacters() {
+				lengthRange += " characters"
 			}
-			fmt.Fprintf(w, " (allowed: %s)", strings.Join(enumStrs, ", "))
+			fmt.Fprintf(w, " (length: %s)", lengthRange)
+		}
+		if opt.Pattern != "" {
+			fmt.Fprintf(w, " (pattern: %s)", opt.Pattern)
 		}
 
 		var fileInfoParts []string
@@ -119,12 +330,87 @@ func generateHelp(w io.Writer, cmdMeta *metadata.CommandMetadata) {
 		if len(fileInfoParts) > 0 {
 			fmt.Fprintf(w, " (file, %s)", strings.Join(fileInfoParts, ", "))
 		}
+		if opt.IsDir {
+			if policy := opt.DirPolicy(); policy != "" {
+				fmt.Fprintf(w, " (directory, %s)", policy)
+			} else {
+				fmt.Fprint(w, " (directory)")
+			}
+		}
 
 		fmt.Fprintln(w) // This is the existing newline print
 	}
+}
 
-	fmt.Fprintln(w, "")
-	helpName := "h, --help"
-	helpText := "Show this help message and exit"
-	fmt.Fprintf(w, "  -%-*s %-8s %s\n", maxNameLen, helpName, "", helpText) // Added empty type indicator for alignment
+// TypeIndicator returns the type of opt shown in help messages (e.g. "string", "ints", "duration", "key=value").
+func TypeIndicator(opt *metadata.OptionMetadata) string {
+	if strings.HasPrefix(opt.UnderlyingKind, "map[") {
+		return "key=value"
+	}
+	baseType := strings.TrimPrefix(opt.TypeName, "*")
+	baseType = strings.TrimPrefix(baseType, "[]")
+	parts := strings.Split(baseType, ".")
+	typeIndicator := strings.ToLower(parts[len(parts)-1])
+	if strings.HasPrefix(opt.TypeName, "[]") {
+		typeIndicator += "s"
+	}
+	return typeIndicator
+}
+
+// IsRequiredFlag reports whether opt is shown as required: a non-boolean required option without a default value.
+func IsRequiredFlag(opt *metadata.OptionMetadata) bool {
+	return opt.IsRequired && (opt.DefaultValue == nil || opt.DefaultValue == "") && !(opt.TypeName == "bool" || opt.TypeName == "*bool")
+}
+
+// DefaultText returns the default value of opt shown in help messages (e.g. `"info"`, `5s` or `a=1,b=2`),
+// or an empty string if it is not shown: false booleans, --no-<flag> booleans and secret options.
+func DefaultText(opt *metadata.OptionMetadata) string {
+	shouldPrintDefault := opt.DefaultValue != nil && opt.DefaultValue != "" // Initial state
+	if strings.HasSuffix(opt.TypeName, "bool") {                            // Covers "bool" and "*bool"
+		isDefaultTrue := opt.DefaultValueAsBool() // Correctly handles nil, non-bool, *bool
+		if !isDefaultTrue {
+			// If default is false (or nil for *bool), don't print default.
+			shouldPrintDefault = false
+		} else { // Default is true
+			if opt.IsRequired {
+				// If required and default is true, it's displayed as --no-flag, so don't print default.
+				shouldPrintDefault = false
+			}
+			// Else (not required and default is true), shouldPrintDefault remains true to print (default: true)
+		}
+	}
+	if !shouldPrintDefault || opt.IsSecret { // The default value of a secret option is not shown
+		return ""
+	}
+
+	baseType := strings.TrimPrefix(strings.TrimPrefix(opt.TypeName, "*"), "[]")
+	if m, ok := opt.DefaultValue.(map[string]any); ok && strings.HasPrefix(opt.UnderlyingKind, "map[") { // Shown like the environment variable (e.g. a=1,b=2)
+		return formatMapDefault(m)
+	} else if s, ok := opt.DefaultValue.(string); ok && baseType != "time.Duration" { // Durations are shown as is (e.g. 5s)
+		return fmt.Sprintf("%q", s)
+	}
+	return fmt.Sprintf("%v", opt.DefaultValue)
+}
+
+// AllowedValues returns the enum values of opt shown in help messages (e.g. `"debug", "info"`), or an empty string.
+func AllowedValues(opt *metadata.OptionMetadata) string {
+	var enumStrs []string
+	for _, v := range opt.EnumValues {
+		if s, ok := v.(string); ok {
+			enumStrs = append(enumStrs, fmt.Sprintf("%q", s))
+		} else {
+			enumStrs = append(enumStrs, fmt.Sprintf("%v", v))
+		}
+	}
+	return strings.Join(enumStrs, ", ")
+}
+
+// formatMapDefault formats the default value of a map option as comma-separated key=value pairs, sorted by key.
+func formatMapDefault(m map[string]any) string {
+	pairs := make([]string, 0, len(m))
+	for key, value := range m {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", key, value))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
 }
@@ -205,3 +205,472 @@ func TestGenerateHelp_qux(t *testing.T) {
 		t.Errorf("GenerateHelp() with 'qux' did not display name correctly in usage.\nExpected to contain: %q\nGot:\n%s", expectedUsage, actualHelp)
 	}
 }
+
+func TestGenerateHelp_Subcommands(t *testing.T) {
+	cmdMeta := &metadata.CommandMetadata{
+		Name:        "mytool",
+		Description: "A tool with subcommands.",
+		Subcommands: []*metadata.CommandMetadata{
+			{Name: "serve", Description: "Start the server.\nIt listens forever."},
+			{Name: "db-migrate", Description: "Apply migrations."},
+		},
+	}
+
+	helpMsg := GenerateHelp(cmdMeta)
+
+	expected := `mytool - A tool with subcommands.
+
+Usage:
+  mytool <command> [flags]
+
+Commands:
+  serve       Start the server.
+  db-migrate  Apply migrations.
+
+Use "mytool <command> --help" for more information about a command.
+`
+	if helpMsg != expected {
+		t.Errorf("help message mismatch:\n---EXPECTED---\n%s\n\n---ACTUAL---\n%s", expected, helpMsg)
+	}
+}
+
+func TestGenerateSubcommandHelp(t *testing.T) {
+	parentMeta := &metadata.CommandMetadata{Name: "example.com/mytool"}
+	subMeta := &metadata.CommandMetadata{
+		Name:        "serve",
+		Description: "Start the server.",
+		Options: []*metadata.OptionMetadata{
+			{Name: "Port", CliName: "port", TypeName: "int", HelpText: "Port number.", DefaultValue: 8080},
+		},
+	}
+
+	helpMsg := GenerateSubcommandHelp(parentMeta, subMeta)
+
+	expected := `mytool serve - Start the server.
+
+Usage:
+  mytool serve [flags]
+
+Flags:
+  --port      int      Port number. (default: 8080)
+
+  -h, --help          Show this help message and exit
+`
+	if helpMsg != expected {
+		t.Errorf("help message mismatch:\n---EXPECTED---\n%s\n\n---ACTUAL---\n%s", expected, helpMsg)
+	}
+}
+
+func TestGenerateHelp_GlobalOptions(t *testing.T) {
+	globalOptions := []*metadata.OptionMetadata{
+		{Name: "Verbose", CliName: "verbose", TypeName: "bool", HelpText: "Enable verbose output.", EnvVar: "VERBOSE"},
+	}
+
+	t.Run("single command", func(t *testing.T) {
+		cmdMeta := &metadata.CommandMetadata{
+			Name:        "mytool",
+			Description: "A tool.",
+			Options: []*metadata.OptionMetadata{
+				{Name: "Port", CliName: "port", TypeName: "int", HelpText: "Port number.", DefaultValue: 8080},
+			},
+			GlobalOptions: globalOptions,
+		}
+
+		helpMsg := GenerateHelp(cmdMeta)
+
+		expected := `mytool - A tool.
+
+Usage:
+  mytool [flags]
+
+Flags:
+  --port      int      Port number. (default: 8080)
+
+Global Flags:
+  --verbose   bool     Enable verbose output. (env: VERBOSE)
+
+  -h, --help          Show this help message and exit
+`
+		if helpMsg != expected {
+			t.Errorf("help message mismatch:\n---EXPECTED---\n%s\n\n---ACTUAL---\n%s", expected, helpMsg)
+		}
+	})
+
+	t.Run("subcommands", func(t *testing.T) {
+		cmdMeta := &metadata.CommandMetadata{
+			Name:          "mytool",
+			Description:   "A tool with subcommands.",
+			GlobalOptions: globalOptions,
+			Subcommands: []*metadata.CommandMetadata{
+				{Name: "serve", Description: "Start the server."},
+			},
+		}
+
+		helpMsg := GenerateHelp(cmdMeta)
+
+		expected := `mytool - A tool with subcommands.
+
+Usage:
+  mytool [global flags] <command> [flags]
+
+Commands:
+  serve  Start the server.
+
+Global Flags:
+  --verbose   bool     Enable verbose output. (env: VERBOSE)
+
+Use "mytool <command> --help" for more information about a command.
+`
+		if helpMsg != expected {
+			t.Errorf("help message mismatch:\n---EXPECTED---\n%s\n\n---ACTUAL---\n%s", expected, helpMsg)
+		}
+	})
+}
+
+func TestGenerateHelp_PositionalArgs(t *testing.T) {
+	cmdMeta := &metadata.CommandMetadata{
+		Name:        "cp",
+		Description: "Copy files.",
+		Options: []*metadata.OptionMetadata{
+			{Name: "Verbose", CliName: "verbose", TypeName: "bool", HelpText: "Enable verbose output."},
+			{Name: "Dst", CliName: "dst", TypeName: "*string", HelpText: "Destination.", IsPointer: true, Positional: &metadata.PositionalArg{Index: 1}},
+			{Name: "Src", CliName: "src", TypeName: "string", HelpText: "Source.", IsRequired: true, Positional: &metadata.PositionalArg{Index: 0}},
+			{Name: "Exclude", CliName: "exclude", TypeName: "[]string", IsRequired: true, Positional: &metadata.PositionalArg{Index: 2, Arity: "*"}},
+		},
+	}
+
+	helpMsg := GenerateHelp(cmdMeta)
+
+	expected := `cp - Copy files.
+
+Usage:
+  cp [flags] <src> [dst] [exclude...]
+
+Arguments:
+  src      Source. (required)
+  dst      Destination. (optional)
+  exclude  (zero or more)
+
+Flags:
+  --verbose   bool     Enable verbose output.
+
+  -h, --help          Show this help message and exit
+`
+	if helpMsg != expected {
+		t.Errorf("help message mismatch:\n---EXPECTED---\n%s\n\n---ACTUAL---\n%s", expected, helpMsg)
+	}
+}
+
+func TestGenerateHelp_FlagAliases(t *testing.T) {
+	cmdMeta := &metadata.CommandMetadata{
+		Name:        "app",
+		Description: "An app.",
+		Options: []*metadata.OptionMetadata{
+			{Name: "Verbose", CliName: "verbose", TypeName: "bool", HelpText: "Enable verbose output.", ShortName: "v"},
+			{Name: "OutputDir", CliName: "output-dir", TypeName: "string", HelpText: "Output directory.", DefaultValue: ".", Aliases: []string{"out"}},
+			{Name: "Port", CliName: "port", TypeName: "int", HelpText: "Port."},
+		},
+	}
+
+	helpMsg := GenerateHelp(cmdMeta)
+
+	expected := `app - An app.
+
+Usage:
+  app [flags]
+
+Flags:
+  -v, --verbose       bool     Enable verbose output.
+  --output-dir, --out string   Output directory. (default: ".")
+  --port              int      Port.
+
+  -h, --help                  Show this help message and exit
+`
+	if helpMsg != expected {
+		t.Errorf("help message mismatch:\n---EXPECTED---\n%s\n\n---ACTUAL---\n%s", expected, helpMsg)
+	}
+}
+
+func TestGenerateHelp_ValueConstraints(t *testing.T) {
+	minLen := 3
+	cmdMeta := &metadata.CommandMetadata{
+		Name:        "server",
+		Description: "A server.",
+		Options: []*metadata.OptionMetadata{
+			{Name: "Port", CliName: "port", TypeName: "int", HelpText: "Port.", DefaultValue: 8080, MinValue: int64(1), MaxValue: int64(65535)},
+			{Name: "Ratio", CliName: "ratio", TypeName: "float64", HelpText: "Ratio.", MaxValue: 0.5},
+			{Name: "Name", CliName: "name", TypeName: "string", HelpText: "Name.", MinLen: &minLen, Pattern: "^[a-z]+$"},
+		},
+	}
+
+	helpMsg := GenerateHelp(cmdMeta)
+
+	expected := `server - A server.
+
+Usage:
+  server [flags]
+
+Flags:
+  --port      int      Port. (default: 8080) (range: 1..65535)
+  --ratio     float64  Ratio. (range: ..0.5)
+  --name      string   Name. (length: 3.. characters) (pattern: ^[a-z]+$)
+
+  -h, --help          Show this help message and exit
+`
+	if helpMsg != expected {
+		t.Errorf("help message mismatch:\n---EXPECTED---\n%s\n\n---ACTUAL---\n%s", expected, helpMsg)
+	}
+}
+
+func TestGenerateHelp_DirOptions(t *testing.T) {
+	cmdMeta := &metadata.CommandMetadata{
+		Name:        "gen",
+		Description: "Generate files.",
+		Options: []*metadata.OptionMetadata{
+			{Name: "OutputDir", CliName: "output-dir", TypeName: "string", HelpText: "Output directory.", DefaultValue: "output", IsDir: true, DirCreateIfMissing: true},
+			{Name: "InputDir", CliName: "input-dir", TypeName: "string", HelpText: "Input directory.", DefaultValue: ".", IsDir: true, DirMustExist: true},
+			{Name: "WorkDir", CliName: "work-dir", TypeName: "*string", HelpText: "Work directory.", IsDir: true},
+		},
+	}
+
+	helpMsg := GenerateHelp(cmdMeta)
+
+	expected := `gen - Generate files.
+
+Usage:
+  gen [flags]
+
+Flags:
+  --output-dir string   Output directory. (default: "output") (directory, created if missing)
+  --input-dir  string   Input directory. (default: ".") (directory, must exist)
+  --work-dir   string   Work directory. (directory)
+
+  -h, --help           Show this help message and exit
+`
+	if helpMsg != expected {
+		t.Errorf("help message mismatch:\n---EXPECTED---\n%s\n\n---ACTUAL---\n%s", expected, helpMsg)
+	}
+}
+
+func TestGenerateHelp_OptionGroups(t *testing.T) {
+	cmdMeta := &metadata.CommandMetadata{
+		Name:        "app",
+		Description: "Print the report.",
+		Options: []*metadata.OptionMetadata{
+			{Name: "JSON", CliName: "json", TypeName: "bool", HelpText: "Output as JSON."},
+			{Name: "YAML", CliName: "yaml", TypeName: "bool", HelpText: "Output as YAML."},
+			{Name: "TLSCert", CliName: "tls-cert", TypeName: "string", HelpText: "TLS certificate."},
+			{Name: "TLSKey", CliName: "tls-key", TypeName: "string", HelpText: "TLS key."},
+		},
+		OptionGroups: []*metadata.OptionGroup{
+			{Kind: metadata.OptionGroupOneOf, Options: []string{"JSON", "YAML"}},
+			{Kind: metadata.OptionGroupTogether, Options: []string{"TLSCert", "TLSKey"}},
+		},
+	}
+
+	helpMsg := GenerateHelp(cmdMeta)
+
+	expected := `app - Print the report.
+
+Usage:
+  app [flags]
+
+Flags:
+  --json      bool     Output as JSON.
+  --yaml      bool     Output as YAML.
+  --tls-cert  string   TLS certificate.
+  --tls-key   string   TLS key.
+
+  -h, --help          Show this help message and exit
+
+Constraints:
+  --json, --yaml: mutually exclusive
+  --tls-cert, --tls-key: must be given together
+`
+	if helpMsg != expected {
+		t.Errorf("help message mismatch:\n---EXPECTED---\n%s\n\n---ACTUAL---\n%s", expected, helpMsg)
+	}
+}
+
+func TestGenerateHelp_NumericTypes(t *testing.T) {
+	cmdMeta := &metadata.CommandMetadata{
+		Name:        "app",
+		Description: "Wait for it.",
+		Options: []*metadata.OptionMetadata{
+			{Name: "Timeout", CliName: "timeout", TypeName: "time.Duration", HelpText: "Timeout.", DefaultValue: "5s", IsRequired: true},
+			{Name: "Ratio", CliName: "ratio", TypeName: "float64", HelpText: "Ratio.", DefaultValue: 0.5, IsRequired: true},
+			{Name: "Size", CliName: "size", TypeName: "int64", HelpText: "Size.", IsRequired: true},
+			{Name: "Count", CliName: "count", TypeName: "*uint", HelpText: "Count.", IsPointer: true},
+		},
+	}
+
+	helpMsg := GenerateHelp(cmdMeta)
+
+	expected := `app - Wait for it.
+
+Usage:
+  app [flags]
+
+Flags:
+  --timeout   duration Timeout. (default: 5s)
+  --ratio     float64  Ratio. (default: 0.5)
+  --size      int64    Size. (required)
+  --count     uint     Count.
+
+  -h, --help          Show this help message and exit
+`
+	if helpMsg != expected {
+		t.Errorf("help message mismatch:\n---EXPECTED---\n%s\n\n---ACTUAL---\n%s", expected, helpMsg)
+	}
+}
+
+func TestGenerateHelp_MapOptions(t *testing.T) {
+	cmdMeta := &metadata.CommandMetadata{
+		Name:        "app",
+		Description: "Label things.",
+		Options: []*metadata.OptionMetadata{
+			{Name: "Label", CliName: "label", TypeName: "map[string]string", UnderlyingKind: "map[string]string", HelpText: "Labels.", DefaultValue: map[string]any{"env": "dev", "app": "x"}, EnvVar: "LABELS", IsRequired: true},
+			{Name: "Limit", CliName: "limit", TypeName: "Limits", UnderlyingKind: "map[string]int", HelpText: "Limits.", IsRequired: true},
+			{Name: "Name", CliName: "name", TypeName: "string", HelpText: "Name.", DefaultValue: "x"},
+		},
+	}
+
+	helpMsg := GenerateHelp(cmdMeta)
+
+	// The type column is as wide as "key=value".
+	expected := `app - Label things.
+
+Usage:
+  app [flags]
+
+Flags:
+  --label     key=value Labels. (default: app=x,env=dev) (env: LABELS)
+  --limit     key=value Limits. (required)
+  --name      string    Name. (default: "x")
+
+  -h, --help           Show this help message and exit
+`
+	if helpMsg != expected {
+		t.Errorf("help message mismatch:\n---EXPECTED---\n%s\n\n---ACTUAL---\n%s", expected, helpMsg)
+	}
+}
+
+func TestGenerateHelp_NestedStructSections(t *testing.T) {
+	cmdMeta := &metadata.CommandMetadata{
+		Name:        "app",
+		Description: "Serve things.",
+		Options: []*metadata.OptionMetadata{
+			{Name: "DB.Host", CliName: "db-host", TypeName: "string", HelpText: "Host.", DefaultValue: "localhost", EnvVar: "APP_DB_HOST", Section: "Database connection.\nThe primary one."},
+			{Name: "Verbose", CliName: "verbose", TypeName: "bool", HelpText: "Verbose."},
+			{Name: "Cache.Port", CliName: "cache-port", TypeName: "int", HelpText: "Port.", DefaultValue: 6379, Section: "Cache"},
+			{Name: "DB.Port", CliName: "db-port", TypeName: "int", HelpText: "Port.", DefaultValue: 5432, Section: "Database connection.\nThe primary one."},
+		},
+	}
+
+	helpMsg := GenerateHelp(cmdMeta)
+
+	expected := `app - Serve things.
+
+Usage:
+  app [flags]
+
+Flags:
+  --verbose    bool     Verbose.
+
+Database connection:
+  --db-host    string   Host. (default: "localhost") (env: APP_DB_HOST)
+  --db-port    int      Port. (default: 5432)
+
+Cache:
+  --cache-port int      Port. (default: 6379)
+
+  -h, --help           Show this help message and exit
+`
+	if helpMsg != expected {
+		t.Errorf("help message mismatch:\n---EXPECTED---\n%s\n\n---ACTUAL---\n%s", expected, helpMsg)
+	}
+}
+
+func TestGenerateHelp_DotEnv(t *testing.T) {
+	cmdMeta := &metadata.CommandMetadata{
+		Name:        "app",
+		Description: "Greet someone.",
+		Options: []*metadata.OptionMetadata{
+			{Name: "Name", CliName: "name", TypeName: "string", HelpText: "Name.", EnvVar: "APP_NAME", IsRequired: true},
+		},
+		DotEnv: true,
+	}
+
+	helpMsg := GenerateHelp(cmdMeta)
+
+	expected := `app - Greet someone.
+
+Usage:
+  app [flags]
+
+Flags:
+  --name      string   Name. (required) (env: APP_NAME)
+
+  -h, --help          Show this help message and exit
+  --env-file  string   Load environment variables from the file (default: ".env")
+`
+	if helpMsg != expected {
+		t.Errorf("help message mismatch:\n---EXPECTED---\n%s\n\n---ACTUAL---\n%s", expected, helpMsg)
+	}
+}
+
+func TestGenerateHelp_PrintConfig(t *testing.T) {
+	cmdMeta := &metadata.CommandMetadata{
+		Name:        "app",
+		Description: "Greet someone.",
+		Options: []*metadata.OptionMetadata{
+			{Name: "Name", CliName: "name", TypeName: "string", HelpText: "Name.", EnvVar: "APP_NAME", IsRequired: true},
+		},
+		PrintConfig: true,
+	}
+
+	helpMsg := GenerateHelp(cmdMeta)
+
+	expected := `app - Greet someone.
+
+Usage:
+  app [flags]
+
+Flags:
+  --name         string   Name. (required) (env: APP_NAME)
+
+  -h, --help             Show this help message and exit
+  --print-config          Print the effective options and their sources (text, or json with --print-config=json), and exit
+`
+	if helpMsg != expected {
+		t.Errorf("help message mismatch:\n---EXPECTED---\n%s\n\n---ACTUAL---\n%s", expected, helpMsg)
+	}
+}
+
+func TestGenerateHelp_SecretOptions(t *testing.T) {
+	cmdMeta := &metadata.CommandMetadata{
+		Name:        "app",
+		Description: "Connect to the database.",
+		Options: []*metadata.OptionMetadata{
+			{Name: "User", CliName: "user", TypeName: "string", HelpText: "User.", DefaultValue: "admin"},
+			{Name: "Password", CliName: "password", TypeName: "string", HelpText: "Password.", EnvVar: "DB_PASSWORD", IsSecret: true, DefaultValue: "changeme"},
+		},
+	}
+
+	helpMsg := GenerateHelp(cmdMeta)
+
+	expected := `app - Connect to the database.
+
+Usage:
+  app [flags]
+
+Flags:
+  --user      string   User. (default: "admin")
+  --password  string   Password. (env: DB_PASSWORD or DB_PASSWORD_FILE) (secret, file: --password-file)
+
+  -h, --help          Show this help message and exit
+`
+	if helpMsg != expected {
+		t.Errorf("help message mismatch:\n---EXPECTED---\n%s\n\n---ACTUAL---\n%s", expected, helpMsg)
+	}
+}
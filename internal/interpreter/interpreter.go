@@ -5,8 +5,12 @@ import (
 	"fmt"
 	"go/ast"
 	"go/token"
+	"go/types"
 	"log/slog"
+	"regexp"
+	"slices"
 	"strconv" // Added strconv import
+	"strings"
 
 	"github.com/podhmo/goat/internal/loader"
 	"github.com/podhmo/goat/internal/metadata"
@@ -59,6 +63,26 @@ func InterpretInitializer(
 
 	slog.InfoContext(ctx, fmt.Sprintf("Interpreting initializer: %s", initializerFuncName))
 
+	var interpretErr error // The first error of the markers, if any
+	var interpretCompositeLit func(compLit *ast.CompositeLit, prefix string)
+	interpretCompositeLit = func(compLit *ast.CompositeLit, prefix string) {
+		for _, elt := range compLit.Elts {
+			if kvExpr, ok := elt.(*ast.KeyValueExpr); ok {
+				if keyIdent, ok := kvExpr.Key.(*ast.Ident); ok {
+					fieldName := prefix + keyIdent.Name
+					if optMeta, exists := optionsMap[fieldName]; exists {
+						markSetByInitializer(optMeta, kvExpr.Value, loader)
+						if err := extractMarkerInfo(ctx, kvExpr.Value, optMeta, fileAst, markerPkgImportPath, loader, currentPkgPath); err != nil && interpretErr == nil {
+							interpretErr = err
+						}
+					} else if nestedLit, ok := kvExpr.Value.(*ast.CompositeLit); ok {
+						// The options of a nested struct, e.g. DB: DBOptions{Host: goat.Default("localhost")}
+						interpretCompositeLit(nestedLit, fieldName+".")
+					}
+				}
+			}
+		}
+	}
 	ast.Inspect(initializerFunc.Body, func(n ast.Node) bool {
 		switch stmtNode := n.(type) {
 		case *ast.AssignStmt: // e.g. options.Field = goat.Default(...) or var x = goat.Default(...)
@@ -67,11 +91,26 @@ func InterpretInitializer(
 			// E.g., `opt.MyField = goat.Default("value")`
 			if len(stmtNode.Lhs) == 1 && len(stmtNode.Rhs) == 1 {
 				if selExpr, ok := stmtNode.Lhs[0].(*ast.SelectorExpr); ok {
-					// Assuming selExpr.X is the options struct variable, selExpr.Sel is the field name
-					fieldName := selExpr.Sel.Name
+					// Assuming the root of selExpr is the options struct variable, the rest is the field name (e.g. "DB.Host")
+					fieldName := fieldPathOf(selExpr)
 					if optMeta, exists := optionsMap[fieldName]; exists {
 						slog.InfoContext(ctx, fmt.Sprintf("Found assignment to options field: %s", fieldName))
-						extractMarkerInfo(ctx, stmtNode.Rhs[0], optMeta, fileAst, markerPkgImportPath, loader, currentPkgPath)
+						markSetByInitializer(optMeta, stmtNode.Rhs[0], loader)
+						if err := extractMarkerInfo(ctx, stmtNode.Rhs[0], optMeta, fileAst, markerPkgImportPath, loader, currentPkgPath); err != nil && interpretErr == nil {
+							interpretErr = err
+						}
+					}
+				} else if _, ok := stmtNode.Lhs[0].(*ast.Ident); ok {
+					// E.g. `opts := &Options{ Field: goat.Default(...) }`, followed by statement-level markers such as goat.OneOf(&opts.A, &opts.B)
+					actualExpr := stmtNode.Rhs[0]
+					if unaryExpr, ok := actualExpr.(*ast.UnaryExpr); ok && unaryExpr.Op == token.AND {
+						actualExpr = unaryExpr.X
+					}
+					if compLit, ok := actualExpr.(*ast.CompositeLit); ok {
+						if typeIdent, ok := compLit.Type.(*ast.Ident); ok && typeIdent.Name == optionsStructName {
+							slog.InfoContext(ctx, fmt.Sprintf("Found assigned composite literal in %s", initializerFuncName))
+							interpretCompositeLit(compLit, "")
+						}
 					}
 				}
 			}
@@ -88,26 +127,138 @@ func InterpretInitializer(
 					// This requires resolving compLit.Type to optionsStructName, which can be complex.
 					// For a simpler start, assume if it's a struct literal in NewOptions, it's the one.
 					slog.InfoContext(ctx, fmt.Sprintf("Found return composite literal in %s", initializerFuncName))
-					for _, elt := range compLit.Elts {
-						if kvExpr, ok := elt.(*ast.KeyValueExpr); ok {
-							if keyIdent, ok := kvExpr.Key.(*ast.Ident); ok {
-								fieldName := keyIdent.Name
-								if optMeta, exists := optionsMap[fieldName]; exists {
-									extractMarkerInfo(ctx, kvExpr.Value, optMeta, fileAst, markerPkgImportPath, loader, currentPkgPath)
-								}
-							}
-						}
-					}
+					interpretCompositeLit(compLit, "")
 				}
 			}
 		}
 		return true
 	})
 
-	return nil
+	return interpretErr
+}
+
+// markSetByInitializer records that the initializer sets the field of the option to expr: the position of expr,
+// and that the default value of the `default` tag, if any, is not assigned by the generated main().
+func markSetByInitializer(optMeta *metadata.OptionMetadata, expr ast.Expr, loader *loader.Loader) {
+	optMeta.DefaultFromTag = false
+	if loader == nil || !expr.Pos().IsValid() {
+		return
+	}
+	pos := loader.Fset().Position(expr.Pos())
+	optMeta.InitializerPosition = &pos
+}
+
+// InterpretOptionGroups collects the option groups declared by statement-level markers
+// in the options initializer function, e.g. `goat.OneOf(&opts.JSON, &opts.YAML)` or `goat.Together(&opts.TLSCert, &opts.TLSKey)`.
+// It returns an error (with the source position) if a marker refers to an unknown or positional option,
+// or has fewer than two options.
+func InterpretOptionGroups(
+	ctx context.Context,
+	fileAst *ast.File,
+	initializerFuncName string,
+	options []*metadata.OptionMetadata,
+	markerPkgImportPath string, // e.g., "github.com/podhmo/goat"
+	loader *loader.Loader, // Loader, for the positions of the errors
+) ([]*metadata.OptionGroup, error) {
+	var initializerFunc *ast.FuncDecl
+	for _, decl := range fileAst.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Name.Name == initializerFuncName && fn.Body != nil {
+			initializerFunc = fn
+			break
+		}
+	}
+	if initializerFunc == nil {
+		return nil, fmt.Errorf("initializer function '%s' not found", initializerFuncName)
+	}
+
+	optionsMap := make(map[string]*metadata.OptionMetadata)
+	for _, opt := range options {
+		optionsMap[opt.Name] = opt
+	}
+
+	var groups []*metadata.OptionGroup
+	for _, stmt := range initializerFunc.Body.List {
+		exprStmt, ok := stmt.(*ast.ExprStmt)
+		if !ok {
+			continue
+		}
+		callExpr, ok := exprStmt.X.(*ast.CallExpr)
+		if !ok {
+			continue
+		}
+		markerFuncName, markerPkgAlias := astutils.GetFullFunctionName(callExpr.Fun)
+		actualMarkerPkgPath := astutils.GetImportPath(fileAst, markerPkgAlias)
+		if actualMarkerPkgPath != markerPkgImportPath && actualMarkerPkgPath != "testcmdmodule/internal/goat" {
+			continue
+		}
+
+		group := &metadata.OptionGroup{}
+		switch markerFuncName {
+		case "OneOf":
+			group.Kind = metadata.OptionGroupOneOf
+		case "Together":
+			group.Kind = metadata.OptionGroupTogether
+		default:
+			continue
+		}
+		pos := loader.Fset().Position(callExpr.Pos())
+		for _, arg := range callExpr.Args {
+			// The fields are given as &opts.Field (or opts.Field).
+			fieldExpr := arg
+			if unaryExpr, ok := fieldExpr.(*ast.UnaryExpr); ok && unaryExpr.Op == token.AND {
+				fieldExpr = unaryExpr.X
+			}
+			selExpr, ok := fieldExpr.(*ast.SelectorExpr)
+			if !ok {
+				return nil, fmt.Errorf("%s: goat.%s expects fields of the options struct (e.g. &opts.Field), but got %s", loader.Fset().Position(arg.Pos()), markerFuncName, astutils.ExprToTypeName(arg))
+			}
+			optMeta, exists := optionsMap[fieldPathOf(selExpr)]
+			if !exists {
+				return nil, fmt.Errorf("%s: goat.%s refers to unknown option %s", loader.Fset().Position(arg.Pos()), markerFuncName, fieldPathOf(selExpr))
+			}
+			if optMeta.Positional != nil {
+				return nil, fmt.Errorf("%s: goat.%s cannot refer to %s, which receives positional arguments", loader.Fset().Position(arg.Pos()), markerFuncName, optMeta.Name)
+			}
+			if slices.Contains(group.Options, optMeta.Name) {
+				return nil, fmt.Errorf("%s: goat.%s refers to %s more than once", loader.Fset().Position(arg.Pos()), markerFuncName, optMeta.Name)
+			}
+			group.Options = append(group.Options, optMeta.Name)
+		}
+		if len(group.Options) < 2 {
+			return nil, fmt.Errorf("%s: goat.%s requires at least two options", pos, markerFuncName)
+		}
+		slog.InfoContext(ctx, fmt.Sprintf("Found option group goat.%s(%s)", markerFuncName, strings.Join(group.Options, ", ")))
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+// fieldPathOf returns the path of the field of the options struct referred to by selExpr,
+// without the options struct variable (e.g. "Name" for opts.Name, "DB.Host" for opts.DB.Host).
+func fieldPathOf(selExpr *ast.SelectorExpr) string {
+	if inner, ok := selExpr.X.(*ast.SelectorExpr); ok {
+		return fieldPathOf(inner) + "." + selExpr.Sel.Name
+	}
+	return selExpr.Sel.Name
+}
+
+// importAliasOf returns the name under which the package importPath is imported in fileAst
+// (the last element of the path if it has no explicit name).
+func importAliasOf(fileAst *ast.File, importPath string) string {
+	for _, imp := range fileAst.Imports {
+		if strings.Trim(imp.Path.Value, `"`) != importPath {
+			continue
+		}
+		if imp.Name != nil {
+			return imp.Name.Name
+		}
+		break
+	}
+	return importPath[strings.LastIndex(importPath, "/")+1:]
 }
 
 // extractMarkerInfo extracts default value and enum choices from a marker function call.
+// It returns an error (with the source position) only for invalid marker arguments, such as a malformed goat.Pattern.
 func extractMarkerInfo(
 	ctx context.Context,
 	valueExpr ast.Expr,
@@ -116,7 +267,7 @@ func extractMarkerInfo(
 	markerPkgImportPath string,
 	loader *loader.Loader,
 	currentPkgPath string,
-) {
+) error {
 	// Unwrap TypeAssertExpr if present, e.g., goat.Default(...).(*string)
 	if typeAssert, ok := valueExpr.(*ast.TypeAssertExpr); ok {
 		valueExpr = typeAssert.X // Use the expression inside the type assertion
@@ -141,7 +292,7 @@ func extractMarkerInfo(
 			// For now, let's assume goat.X markers are the primary source of metadata.
 			slog.InfoContext(ctx, fmt.Sprintf("  Field %s is assigned a literal value '%v' directly. This might be a default, but typically use goat.Default() for clarity.", optMeta.Name, evalRes.Value))
 		}
-		return
+		return nil
 	}
 
 	markerFuncName, markerPkgAlias := astutils.GetFullFunctionName(callExpr.Fun)
@@ -153,12 +304,19 @@ func extractMarkerInfo(
 
 	if !isKnownMarkerPackage {
 		slog.InfoContext(ctx, fmt.Sprintf("  Call is to package '%s' (alias '%s'), not the recognized marker package(s) ('%s' or 'testcmdmodule/internal/goat')", actualMarkerPkgPath, markerPkgAlias, markerPkgImportPath))
-		return
+		return nil
 	}
 
 	switch markerFuncName {
-	case "Default":
-		slog.InfoContext(ctx, fmt.Sprintf("Interpreting goat.Default for field %s (current Pkg: %s)", optMeta.Name, currentPkgPath))
+	case "Default", "With":
+		slog.InfoContext(ctx, fmt.Sprintf("Interpreting goat.%s for field %s (current Pkg: %s)", markerFuncName, optMeta.Name, currentPkgPath))
+		if markerFuncName == "With" && len(callExpr.Args) > 0 && isValueMarkerCall(callExpr.Args[0], fileAst, markerPkgImportPath) {
+			// The value is given by another marker, e.g. goat.With(goat.Default("info", goat.Enum(levels)), goat.Short("l"))
+			if err := extractMarkerInfo(ctx, callExpr.Args[0], optMeta, fileAst, markerPkgImportPath, loader, currentPkgPath); err != nil {
+				return err
+			}
+			return applyOptionMarkers(ctx, callExpr, optMeta, fileAst, markerPkgImportPath, loader)
+		}
 		if len(callExpr.Args) > 0 {
 			// Default value is the first argument
 			defaultArgExpr := callExpr.Args[0]
@@ -167,7 +325,38 @@ func extractMarkerInfo(
 			// Check if the option field itself is a pointer type
 			isPointerField := optMeta.IsPointer
 
-			if isPointerField {
+			if strings.TrimPrefix(optMeta.TypeName, "*") == "time.Duration" {
+				// Durations are constant expressions such as 5*time.Second, kept in their string form (e.g. "5s").
+				durationExpr := defaultArgExpr
+				if innerCall, ok := durationExpr.(*ast.CallExpr); ok && isPointerField && len(innerCall.Args) == 1 {
+					durationExpr = innerCall.Args[0] // e.g. durationPtr(5*time.Second)
+				}
+				if d, ok := astutils.EvaluateDurationArg(ctx, durationExpr, importAliasOf(fileAst, "time")); ok {
+					optMeta.DefaultValue = d.String()
+					slog.InfoContext(ctx, fmt.Sprintf("  Default value (duration): %v for field %s", optMeta.DefaultValue, optMeta.Name))
+				} else {
+					slog.WarnContext(ctx, fmt.Sprintf("  Could not evaluate the default duration of field %s. DefaultValue will be nil.", optMeta.Name))
+					optMeta.DefaultValue = nil
+				}
+			} else if strings.HasPrefix(optMeta.TypeName, "[]") {
+				// Slices are kept as the []any of their elements, for a literal such as []string{"a", "b"}.
+				if sliceEvalResult := astutils.EvaluateSliceArg(ctx, defaultArgExpr); sliceEvalResult.Value != nil {
+					optMeta.DefaultValue = sliceEvalResult.Value
+					slog.InfoContext(ctx, fmt.Sprintf("  Default value (slice): %v for field %s", optMeta.DefaultValue, optMeta.Name))
+				} else {
+					slog.WarnContext(ctx, fmt.Sprintf("  Could not evaluate the default slice of field %s. DefaultValue will be nil.", optMeta.Name))
+					optMeta.DefaultValue = nil
+				}
+			} else if strings.HasPrefix(optMeta.UnderlyingKind, "map[") {
+				// Maps are kept as the map[string]any of their entries, for a literal such as map[string]string{"env": "dev"}.
+				if mapEvalResult := astutils.EvaluateMapArg(ctx, defaultArgExpr); mapEvalResult.Value != nil {
+					optMeta.DefaultValue = mapEvalResult.Value
+					slog.InfoContext(ctx, fmt.Sprintf("  Default value (map): %v for field %s", optMeta.DefaultValue, optMeta.Name))
+				} else {
+					slog.WarnContext(ctx, fmt.Sprintf("  Could not evaluate the default map of field %s. DefaultValue will be nil.", optMeta.Name))
+					optMeta.DefaultValue = nil
+				}
+			} else if isPointerField {
 				slog.InfoContext(ctx, fmt.Sprintf("  Field %s is a pointer type (TypeName: %s). Attempting to extract underlying default value.", optMeta.Name, optMeta.TypeName))
 				// If the field is a pointer, we want the underlying value.
 				// Case 1: Argument is a call to a helper function, e.g., stringPtr("value")
@@ -250,9 +439,12 @@ func extractMarkerInfo(
 				}
 			}
 
-			// Subsequent args could be an Enum call for enumConstraint
-			if len(callExpr.Args) > 1 {
-				enumArg := callExpr.Args[1]
+			if markerFuncName == "With" {
+				return applyOptionMarkers(ctx, callExpr, optMeta, fileAst, markerPkgImportPath, loader)
+			}
+
+			// Subsequent args are for enumConstraint (an Enum call or a slice)
+			for _, enumArg := range callExpr.Args[1:] {
 				if enumInnerCallExpr, ok := enumArg.(*ast.CallExpr); ok { // goat.Default("val", goat.Enum(MyEnumVar))
 					innerFuncName, innerPkgAlias := astutils.GetFullFunctionName(enumInnerCallExpr.Fun)
 					resolvedInnerPkgPath := astutils.GetImportPath(fileAst, innerPkgAlias)
@@ -266,7 +458,7 @@ func extractMarkerInfo(
 							extractEnumValuesFromEvalResult(ctx, evalResult, optMeta, fileAst, loader, currentPkgPath, "Default (via goat.Enum)")
 						}
 					} else {
-						slog.DebugContext(ctx, fmt.Sprintf("Second argument to goat.Default for field %s is a call to %s.%s, not goat.Enum. Ignoring for enum constraints.", optMeta.Name, innerPkgAlias, innerFuncName))
+						return fmt.Errorf("%s: goat.Default for field %s does not accept %s, which is not the allowed values of the field (e.g. goat.Enum); option markers such as goat.Short are passed to goat.With", loader.Fset().Position(enumArg.Pos()), optMeta.Name, types.ExprString(enumArg))
 					}
 				} else { // goat.Default("val", MyEnumVarOrSliceLiteral)
 					// Corrected: Pass ctx to EvaluateSliceArg
@@ -276,7 +468,7 @@ func extractMarkerInfo(
 							optMeta.EnumValues = s
 							slog.InfoContext(ctx, fmt.Sprintf("  Enum values for Default from direct evaluation: %v", optMeta.EnumValues))
 						} else {
-							slog.InfoContext(ctx, fmt.Sprintf("  Enum values for Default for field %s from direct evaluation was not []any, but %T", optMeta.Name, enumEvalResult.Value))
+							return fmt.Errorf("%s: goat.Default for field %s does not accept %s, which is not the allowed values of the field (e.g. goat.Enum); option markers such as goat.Short are passed to goat.With", loader.Fset().Position(enumArg.Pos()), optMeta.Name, types.ExprString(enumArg))
 						}
 					} else if enumEvalResult.IdentifierName != "" {
 						slog.InfoContext(ctx, fmt.Sprintf("  Enum constraint for Default for field %s is an identifier '%s' (pkg '%s'). Attempting resolution.", optMeta.Name, enumEvalResult.IdentifierName, enumEvalResult.PkgName))
@@ -286,7 +478,7 @@ func extractMarkerInfo(
 						extractEnumValuesFromEvalResult(ctx, enumEvalResult, optMeta, fileAst, loader, currentPkgPath, "Default (direct ident)")
 					} else {
 						// This case handles where enumEvalResult.Value is nil AND enumEvalResult.IdentifierName is empty.
-						slog.InfoContext(ctx, fmt.Sprintf("  Enum argument for Default for field %s (type %T) could not be evaluated to a literal slice or a resolvable identifier. EvalResult: %+v", optMeta.Name, enumArg, enumEvalResult))
+						return fmt.Errorf("%s: goat.Default for field %s does not accept %s, which is not the allowed values of the field (e.g. goat.Enum); option markers such as goat.Short are passed to goat.With", loader.Fset().Position(enumArg.Pos()), optMeta.Name, types.ExprString(enumArg))
 					}
 				}
 			}
@@ -301,7 +493,7 @@ func extractMarkerInfo(
 			valuesArg = callExpr.Args[1]
 		} else {
 			slog.DebugContext(ctx, fmt.Sprintf("Warning: goat.Enum for field %s called with unexpected number of arguments: %d. Expected 1 or 2.", optMeta.Name, len(callExpr.Args)))
-			return // or break, depending on desired error handling
+			return nil // or break, depending on desired error handling
 		}
 
 		if valuesArg != nil {
@@ -344,9 +536,13 @@ func extractMarkerInfo(
 		}
 	case "File":
 		slog.DebugContext(ctx, fmt.Sprintf("Interpreting goat.File for field %s", optMeta.Name))
+		optMeta.IsFile = true
 		if len(callExpr.Args) > 0 {
 			// Corrected: Pass ctx to EvaluateArg
 			fileArgEvalResult := astutils.EvaluateArg(ctx, callExpr.Args[0])
+			if strings.HasPrefix(optMeta.TypeName, "[]") { // e.g. goat.File([]string{"*.go"}, goat.GlobPattern())
+				fileArgEvalResult = astutils.EvaluateSliceArg(ctx, callExpr.Args[0])
+			}
 			if fileArgEvalResult.IdentifierName == "" {
 				optMeta.DefaultValue = fileArgEvalResult.Value
 				slog.InfoContext(ctx, fmt.Sprintf("  Default path: %v", optMeta.DefaultValue))
@@ -354,7 +550,9 @@ func extractMarkerInfo(
 				slog.InfoContext(ctx, fmt.Sprintf("  Default path for field %s is an identifier '%s' (pkg '%s'). Resolution of identifiers for file paths is not yet implemented here. DefaultValue will be nil.", optMeta.Name, fileArgEvalResult.IdentifierName, fileArgEvalResult.PkgName))
 				optMeta.DefaultValue = nil
 			}
-			optMeta.TypeName = "string" // File paths are strings
+			if optMeta.TypeName == "" {
+				optMeta.TypeName = "string" // File paths are strings, unless the field type is known (e.g. *string, []string)
+			}
 
 			// Subsequent args are FileOption calls (e.g., goat.MustExist(), goat.GlobPattern())
 			if len(callExpr.Args) > 1 {
@@ -363,7 +561,7 @@ func extractMarkerInfo(
 						optionFuncName, optionFuncPkgAlias := astutils.GetFullFunctionName(optionCallExpr.Fun)
 						actualOptionFuncPkgPath := astutils.GetImportPath(fileAst, optionFuncPkgAlias)
 
-						if actualOptionFuncPkgPath == markerPkgImportPath { // Ensure it's a goat.Xxx call
+						if actualOptionFuncPkgPath == markerPkgImportPath || actualOptionFuncPkgPath == "testcmdmodule/internal/goat" { // Ensure it's a goat.Xxx call
 							switch optionFuncName {
 							case "MustExist":
 								optMeta.FileMustExist = true
@@ -383,6 +581,170 @@ func extractMarkerInfo(
 		// Not a recognized marker function from the specified package
 		slog.DebugContext(ctx, fmt.Sprintf("Not a goat marker function: %s.%s", markerPkgAlias, markerFuncName))
 	}
+	return nil
+}
+
+// isValueMarkerCall reports whether expr is a call to a marker giving the value of a field (goat.Default or goat.File),
+// passed to goat.With.
+func isValueMarkerCall(expr ast.Expr, fileAst *ast.File, markerPkgImportPath string) bool {
+	callExpr, ok := expr.(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	funcName, pkgAlias := astutils.GetFullFunctionName(callExpr.Fun)
+	pkgPath := astutils.GetImportPath(fileAst, pkgAlias)
+	if pkgPath != markerPkgImportPath && pkgPath != "testcmdmodule/internal/goat" {
+		return false
+	}
+	return funcName == "Default" || funcName == "File"
+}
+
+// applyOptionMarkers handles the arguments of goat.With after the value, which must be option markers.
+func applyOptionMarkers(ctx context.Context, callExpr *ast.CallExpr, optMeta *metadata.OptionMetadata, fileAst *ast.File, markerPkgImportPath string, loader *loader.Loader) error {
+	for _, arg := range callExpr.Args[1:] {
+		if optionCallExpr, ok := arg.(*ast.CallExpr); ok {
+			handled, err := applyOptionMarker(ctx, optionCallExpr, optMeta, fileAst, markerPkgImportPath, loader)
+			if err != nil {
+				return err
+			}
+			if handled {
+				continue
+			}
+		}
+		return fmt.Errorf("%s: goat.With for field %s does not accept %s, which is not an option marker (e.g. goat.Short)", loader.Fset().Position(arg.Pos()), optMeta.Name, types.ExprString(arg))
+	}
+	return nil
+}
+
+// applyOptionMarker handles the option markers passed to goat.With, such as goat.Short("v"), goat.Alias("out")
+// the value constraints (goat.Min, goat.Max, goat.Range, goat.MinLen, goat.MaxLen and goat.Pattern), goat.Dir and goat.Secret.
+// It reports whether callExpr is a call to one of them.
+// An invalid regular expression passed to goat.Pattern is reported as an error with its source position.
+func applyOptionMarker(ctx context.Context, callExpr *ast.CallExpr, optMeta *metadata.OptionMetadata, fileAst *ast.File, markerPkgImportPath string, loader *loader.Loader) (bool, error) {
+	funcName, pkgAlias := astutils.GetFullFunctionName(callExpr.Fun)
+	pkgPath := astutils.GetImportPath(fileAst, pkgAlias)
+	if pkgPath != markerPkgImportPath && pkgPath != "testcmdmodule/internal/goat" {
+		return false, nil
+	}
+
+	switch funcName {
+	case "Short":
+		if len(callExpr.Args) != 1 {
+			slog.WarnContext(ctx, fmt.Sprintf("goat.Short for field %s expects exactly one argument, got %d", optMeta.Name, len(callExpr.Args)))
+			return true, nil
+		}
+		if name, ok := astutils.EvaluateArg(ctx, callExpr.Args[0]).Value.(string); ok {
+			optMeta.ShortName = name
+			slog.InfoContext(ctx, fmt.Sprintf("  Short name: %s for field %s", name, optMeta.Name))
+		} else {
+			slog.WarnContext(ctx, fmt.Sprintf("goat.Short for field %s expects a string literal", optMeta.Name))
+		}
+	case "Alias":
+		for _, arg := range callExpr.Args {
+			if name, ok := astutils.EvaluateArg(ctx, arg).Value.(string); ok {
+				optMeta.Aliases = append(optMeta.Aliases, name)
+				slog.InfoContext(ctx, fmt.Sprintf("  Alias: %s for field %s", name, optMeta.Name))
+			} else {
+				slog.WarnContext(ctx, fmt.Sprintf("goat.Alias for field %s expects string literals", optMeta.Name))
+			}
+		}
+	case "Min", "Max", "Range":
+		wantArgs := 1
+		if funcName == "Range" {
+			wantArgs = 2
+		}
+		if len(callExpr.Args) != wantArgs {
+			slog.WarnContext(ctx, fmt.Sprintf("goat.%s for field %s expects %d argument(s), got %d", funcName, optMeta.Name, wantArgs, len(callExpr.Args)))
+			return true, nil
+		}
+		var values []any
+		for _, arg := range callExpr.Args {
+			switch v := astutils.EvaluateArg(ctx, arg).Value.(type) {
+			case int64, float64:
+				values = append(values, v)
+			default:
+				slog.WarnContext(ctx, fmt.Sprintf("goat.%s for field %s expects numeric literals, got %s", funcName, optMeta.Name, astutils.ExprToTypeName(arg)))
+				return true, nil
+			}
+		}
+		switch funcName {
+		case "Min":
+			optMeta.MinValue = values[0]
+		case "Max":
+			optMeta.MaxValue = values[0]
+		default:
+			optMeta.MinValue, optMeta.MaxValue = values[0], values[1]
+		}
+		slog.InfoContext(ctx, fmt.Sprintf("  Range: %s for field %s", optMeta.ValueRange(), optMeta.Name))
+	case "MinLen", "MaxLen":
+		if len(callExpr.Args) != 1 {
+			slog.WarnContext(ctx, fmt.Sprintf("goat.%s for field %s expects exactly one argument, got %d", funcName, optMeta.Name, len(callExpr.Args)))
+			return true, nil
+		}
+		n, ok := astutils.EvaluateArg(ctx, callExpr.Args[0]).Value.(int64)
+		if !ok {
+			slog.WarnContext(ctx, fmt.Sprintf("goat.%s for field %s expects an integer literal", funcName, optMeta.Name))
+			return true, nil
+		}
+		length := int(n)
+		if funcName == "MinLen" {
+			optMeta.MinLen = &length
+		} else {
+			optMeta.MaxLen = &length
+		}
+		slog.InfoContext(ctx, fmt.Sprintf("  Length: %s for field %s", optMeta.LengthRange(), optMeta.Name))
+	case "Dir":
+		optMeta.IsDir = true
+		for _, arg := range callExpr.Args {
+			optionCallExpr, ok := arg.(*ast.CallExpr)
+			if !ok {
+				continue
+			}
+			optionFuncName, optionFuncPkgAlias := astutils.GetFullFunctionName(optionCallExpr.Fun)
+			optionPkgPath := astutils.GetImportPath(fileAst, optionFuncPkgAlias)
+			if optionPkgPath != markerPkgImportPath && optionPkgPath != "testcmdmodule/internal/goat" {
+				continue
+			}
+			switch optionFuncName {
+			case "MustExist":
+				optMeta.DirMustExist = true
+			case "CreateIfMissing":
+				optMeta.DirCreateIfMissing = true
+				optMeta.DirPerm = 0o755 // Used if the permission cannot be evaluated
+				if len(optionCallExpr.Args) == 1 {
+					if perm, ok := astutils.EvaluateArg(ctx, optionCallExpr.Args[0]).Value.(int64); ok {
+						optMeta.DirPerm = uint32(perm)
+					} else {
+						slog.WarnContext(ctx, fmt.Sprintf("goat.CreateIfMissing for field %s expects an integer literal (e.g. 0o755)", optMeta.Name))
+					}
+				}
+			default:
+				slog.InfoContext(ctx, fmt.Sprintf("  Unknown directory option: %s", optionFuncName))
+			}
+		}
+		slog.InfoContext(ctx, fmt.Sprintf("  Directory: %q policy for field %s", optMeta.DirPolicy(), optMeta.Name))
+	case "Pattern":
+		if len(callExpr.Args) != 1 {
+			slog.WarnContext(ctx, fmt.Sprintf("goat.Pattern for field %s expects exactly one argument, got %d", optMeta.Name, len(callExpr.Args)))
+			return true, nil
+		}
+		pattern, ok := astutils.EvaluateArg(ctx, callExpr.Args[0]).Value.(string)
+		if !ok {
+			slog.WarnContext(ctx, fmt.Sprintf("goat.Pattern for field %s expects a string literal", optMeta.Name))
+			return true, nil
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			return true, fmt.Errorf("%s: invalid pattern for field %s: %w", loader.Fset().Position(callExpr.Args[0].Pos()), optMeta.Name, err)
+		}
+		optMeta.Pattern = pattern
+		slog.InfoContext(ctx, fmt.Sprintf("  Pattern: %s for field %s", pattern, optMeta.Name))
+	case "Secret":
+		optMeta.IsSecret = true
+		slog.InfoContext(ctx, fmt.Sprintf("  Secret: field %s", optMeta.Name))
+	default:
+		return false, nil
+	}
+	return true, nil
 }
 
 // extractEnumValuesFromEvalResult is a helper to resolve enum values from EvalResult.
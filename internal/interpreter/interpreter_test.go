@@ -606,6 +606,622 @@ func InitOptions() *Options {
 	}
 }
 
+func TestInterpretInitializer_FlagNameMarkers(t *testing.T) {
+	content := `
+package main
+import "github.com/podhmo/goat"
+
+type Options struct {
+	Verbose   bool
+	OutputDir string
+	Level     string
+}
+
+func InitOptions() *Options {
+	return &Options{
+		Verbose:   goat.With(false, goat.Short("v")),
+		OutputDir: goat.With(".", goat.Alias("out", "o-dir")),
+		Level:     goat.With(goat.Default("info", goat.Enum([]string{"debug", "info"})), goat.Short("l")),
+	}
+}
+`
+	fileAst := parseTestFileForInterpreter(t, content)
+	optionsMeta := []*metadata.OptionMetadata{
+		{Name: "Verbose", CliName: "verbose", TypeName: "bool"},
+		{Name: "OutputDir", CliName: "output-dir", TypeName: "string"},
+		{Name: "Level", CliName: "level", TypeName: "string"},
+	}
+
+	ctx := context.Background()
+	dummyLoader := loader.New(loader.Config{})
+	err := InterpretInitializer(ctx, fileAst, "Options", "InitOptions", optionsMeta, goatPkgImportPath, "github.com/podhmo/goat/internal/interpreter/testpkgs/flagnames", dummyLoader)
+	if err != nil {
+		t.Fatalf("InterpretInitializer failed: %v", err)
+	}
+
+	if got := optionsMeta[0].ShortName; got != "v" {
+		t.Errorf("Verbose: expected short name %q, got %q", "v", got)
+	}
+	if got, want := optionsMeta[1].Aliases, []string{"out", "o-dir"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("OutputDir: expected aliases %v, got %v", want, got)
+	}
+	if optionsMeta[1].DefaultValue != "." {
+		t.Errorf("OutputDir: expected default %q, got %v", ".", optionsMeta[1].DefaultValue)
+	}
+	levelOpt := optionsMeta[2]
+	if levelOpt.ShortName != "l" || levelOpt.DefaultValue != "info" {
+		t.Errorf("Level: expected short name %q and default %q, got %q and %v", "l", "info", levelOpt.ShortName, levelOpt.DefaultValue)
+	}
+	if want := []any{"debug", "info"}; !reflect.DeepEqual(levelOpt.EnumValues, want) {
+		t.Errorf("Level: expected enum %v, got %v", want, levelOpt.EnumValues)
+	}
+}
+
+func TestInterpretInitializer_ConstraintMarkers(t *testing.T) {
+	content := `
+package main
+import "github.com/podhmo/goat"
+
+type Options struct {
+	Port    int
+	Ratio   float64
+	Retries int
+	Name    string
+}
+
+func InitOptions() *Options {
+	return &Options{
+		Port:    goat.With(8080, goat.Range(1, 65535)),
+		Ratio:   goat.With(0.5, goat.Min(-1.5), goat.Max(1.0)),
+		Retries: goat.With(3, goat.Min(0)),
+		Name:    goat.With("guest", goat.MinLen(3), goat.MaxLen(20)),
+	}
+}
+`
+	fileAst := parseTestFileForInterpreter(t, content)
+	optionsMeta := []*metadata.OptionMetadata{
+		{Name: "Port", CliName: "port", TypeName: "int"},
+		{Name: "Ratio", CliName: "ratio", TypeName: "float64"},
+		{Name: "Retries", CliName: "retries", TypeName: "int"},
+		{Name: "Name", CliName: "name", TypeName: "string"},
+	}
+
+	ctx := context.Background()
+	dummyLoader := loader.New(loader.Config{})
+	err := InterpretInitializer(ctx, fileAst, "Options", "InitOptions", optionsMeta, goatPkgImportPath, "github.com/podhmo/goat/internal/interpreter/testpkgs/constraints", dummyLoader)
+	if err != nil {
+		t.Fatalf("InterpretInitializer failed: %v", err)
+	}
+
+	expectedRanges := map[string]string{"Port": "1..65535", "Ratio": "-1.5..1", "Retries": "0..", "Name": ""}
+	for _, opt := range optionsMeta {
+		if got := opt.ValueRange(); got != expectedRanges[opt.Name] {
+			t.Errorf("%s: expected range %q, got %q", opt.Name, expectedRanges[opt.Name], got)
+		}
+	}
+	if got := optionsMeta[3].LengthRange(); got != "3..20" {
+		t.Errorf("Name: expected length %q, got %q", "3..20", got)
+	}
+	if optionsMeta[0].DefaultValue != int64(8080) {
+		t.Errorf("Port: expected default 8080, got %v", optionsMeta[0].DefaultValue)
+	}
+}
+
+func TestInterpretInitializer_PatternMarker(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		content := `
+package main
+import "github.com/podhmo/goat"
+
+type Options struct {
+	Name string
+}
+
+func InitOptions() *Options {
+	return &Options{
+		Name: goat.With("app", goat.Pattern(` + "`^[a-z0-9-]+$`" + `)),
+	}
+}
+`
+		fileAst := parseTestFileForInterpreter(t, content)
+		optionsMeta := []*metadata.OptionMetadata{{Name: "Name", CliName: "name", TypeName: "string"}}
+
+		err := InterpretInitializer(context.Background(), fileAst, "Options", "InitOptions", optionsMeta, goatPkgImportPath, "github.com/podhmo/goat/internal/interpreter/testpkgs/pattern", loader.New(loader.Config{}))
+		if err != nil {
+			t.Fatalf("InterpretInitializer failed: %v", err)
+		}
+		if got, want := optionsMeta[0].Pattern, "^[a-z0-9-]+$"; got != want {
+			t.Errorf("Name: expected pattern %q, got %q", want, got)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		content := `package main
+import "github.com/podhmo/goat"
+
+type Options struct {
+	Name string
+}
+
+func InitOptions() *Options {
+	return &Options{
+		Name: goat.With("app", goat.Pattern("[a-z")),
+	}
+}
+`
+		fset := token.NewFileSet()
+		fileAst, err := parser.ParseFile(fset, "main.go", content, parser.ParseComments)
+		if err != nil {
+			t.Fatalf("Failed to parse test file content: %v", err)
+		}
+		optionsMeta := []*metadata.OptionMetadata{{Name: "Name", CliName: "name", TypeName: "string"}}
+
+		err = InterpretInitializer(context.Background(), fileAst, "Options", "InitOptions", optionsMeta, goatPkgImportPath, "github.com/podhmo/goat/internal/interpreter/testpkgs/pattern", loader.New(loader.Config{Fset: fset}))
+		if err == nil {
+			t.Fatal("InterpretInitializer should fail for an invalid pattern")
+		}
+		if want := "main.go:10:39: invalid pattern for field Name"; !strings.HasPrefix(err.Error(), want) {
+			t.Errorf("expected error starting with %q, got %q", want, err.Error())
+		}
+	})
+}
+
+func TestInterpretInitializer_UnknownDefaultArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		want string
+	}{
+		{name: "literal", expr: `goat.Default(8080, "x")`, want: `main.go:10:28: goat.Default for field Port does not accept "x"`},
+		{name: "other call", expr: `goat.Default(8080, strconv.Itoa(1))`, want: "main.go:10:28: goat.Default for field Port does not accept strconv.Itoa(1)"},
+		{name: "unknown marker", expr: `goat.Default(8080, goat.Unknown())`, want: "main.go:10:28: goat.Default for field Port does not accept goat.Unknown()"},
+		{name: "option marker", expr: `goat.Default(8080, goat.Short("p"))`, want: `main.go:10:28: goat.Default for field Port does not accept goat.Short("p")`},
+		{name: "enum passed to with", expr: `goat.With(8080, goat.Enum(nil))`, want: "main.go:10:25: goat.With for field Port does not accept goat.Enum(nil)"},
+		{name: "literal passed to with", expr: `goat.With(8080, "x")`, want: `main.go:10:25: goat.With for field Port does not accept "x"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := `package main
+import "github.com/podhmo/goat"
+
+type Options struct {
+	Port int
+}
+
+func InitOptions() *Options {
+	return &Options{
+		Port: ` + tt.expr + `,
+	}
+}
+`
+			fset := token.NewFileSet()
+			fileAst, err := parser.ParseFile(fset, "main.go", content, parser.ParseComments)
+			if err != nil {
+				t.Fatalf("Failed to parse test file content: %v", err)
+			}
+			optionsMeta := []*metadata.OptionMetadata{{Name: "Port", CliName: "port", TypeName: "int"}}
+
+			err = InterpretInitializer(context.Background(), fileAst, "Options", "InitOptions", optionsMeta, goatPkgImportPath, "github.com/podhmo/goat/internal/interpreter/testpkgs/unknown", loader.New(loader.Config{Fset: fset}))
+			if err == nil {
+				t.Fatal("InterpretInitializer should fail for an argument that is not an option marker")
+			}
+			if !strings.HasPrefix(err.Error(), tt.want) {
+				t.Errorf("expected error starting with %q, got %q", tt.want, err.Error())
+			}
+		})
+	}
+}
+
+func TestInterpretInitializer_SecretMarker(t *testing.T) {
+	content := `
+package main
+import "github.com/podhmo/goat"
+
+type Options struct {
+	Password string
+	User     string
+}
+
+func InitOptions() *Options {
+	return &Options{
+		Password: goat.With("changeme", goat.Secret()),
+		User:     goat.Default("admin"),
+	}
+}
+`
+	fileAst := parseTestFileForInterpreter(t, content)
+	optionsMeta := []*metadata.OptionMetadata{
+		{Name: "Password", CliName: "password", TypeName: "string"},
+		{Name: "User", CliName: "user", TypeName: "string"},
+	}
+
+	err := InterpretInitializer(context.Background(), fileAst, "Options", "InitOptions", optionsMeta, goatPkgImportPath, "github.com/podhmo/goat/internal/interpreter/testpkgs/secret", loader.New(loader.Config{}))
+	if err != nil {
+		t.Fatalf("InterpretInitializer failed: %v", err)
+	}
+	if !optionsMeta[0].IsSecret || optionsMeta[0].DefaultValue != "changeme" {
+		t.Errorf("Password: expected a secret with default %q, got IsSecret=%v and %v", "changeme", optionsMeta[0].IsSecret, optionsMeta[0].DefaultValue)
+	}
+	if optionsMeta[1].IsSecret {
+		t.Errorf("User: expected not to be a secret")
+	}
+}
+
+func TestInterpretInitializer_DirMarker(t *testing.T) {
+	content := `
+package main
+import "github.com/podhmo/goat"
+
+type Options struct {
+	OutputDir string
+	InputDir  string
+	WorkDir   string
+}
+
+func InitOptions() *Options {
+	return &Options{
+		OutputDir: goat.With("output", goat.Dir(goat.CreateIfMissing(0o750))),
+		InputDir:  goat.With(".", goat.Dir(goat.MustExist())),
+		WorkDir:   goat.With("work", goat.Dir()),
+	}
+}
+`
+	fileAst := parseTestFileForInterpreter(t, content)
+	optionsMeta := []*metadata.OptionMetadata{
+		{Name: "OutputDir", CliName: "output-dir", TypeName: "string"},
+		{Name: "InputDir", CliName: "input-dir", TypeName: "string"},
+		{Name: "WorkDir", CliName: "work-dir", TypeName: "string"},
+	}
+
+	err := InterpretInitializer(context.Background(), fileAst, "Options", "InitOptions", optionsMeta, goatPkgImportPath, "github.com/podhmo/goat/internal/interpreter/testpkgs/dir", loader.New(loader.Config{}))
+	if err != nil {
+		t.Fatalf("InterpretInitializer failed: %v", err)
+	}
+
+	expected := []*metadata.OptionMetadata{
+		{Name: "OutputDir", CliName: "output-dir", TypeName: "string", DefaultValue: "output", IsDir: true, DirCreateIfMissing: true, DirPerm: 0o750},
+		{Name: "InputDir", CliName: "input-dir", TypeName: "string", DefaultValue: ".", IsDir: true, DirMustExist: true},
+		{Name: "WorkDir", CliName: "work-dir", TypeName: "string", DefaultValue: "work", IsDir: true},
+	}
+	for i, want := range expected {
+		want.InitializerPosition = optionsMeta[i].InitializerPosition // Checked by TestInterpretInitializer_InitializerPosition
+		if !reflect.DeepEqual(optionsMeta[i], want) {
+			t.Errorf("OptionMetadata mismatch for %s:\nExpected: %+v\nActual:   %+v", want.Name, want, optionsMeta[i])
+		}
+	}
+}
+
+func TestInterpretInitializer_InitializerPosition(t *testing.T) {
+	content := `
+package main
+import "github.com/podhmo/goat"
+
+type Options struct {
+	Name  string
+	Level string
+	Port  int
+}
+
+func InitOptions() *Options {
+	opts := &Options{
+		Name: goat.Default("guest"),
+	}
+	opts.Level = goat.Enum([]string{"debug", "info"})
+	return opts
+}
+`
+	fset := token.NewFileSet()
+	fileAst, err := parser.ParseFile(fset, "test.go", content, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("Failed to parse test file content: %v", err)
+	}
+	optionsMeta := []*metadata.OptionMetadata{
+		{Name: "Name", CliName: "name", TypeName: "string", DefaultValue: "tag", DefaultFromTag: true},
+		{Name: "Level", CliName: "level", TypeName: "string"},
+		{Name: "Port", CliName: "port", TypeName: "int", DefaultValue: 8080, DefaultFromTag: true},
+	}
+
+	err = InterpretInitializer(context.Background(), fileAst, "Options", "InitOptions", optionsMeta, goatPkgImportPath, "github.com/podhmo/goat/internal/interpreter/testpkgs/position", loader.New(loader.Config{Fset: fset}))
+	if err != nil {
+		t.Fatalf("InterpretInitializer failed: %v", err)
+	}
+
+	expected := map[string]string{
+		"Name":  "test.go:13:9",  // The value in the composite literal
+		"Level": "test.go:15:15", // The right-hand side of the assignment
+	}
+	for _, opt := range optionsMeta {
+		want, ok := expected[opt.Name]
+		if !ok {
+			if opt.InitializerPosition != nil {
+				t.Errorf("%s: expected no initializer position, got %s", opt.Name, opt.InitializerPosition)
+			}
+			continue
+		}
+		if opt.InitializerPosition == nil || opt.InitializerPosition.String() != want {
+			t.Errorf("%s: expected the initializer position %s, got %v", opt.Name, want, opt.InitializerPosition)
+		}
+	}
+
+	// The default of the tag is kept only for the field that the initializer does not set.
+	if optionsMeta[0].DefaultFromTag || !optionsMeta[2].DefaultFromTag {
+		t.Errorf("Expected DefaultFromTag to be cleared for Name and kept for Port, got %v and %v", optionsMeta[0].DefaultFromTag, optionsMeta[2].DefaultFromTag)
+	}
+}
+
+func TestInterpretOptionGroups(t *testing.T) {
+	content := `
+package main
+import "github.com/podhmo/goat"
+
+type Options struct {
+	JSON    bool
+	YAML    bool
+	TLSCert string
+	TLSKey  string
+	Src     string
+}
+
+func NewOptions() *Options {
+	opts := &Options{
+		TLSCert: goat.Default("cert.pem"),
+	}
+	goat.OneOf(&opts.JSON, &opts.YAML)
+	goat.Together(opts.TLSCert, opts.TLSKey)
+	return opts
+}
+
+func NewOptionsUnknownField() *Options {
+	opts := &Options{}
+	goat.OneOf(&opts.JSON, &opts.XML)
+	return opts
+}
+
+func NewOptionsSingleField() *Options {
+	opts := &Options{}
+	goat.Together(&opts.TLSCert)
+	return opts
+}
+
+func NewOptionsPositional() *Options {
+	opts := &Options{}
+	goat.OneOf(&opts.JSON, &opts.Src)
+	return opts
+}
+`
+	newOptionsMeta := func() []*metadata.OptionMetadata {
+		return []*metadata.OptionMetadata{
+			{Name: "JSON", CliName: "json", TypeName: "bool"},
+			{Name: "YAML", CliName: "yaml", TypeName: "bool"},
+			{Name: "TLSCert", CliName: "tls-cert", TypeName: "string"},
+			{Name: "TLSKey", CliName: "tls-key", TypeName: "string"},
+			{Name: "Src", CliName: "src", TypeName: "string", Positional: &metadata.PositionalArg{Index: 0}},
+		}
+	}
+	fileAst := parseTestFileForInterpreter(t, content)
+
+	t.Run("groups", func(t *testing.T) {
+		optionsMeta := newOptionsMeta()
+		ctx := context.Background()
+		if err := InterpretInitializer(ctx, fileAst, "Options", "NewOptions", optionsMeta, goatPkgImportPath, "github.com/podhmo/goat/internal/interpreter/testpkgs/groups", loader.New(loader.Config{})); err != nil {
+			t.Fatalf("InterpretInitializer failed: %v", err)
+		}
+		// The defaults of the assigned composite literal are interpreted, too.
+		if optionsMeta[2].DefaultValue != "cert.pem" {
+			t.Errorf("TLSCert: expected default 'cert.pem', got '%v'", optionsMeta[2].DefaultValue)
+		}
+
+		groups, err := InterpretOptionGroups(ctx, fileAst, "NewOptions", optionsMeta, goatPkgImportPath, loader.New(loader.Config{}))
+		if err != nil {
+			t.Fatalf("InterpretOptionGroups failed: %v", err)
+		}
+		expected := []*metadata.OptionGroup{
+			{Kind: metadata.OptionGroupOneOf, Options: []string{"JSON", "YAML"}},
+			{Kind: metadata.OptionGroupTogether, Options: []string{"TLSCert", "TLSKey"}},
+		}
+		if !reflect.DeepEqual(groups, expected) {
+			t.Errorf("groups mismatch:\nExpected: %+v\nActual:   %+v", expected, groups)
+		}
+	})
+
+	errorCases := []struct {
+		initializer string
+		wantErr     string
+	}{
+		{initializer: "NewOptionsUnknownField", wantErr: "goat.OneOf refers to unknown option XML"},
+		{initializer: "NewOptionsSingleField", wantErr: "goat.Together requires at least two options"},
+		{initializer: "NewOptionsPositional", wantErr: "goat.OneOf cannot refer to Src, which receives positional arguments"},
+	}
+	for _, tc := range errorCases {
+		t.Run(tc.initializer, func(t *testing.T) {
+			_, err := InterpretOptionGroups(context.Background(), fileAst, tc.initializer, newOptionsMeta(), goatPkgImportPath, loader.New(loader.Config{}))
+			if err == nil {
+				t.Fatalf("expected an error containing %q, but got nil", tc.wantErr)
+			}
+			if !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("expected an error containing %q, but got %q", tc.wantErr, err.Error())
+			}
+		})
+	}
+}
+
+func TestInterpretInitializer_NestedStructs(t *testing.T) {
+	content := `
+package main
+import "github.com/podhmo/goat"
+
+type TLSOptions struct {
+	Cert string
+}
+
+type DBOptions struct {
+	Host string
+	Port int
+	TLS  TLSOptions
+}
+
+type Options struct {
+	Host  string
+	DB    DBOptions
+	Cache DBOptions
+}
+
+func NewOptions() *Options {
+	opts := &Options{
+		DB: DBOptions{
+			Host: goat.Default("localhost"),
+			TLS:  TLSOptions{Cert: goat.Default("cert.pem")},
+		},
+	}
+	opts.Cache.Port = goat.Default(6379)
+	goat.OneOf(&opts.DB.Host, &opts.Cache.Host)
+	return opts
+}
+`
+	fileAst := parseTestFileForInterpreter(t, content)
+	optionsMeta := []*metadata.OptionMetadata{
+		{Name: "Host", CliName: "host", TypeName: "string"},
+		{Name: "DB.Host", CliName: "db-host", TypeName: "string"},
+		{Name: "DB.Port", CliName: "db-port", TypeName: "int"},
+		{Name: "DB.TLS.Cert", CliName: "db-tls-cert", TypeName: "string"},
+		{Name: "Cache.Host", CliName: "cache-host", TypeName: "string"},
+		{Name: "Cache.Port", CliName: "cache-port", TypeName: "int"},
+	}
+
+	ctx := context.Background()
+	err := InterpretInitializer(ctx, fileAst, "Options", "NewOptions", optionsMeta, goatPkgImportPath, "github.com/podhmo/goat/internal/interpreter/testpkgs/nested", loader.New(loader.Config{}))
+	if err != nil {
+		t.Fatalf("InterpretInitializer failed: %v", err)
+	}
+	expected := map[string]any{
+		"Host":        nil, // Not the Host of DB
+		"DB.Host":     "localhost",
+		"DB.Port":     nil,
+		"DB.TLS.Cert": "cert.pem",
+		"Cache.Host":  nil,
+		"Cache.Port":  int64(6379),
+	}
+	for _, opt := range optionsMeta {
+		if !reflect.DeepEqual(opt.DefaultValue, expected[opt.Name]) {
+			t.Errorf("%s: expected default %v (%T), got %v (%T)", opt.Name, expected[opt.Name], expected[opt.Name], opt.DefaultValue, opt.DefaultValue)
+		}
+	}
+
+	groups, err := InterpretOptionGroups(ctx, fileAst, "NewOptions", optionsMeta, goatPkgImportPath, loader.New(loader.Config{}))
+	if err != nil {
+		t.Fatalf("InterpretOptionGroups failed: %v", err)
+	}
+	if want := []*metadata.OptionGroup{{Kind: metadata.OptionGroupOneOf, Options: []string{"DB.Host", "Cache.Host"}}}; !reflect.DeepEqual(groups, want) {
+		t.Errorf("groups mismatch:\nExpected: %+v\nActual:   %+v", want, groups)
+	}
+}
+
+func TestInterpretInitializer_NumericDefaults(t *testing.T) {
+	content := `
+package main
+import (
+	"time"
+
+	"github.com/podhmo/goat"
+)
+
+type Options struct {
+	Timeout  time.Duration
+	Interval *time.Duration
+	Ratio    float64
+	Size     int64
+	Count    uint
+	Limit    int64
+}
+
+func durationPtr(d time.Duration) *time.Duration { return &d }
+
+func NewOptions() *Options {
+	return &Options{
+		Timeout:  goat.Default(5 * time.Second),
+		Interval: goat.Default(durationPtr(time.Minute + 30*time.Second)),
+		Ratio:    goat.Default(0.5),
+		Size:     goat.Default(int64(1024)),
+		Count:    goat.Default(uint(3)),
+		Limit:    goat.Default(int64(1) << 40),
+	}
+}
+`
+	fileAst := parseTestFileForInterpreter(t, content)
+	optionsMeta := []*metadata.OptionMetadata{
+		{Name: "Timeout", CliName: "timeout", TypeName: "time.Duration"},
+		{Name: "Interval", CliName: "interval", TypeName: "*time.Duration", IsPointer: true},
+		{Name: "Ratio", CliName: "ratio", TypeName: "float64"},
+		{Name: "Size", CliName: "size", TypeName: "int64"},
+		{Name: "Count", CliName: "count", TypeName: "uint"},
+		{Name: "Limit", CliName: "limit", TypeName: "int64"},
+	}
+
+	err := InterpretInitializer(context.Background(), fileAst, "Options", "NewOptions", optionsMeta, goatPkgImportPath, "github.com/podhmo/goat/internal/interpreter/testpkgs/numeric", loader.New(loader.Config{}))
+	if err != nil {
+		t.Fatalf("InterpretInitializer failed: %v", err)
+	}
+
+	expected := map[string]any{
+		"Timeout":  "5s",
+		"Interval": "1m30s",
+		"Ratio":    0.5,
+		"Size":     int64(1024),
+		"Count":    int64(3),
+		"Limit":    int64(1) << 40,
+	}
+	for _, opt := range optionsMeta {
+		if !reflect.DeepEqual(opt.DefaultValue, expected[opt.Name]) {
+			t.Errorf("%s: expected default %v (%T), got %v (%T)", opt.Name, expected[opt.Name], expected[opt.Name], opt.DefaultValue, opt.DefaultValue)
+		}
+	}
+}
+
+func TestInterpretInitializer_SliceDefaults(t *testing.T) {
+	content := `
+package main
+import "github.com/podhmo/goat"
+
+type Options struct {
+	Tags  []string
+	Ports []int
+	Names []string
+}
+
+var defaultNames = []string{"x"}
+
+func NewOptions() *Options {
+	return &Options{
+		Tags:  goat.Default([]string{"a", "b"}),
+		Ports: goat.Default([]int{80, 443}),
+		Names: goat.Default(defaultNames),
+	}
+}
+`
+	fileAst := parseTestFileForInterpreter(t, content)
+	optionsMeta := []*metadata.OptionMetadata{
+		{Name: "Tags", CliName: "tags", TypeName: "[]string"},
+		{Name: "Ports", CliName: "ports", TypeName: "[]int"},
+		{Name: "Names", CliName: "names", TypeName: "[]string"},
+	}
+
+	err := InterpretInitializer(context.Background(), fileAst, "Options", "NewOptions", optionsMeta, goatPkgImportPath, "github.com/podhmo/goat/internal/interpreter/testpkgs/slice", loader.New(loader.Config{}))
+	if err != nil {
+		t.Fatalf("InterpretInitializer failed: %v", err)
+	}
+
+	expected := map[string]any{
+		"Tags":  []any{"a", "b"},
+		"Ports": []any{int64(80), int64(443)},
+		"Names": nil, // Not a literal
+	}
+	for _, opt := range optionsMeta {
+		if !reflect.DeepEqual(opt.DefaultValue, expected[opt.Name]) {
+			t.Errorf("%s: expected default %v (%T), got %v (%T)", opt.Name, expected[opt.Name], expected[opt.Name], opt.DefaultValue, opt.DefaultValue)
+		}
+	}
+}
+
 func TestInterpretInitializer_AssignmentStyle(t *testing.T) {
 	content := `
 package main
@@ -702,7 +1318,7 @@ func NewConfig() *Config { return &Config{ InputFile: g.File("/path/to/default.t
 			initializerName: "NewConfig",
 			initialOptMeta:  []*metadata.OptionMetadata{{Name: "InputFile"}},
 			expectedOptMeta: []*metadata.OptionMetadata{
-				{Name: "InputFile", DefaultValue: "/path/to/default.txt", TypeName: "string", FileMustExist: false, FileGlobPattern: false},
+				{Name: "InputFile", DefaultValue: "/path/to/default.txt", TypeName: "string", IsFile: true, FileMustExist: false, FileGlobPattern: false},
 			},
 		},
 		{
@@ -716,7 +1332,7 @@ func NewConfig() *Config { return &Config{ DataFile: g.File("data.csv", g.MustEx
 			initializerName: "NewConfig",
 			initialOptMeta:  []*metadata.OptionMetadata{{Name: "DataFile"}},
 			expectedOptMeta: []*metadata.OptionMetadata{
-				{Name: "DataFile", DefaultValue: "data.csv", TypeName: "string", FileMustExist: true, FileGlobPattern: false},
+				{Name: "DataFile", DefaultValue: "data.csv", TypeName: "string", IsFile: true, FileMustExist: true, FileGlobPattern: false},
 			},
 		},
 		{
@@ -730,7 +1346,7 @@ func NewConfig() *Config { return &Config{ Pattern: g.File("*.json", g.GlobPatte
 			initializerName: "NewConfig",
 			initialOptMeta:  []*metadata.OptionMetadata{{Name: "Pattern"}},
 			expectedOptMeta: []*metadata.OptionMetadata{
-				{Name: "Pattern", DefaultValue: "*.json", TypeName: "string", FileMustExist: false, FileGlobPattern: true},
+				{Name: "Pattern", DefaultValue: "*.json", TypeName: "string", IsFile: true, FileMustExist: false, FileGlobPattern: true},
 			},
 		},
 		{
@@ -744,7 +1360,7 @@ func NewConfig() *Config { return &Config{ AssetsDir: g.File("./assets", g.MustE
 			initializerName: "NewConfig",
 			initialOptMeta:  []*metadata.OptionMetadata{{Name: "AssetsDir"}},
 			expectedOptMeta: []*metadata.OptionMetadata{
-				{Name: "AssetsDir", DefaultValue: "./assets", TypeName: "string", FileMustExist: true, FileGlobPattern: true},
+				{Name: "AssetsDir", DefaultValue: "./assets", TypeName: "string", IsFile: true, FileMustExist: true, FileGlobPattern: true},
 			},
 		},
 		{
@@ -762,7 +1378,22 @@ func LoadSettings() *Settings {
 			initializerName: "LoadSettings",
 			initialOptMeta:  []*metadata.OptionMetadata{{Name: "ConfigFile"}},
 			expectedOptMeta: []*metadata.OptionMetadata{
-				{Name: "ConfigFile", DefaultValue: "config.yaml", TypeName: "string", FileMustExist: true, FileGlobPattern: false},
+				{Name: "ConfigFile", DefaultValue: "config.yaml", TypeName: "string", IsFile: true, FileMustExist: true, FileGlobPattern: false},
+			},
+		},
+		{
+			name: "File with slice field and GlobPattern",
+			content: `
+package main
+import g "github.com/podhmo/goat"
+type Config struct { Inputs []string; Output *string }
+func NewConfig() *Config { return &Config{ Inputs: g.File([]string{"*.txt"}, g.GlobPattern(), g.MustExist()), Output: g.File("-") } }`,
+			optionsName:     "Config",
+			initializerName: "NewConfig",
+			initialOptMeta:  []*metadata.OptionMetadata{{Name: "Inputs", TypeName: "[]string"}, {Name: "Output", TypeName: "*string", IsPointer: true}},
+			expectedOptMeta: []*metadata.OptionMetadata{
+				{Name: "Inputs", DefaultValue: []any{"*.txt"}, TypeName: "[]string", IsFile: true, FileMustExist: true, FileGlobPattern: true},
+				{Name: "Output", DefaultValue: "-", TypeName: "*string", IsPointer: true, IsFile: true},
 			},
 		},
 		{
@@ -778,7 +1409,7 @@ func New() *Config { return &Config{ Input: g.File("in.txt", other.SomeOption())
 			initialOptMeta:  []*metadata.OptionMetadata{{Name: "Input"}},
 			expectedOptMeta: []*metadata.OptionMetadata{
 				// Only DefaultValue from g.File should be processed. FileMustExist/FileGlobPattern should remain false.
-				{Name: "Input", DefaultValue: "in.txt", TypeName: "string", FileMustExist: false, FileGlobPattern: false},
+				{Name: "Input", DefaultValue: "in.txt", TypeName: "string", IsFile: true, FileMustExist: false, FileGlobPattern: false},
 			},
 		},
 	}
@@ -825,6 +1456,7 @@ func New() *Config { return &Config{ Input: g.File("in.txt", other.SomeOption())
 				if expectedOpt.CliName == "" {
 					expectedOpt.CliName = actualOpt.CliName
 				}
+				expectedOpt.InitializerPosition = actualOpt.InitializerPosition // Checked by TestInterpretInitializer_InitializerPosition
 
 				if !reflect.DeepEqual(actualOpt, expectedOpt) {
 					t.Errorf("OptionMetadata mismatch for '%s':\nExpected: %+v (type %T)\nActual:   %+v (type %T)",
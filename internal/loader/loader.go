@@ -179,6 +179,11 @@ func (l *Loader) resolveImport(ctx context.Context, importerPath string, importP
 	return pkg, nil
 }
 
+// Fset returns the file set used for parsing files.
+func (l *Loader) Fset() *token.FileSet {
+	return l.fset
+}
+
 // GetAST retrieves a parsed AST from the cache by its canonical file path.
 // It returns the AST and true if found, otherwise nil and false.
 func (l *Loader) GetAST(filePath string) (*ast.File, bool) {
@@ -290,10 +290,12 @@ func (p *Package) GetStruct(name string) (*StructInfo, error) {
 						fi := FieldInfo{
 							Name:         fieldName.Name,
 							TypeExpr:     field.Type,
+							Pos:          fieldName.Pos(),
 							ParentStruct: structInfo, // Set ParentStruct
 						}
 						if field.Tag != nil {
 							fi.Tag = field.Tag.Value
+							fi.TagPos = field.Tag.Pos()
 						}
 						structInfo.Fields = append(structInfo.Fields, fi)
 					}
@@ -303,10 +305,12 @@ func (p *Package) GetStruct(name string) (*StructInfo, error) {
 							Name:         "", // Embedded field
 							TypeExpr:     field.Type,
 							Embedded:     true,
+							Pos:          field.Type.Pos(),
 							ParentStruct: structInfo, // Set ParentStruct
 						}
 						if field.Tag != nil {
 							fi.Tag = field.Tag.Value
+							fi.TagPos = field.Tag.Pos()
 						}
 						structInfo.Fields = append(structInfo.Fields, fi)
 					}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"go/ast"
+	"go/token"
 	"reflect"
 	"strconv"
 )
@@ -23,14 +24,22 @@ type FieldInfo struct {
 	Tag      string   // Raw tag string (e.g., `json:"name,omitempty"`)
 	TypeExpr ast.Expr // AST expression for the field's type
 	Embedded bool
+	Pos      token.Pos // Position of the field name (or of the type of an embedded field)
+	TagPos   token.Pos // Position of the tag literal, token.NoPos if the field has no tag
 	// ParentStruct allows FieldInfo to access its containing struct's context, like its package.
 	ParentStruct *StructInfo
 }
 
 // GetTag parses the struct tag and returns the value associated with the given key.
 func (fi *FieldInfo) GetTag(key string) string {
+	value, _ := fi.LookupTag(key)
+	return value
+}
+
+// LookupTag is like GetTag, but also reports whether the key is present in the struct tag (e.g. `default:""`).
+func (fi *FieldInfo) LookupTag(key string) (string, bool) {
 	if fi.Tag == "" {
-		return ""
+		return "", false
 	}
 	// Unquote the tag first if it's quoted (like from ast.BasicLit.Value)
 	unquotedTag := fi.Tag
@@ -42,7 +51,58 @@ func (fi *FieldInfo) GetTag(key string) string {
 			unquotedTag = fi.Tag
 		}
 	}
-	return reflect.StructTag(unquotedTag).Get(key)
+	return reflect.StructTag(unquotedTag).Lookup(key)
+}
+
+// TagKeyPos returns the position of the key in the struct tag (e.g. of env in `env:"PORT"`),
+// or token.NoPos if the key is not in the tag.
+func (fi *FieldInfo) TagKeyPos(key string) token.Pos {
+	if !fi.TagPos.IsValid() {
+		return token.NoPos
+	}
+	if _, ok := fi.LookupTag(key); !ok {
+		return token.NoPos
+	}
+	if fi.Tag[0] != '`' {
+		return fi.TagPos
+	}
+
+	// Walk the tag like reflect.StructTag.Lookup, keeping the offset of each key in the literal.
+	tag := fi.Tag[1 : len(fi.Tag)-1]
+	offset := 1 // The opening backquote
+	for tag != "" {
+		i := 0
+		for i < len(tag) && tag[i] == ' ' {
+			i++
+		}
+		offset += i
+		tag = tag[i:]
+
+		i = 0
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+		if i == 0 || i+1 >= len(tag) || tag[i] != ':' || tag[i+1] != '"' {
+			break
+		}
+		name := tag[:i]
+		j := i + 2 // Skip the quoted value
+		for j < len(tag) && tag[j] != '"' {
+			if tag[j] == '\\' {
+				j++
+			}
+			j++
+		}
+		if j >= len(tag) {
+			break
+		}
+		if name == key {
+			return fi.TagPos + token.Pos(offset)
+		}
+		offset += j + 1
+		tag = tag[j+1:]
+	}
+	return fi.TagPos
 }
 
 // ResolveType (Conceptual): This method would be responsible for analyzing
@@ -0,0 +1,371 @@
+package mangen
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	helpgen "github.com/podhmo/goat/internal/helpgen"
+	"github.com/podhmo/goat/internal/metadata"
+)
+
+// GenerateMan returns the man(1) page of the command in roff, from the same CommandMetadata as the help message.
+func GenerateMan(cmdMeta *metadata.CommandMetadata) string {
+	if cmdMeta == nil {
+		return "<error>" // Handle nil case gracefully
+	}
+
+	var sb strings.Builder
+	name := helpgen.CommandName(cmdMeta)
+	fmt.Fprintf(&sb, ".TH %s 1 \"\" \"\" \"User Commands\"\n", quote(strings.ToUpper(name)))
+
+	sb.WriteString(".SH NAME\n")
+	if summary := summaryOf(cmdMeta.Description); summary != "" {
+		fmt.Fprintf(&sb, "%s \\- %s\n", escape(name), escape(summary))
+	} else {
+		fmt.Fprintf(&sb, "%s\n", escape(name))
+	}
+
+	sb.WriteString(".SH SYNOPSIS\n")
+	argOptions := positionalOptions(cmdMeta.Options)
+	fmt.Fprintf(&sb, ".B %s\n", escape(name))
+	switch {
+	case len(cmdMeta.Subcommands) > 0 && len(cmdMeta.GlobalOptions) > 0:
+		sb.WriteString("[\\fIglobal flags\\fR] \\fIcommand\\fR [\\fIflags\\fR]\n")
+	case len(cmdMeta.Subcommands) > 0:
+		sb.WriteString("\\fIcommand\\fR [\\fIflags\\fR]\n")
+	case len(argOptions) > 0:
+		fmt.Fprintf(&sb, "[\\fIflags\\fR] %s\n", argsSynopsis(argOptions))
+	default:
+		sb.WriteString("[\\fIflags\\fR]\n")
+	}
+
+	if cmdMeta.Description != "" {
+		sb.WriteString(".SH DESCRIPTION\n")
+		writeParagraphs(&sb, cmdMeta.Description)
+	}
+
+	if len(cmdMeta.Subcommands) > 0 {
+		// The global flags (and --env-file and --print-config with them) are given before the subcommand.
+		sb.WriteString(".SH OPTIONS\n")
+		writeOptionSections(&sb, cmdMeta.GlobalOptions)
+		if len(cmdMeta.GlobalOptions) > 0 {
+			writeBuiltinOptions(&sb, cmdMeta)
+		} else {
+			writeHelpOption(&sb)
+		}
+
+		sb.WriteString(".SH COMMANDS\n")
+		for _, sub := range cmdMeta.Subcommands {
+			fmt.Fprintf(&sb, ".SS %s\n", quote(name+" "+sub.Name))
+			subArgOptions := positionalOptions(sub.Options)
+			fmt.Fprintf(&sb, ".B %s %s\n", escape(name), escape(sub.Name))
+			if len(subArgOptions) > 0 {
+				fmt.Fprintf(&sb, "[\\fIflags\\fR] %s\n", argsSynopsis(subArgOptions))
+			} else {
+				sb.WriteString("[\\fIflags\\fR]\n")
+			}
+			if sub.Description != "" {
+				sb.WriteString(".PP\n")
+				writeParagraphs(&sb, sub.Description)
+			}
+			writeArguments(&sb, subArgOptions)
+			writeOptions(&sb, flagOptions(sub.Options))
+			writeBuiltinOptions(&sb, sub)
+		}
+	} else {
+		if len(argOptions) > 0 {
+			sb.WriteString(".SH ARGUMENTS\n")
+			writeArguments(&sb, argOptions)
+		}
+		sb.WriteString(".SH OPTIONS\n")
+		writeOptionSections(&sb, flagOptions(cmdMeta.Options))
+		writeBuiltinOptions(&sb, cmdMeta)
+		if len(cmdMeta.GlobalOptions) > 0 {
+			sb.WriteString(".SH \"GLOBAL OPTIONS\"\n")
+			writeOptionSections(&sb, cmdMeta.GlobalOptions)
+		}
+	}
+
+	writeEnvironment(&sb, cmdMeta)
+	return sb.String()
+}
+
+// summaryOf returns the first line of the description, without the trailing period, for the NAME section.
+func summaryOf(description string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(description), "\n")
+	return strings.TrimSuffix(strings.TrimSpace(line), ".")
+}
+
+func positionalOptions(options []*metadata.OptionMetadata) []*metadata.OptionMetadata {
+	var argOptions []*metadata.OptionMetadata
+	for _, opt := range options {
+		if opt.Positional != nil {
+			argOptions = append(argOptions, opt)
+		}
+	}
+	sort.SliceStable(argOptions, func(i, j int) bool { return argOptions[i].Positional.Index < argOptions[j].Positional.Index })
+	return argOptions
+}
+
+func flagOptions(options []*metadata.OptionMetadata) []*metadata.OptionMetadata {
+	var flags []*metadata.OptionMetadata
+	for _, opt := range options {
+		if opt.Positional == nil {
+			flags = append(flags, opt)
+		}
+	}
+	return flags
+}
+
+// argsSynopsis returns the positional arguments in the SYNOPSIS section (e.g. "\fIsrc\fR [\fIfiles\fR...]").
+func argsSynopsis(argOptions []*metadata.OptionMetadata) string {
+	parts := make([]string, 0, len(argOptions))
+	for _, opt := range argOptions {
+		arg := "\\fI" + escape(opt.CliName) + "\\fR"
+		switch {
+		case opt.Positional.Arity == "*":
+			parts = append(parts, "["+arg+"...]")
+		case opt.Positional.Arity != "":
+			parts = append(parts, arg+"...")
+		case opt.IsPointer:
+			parts = append(parts, "["+arg+"]")
+		default:
+			parts = append(parts, arg)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// writeArguments writes a tagged paragraph per positional argument.
+func writeArguments(w io.Writer, argOptions []*metadata.OptionMetadata) {
+	for _, opt := range argOptions {
+		arity := "Required."
+		switch opt.Positional.Arity {
+		case "":
+			if opt.IsPointer {
+				arity = "Optional."
+			}
+		case "*":
+			arity = "Zero or more."
+		case "+":
+			arity = "One or more."
+		default:
+			arity = fmt.Sprintf("Exactly %s.", opt.Positional.Arity)
+		}
+		fmt.Fprintf(w, ".TP\n\\fI%s\\fR\n", escape(opt.CliName))
+		if opt.HelpText != "" {
+			writeLines(w, opt.HelpText)
+			fmt.Fprintln(w, ".br")
+		}
+		fmt.Fprintln(w, arity)
+	}
+}
+
+// writeOptionSections writes the options, those of each nested struct (with a Section) under their own subsection,
+// like the help message.
+func writeOptionSections(w io.Writer, options []*metadata.OptionMetadata) {
+	var sections []string
+	optionsBySection := make(map[string][]*metadata.OptionMetadata)
+	for _, opt := range options {
+		if _, exists := optionsBySection[opt.Section]; !exists && opt.Section != "" {
+			sections = append(sections, opt.Section)
+		}
+		optionsBySection[opt.Section] = append(optionsBySection[opt.Section], opt)
+	}
+	writeOptions(w, optionsBySection[""])
+	for _, section := range sections {
+		heading, _, _ := strings.Cut(section, "\n") // The first line of the doc comment
+		fmt.Fprintf(w, ".SS %s\n", quote(strings.TrimSuffix(heading, ".")))
+		writeOptions(w, optionsBySection[section])
+	}
+}
+
+// writeOptions writes a tagged paragraph per option: the flag names, the help text,
+// and the notes of the help message (default, allowed values, environment variable, ...) as sentences.
+func writeOptions(w io.Writer, options []*metadata.OptionMetadata) {
+	for _, opt := range options {
+		var names []string
+		for _, name := range helpgen.FlagNames(opt) {
+			names = append(names, "\\fB"+escapeFlag(name)+"\\fR")
+		}
+		fmt.Fprintf(w, ".TP\n%s", strings.Join(names, ", "))
+		if !strings.HasSuffix(opt.TypeName, "bool") {
+			fmt.Fprintf(w, " \\fI%s\\fR", escape(helpgen.TypeIndicator(opt)))
+		}
+		fmt.Fprintln(w)
+		if opt.HelpText != "" {
+			writeLines(w, opt.HelpText)
+		}
+
+		var notes []string
+		if helpgen.IsRequiredFlag(opt) {
+			notes = append(notes, "Required.")
+		}
+		if defaultText := helpgen.DefaultText(opt); defaultText != "" {
+			notes = append(notes, fmt.Sprintf("Default: %s.", defaultText))
+		}
+		if allowed := helpgen.AllowedValues(opt); allowed != "" {
+			notes = append(notes, fmt.Sprintf("Allowed values: %s.", allowed))
+		}
+		if valueRange := opt.ValueRange(); valueRange != "" {
+			notes = append(notes, fmt.Sprintf("Range: %s.", valueRange))
+		}
+		if lengthRange := opt.LengthRange(); lengthRange != "" {
+			if opt.LengthInCharacters() {
+				lengthRange += " characters"
+			}
+			notes = append(notes, fmt.Sprintf("Length: %s.", lengthRange))
+		}
+		if opt.Pattern != "" {
+			notes = append(notes, fmt.Sprintf("Pattern: %s.", opt.Pattern))
+		}
+		if opt.FileMustExist {
+			notes = append(notes, "The file must exist.")
+		}
+		if opt.FileGlobPattern {
+			notes = append(notes, "Glob patterns are expanded.")
+		}
+		if policy := opt.DirPolicy(); opt.IsDir && policy != "" {
+			notes = append(notes, fmt.Sprintf("The directory %s.", strings.Replace(policy, "created", "is created", 1)))
+		}
+		if opt.IsSecret {
+			notes = append(notes, fmt.Sprintf("The value is secret, and can be read from the file given by --%s.", opt.SecretFileFlag()))
+		}
+		if opt.EnvVar != "" {
+			notes = append(notes, fmt.Sprintf("Environment variable: %s.", opt.EnvVar))
+		}
+		if len(notes) > 0 {
+			if opt.HelpText != "" {
+				fmt.Fprintln(w, ".br")
+			}
+			fmt.Fprintln(w, escapeText(strings.Join(notes, " ")))
+		}
+	}
+}
+
+// writeBuiltinOptions writes the flags added by goat: --help, and --env-file and --print-config if enabled.
+func writeBuiltinOptions(w io.Writer, cmdMeta *metadata.CommandMetadata) {
+	writeHelpOption(w)
+	if cmdMeta.DotEnv {
+		fmt.Fprintf(w, ".TP\n\\fB%s\\fR \\fIstring\\fR\n", escapeFlag("--env-file"))
+		fmt.Fprintln(w, "Load environment variables from the file.")
+		fmt.Fprintln(w, ".br")
+		fmt.Fprintln(w, `Default: ".env".`)
+	}
+	if cmdMeta.PrintConfig {
+		fmt.Fprintf(w, ".TP\n\\fB%s\\fR\n", escapeFlag("--print-config"))
+		fmt.Fprintln(w, escapeText("Print the effective options and their sources (text, or json with --print-config=json), and exit."))
+	}
+}
+
+func writeHelpOption(w io.Writer) {
+	fmt.Fprintf(w, ".TP\n\\fB%s\\fR, \\fB%s\\fR\n", escapeFlag("-h"), escapeFlag("--help"))
+	fmt.Fprintln(w, "Show this help message and exit.")
+}
+
+// writeEnvironment writes the ENVIRONMENT section, a tagged paragraph per environment variable of the options
+// (and the subcommands), or nothing if there are none.
+func writeEnvironment(w io.Writer, cmdMeta *metadata.CommandMetadata) {
+	type envEntry struct {
+		name        string
+		description string
+	}
+	var entries []envEntry
+	seen := make(map[string]bool)
+	add := func(options []*metadata.OptionMetadata, subcommand string) {
+		for _, opt := range options {
+			if opt.EnvVar == "" || seen[opt.EnvVar] {
+				continue
+			}
+			seen[opt.EnvVar] = true
+			target, format := "--"+opt.CliName, "Sets %s."
+			switch {
+			case opt.Positional != nil:
+				target = opt.CliName
+			case opt.TypeName == "bool" && opt.IsRequired && opt.DefaultValueAsBool(): // Given as --no-<flag>
+				target, format = "--no-"+opt.CliName, "Sets the value turned off by %s (true or false)."
+			}
+			if subcommand != "" {
+				target += " of " + subcommand
+			}
+			description := fmt.Sprintf(format, target)
+			entries = append(entries, envEntry{name: opt.EnvVar, description: description})
+			if opt.IsSecret {
+				entries = append(entries, envEntry{name: opt.SecretFileEnvVar(), description: fmt.Sprintf("File to read the value of %s from.", target)})
+			}
+		}
+	}
+	add(cmdMeta.Options, "")
+	add(cmdMeta.GlobalOptions, "")
+	for _, sub := range cmdMeta.Subcommands {
+		add(sub.Options, sub.Name)
+	}
+	if len(entries) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w, ".SH ENVIRONMENT")
+	fmt.Fprintln(w, "The flags take precedence over the environment variables.")
+	if cmdMeta.DotEnv {
+		fmt.Fprintln(w, escapeText("The variables are also loaded from the .env file (or the file given by --env-file), without overriding the environment."))
+	}
+	for _, entry := range entries {
+		fmt.Fprintf(w, ".TP\n.B %s\n%s\n", escape(entry.name), escapeText(entry.description))
+	}
+}
+
+// writeParagraphs writes the text, with a .PP request between the paragraphs separated by blank lines.
+func writeParagraphs(w io.Writer, text string) {
+	for i, paragraph := range strings.Split(strings.TrimSpace(text), "\n\n") {
+		if i > 0 {
+			fmt.Fprintln(w, ".PP")
+		}
+		writeLines(w, paragraph)
+	}
+}
+
+// writeLines writes the lines of the text, escaped.
+func writeLines(w io.Writer, text string) {
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			fmt.Fprintln(w, escapeText(line))
+		}
+	}
+}
+
+// escape escapes the backslashes of s for roff.
+func escape(s string) string {
+	return strings.ReplaceAll(s, `\`, `\e`)
+}
+
+// escapeText escapes a line of text for roff: the backslashes, the flag names (e.g. --env-file),
+// and a leading dot or quote, which would start a request.
+func escapeText(s string) string {
+	words := strings.Split(escape(s), " ")
+	for i, word := range words {
+		if strings.HasPrefix(word, "-") || strings.Contains(word, "--") {
+			words[i] = escapeFlag(word)
+		}
+	}
+	s = strings.Join(words, " ")
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+		s = `\&` + s
+	}
+	return s
+}
+
+// escapeFlag escapes the hyphens of a flag name, so that they are rendered as ASCII hyphens (e.g. \-\-verbose).
+func escapeFlag(s string) string {
+	return strings.ReplaceAll(s, "-", `\-`)
+}
+
+// quote quotes a macro argument containing spaces.
+func quote(s string) string {
+	s = escape(s)
+	if strings.ContainsAny(s, " \"") {
+		return `"` + strings.ReplaceAll(s, `"`, `\(dq`) + `"`
+	}
+	return s
+}
@@ -0,0 +1,117 @@
+package mangen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/podhmo/goat/internal/metadata"
+)
+
+func TestGenerateMan(t *testing.T) {
+	cmdMeta := &metadata.CommandMetadata{
+		Name:        "app",
+		Description: "Copy the files.\nIt copies the files to the server.\n\n.gitignore files are skipped.",
+		Options: []*metadata.OptionMetadata{
+			{Name: "Level", CliName: "level", TypeName: "string", HelpText: "Log level.", EnvVar: "APP_LEVEL", DefaultValue: "info", EnumValues: []any{"debug", "info"}, Pattern: "^[a-z]+$", IsRequired: true},
+			{Name: "Color", CliName: "color", TypeName: "bool", HelpText: "Colored output.", EnvVar: "APP_COLOR", DefaultValue: true, IsRequired: true},
+			{Name: "Token", CliName: "token", TypeName: "string", HelpText: "API token.", EnvVar: "APP_TOKEN", IsSecret: true, DefaultValue: "xxx"},
+			{Name: "Port", CliName: "port", TypeName: "int", ShortName: "p", HelpText: "Port.", MinValue: int64(1), MaxValue: int64(65535), IsRequired: true},
+			{Name: "Files", CliName: "files", TypeName: "[]string", HelpText: "Files to copy.", Positional: &metadata.PositionalArg{Index: 0, Arity: "+"}},
+		},
+		DotEnv: true,
+	}
+
+	man := GenerateMan(cmdMeta)
+
+	expected := `.TH APP 1 "" "" "User Commands"
+.SH NAME
+app \- Copy the files
+.SH SYNOPSIS
+.B app
+[\fIflags\fR] \fIfiles\fR...
+.SH DESCRIPTION
+Copy the files.
+It copies the files to the server.
+.PP
+\&.gitignore files are skipped.
+.SH ARGUMENTS
+.TP
+\fIfiles\fR
+Files to copy.
+.br
+One or more.
+.SH OPTIONS
+.TP
+\fB\-\-level\fR \fIstring\fR
+Log level.
+.br
+Default: "info". Allowed values: "debug", "info". Pattern: ^[a-z]+$. Environment variable: APP_LEVEL.
+.TP
+\fB\-\-no\-color\fR
+Colored output.
+.br
+Environment variable: APP_COLOR.
+.TP
+\fB\-\-token\fR \fIstring\fR
+API token.
+.br
+The value is secret, and can be read from the file given by \-\-token\-file. Environment variable: APP_TOKEN.
+.TP
+\fB\-p\fR, \fB\-\-port\fR \fIint\fR
+Port.
+.br
+Required. Range: 1..65535.
+.TP
+\fB\-h\fR, \fB\-\-help\fR
+Show this help message and exit.
+.TP
+\fB\-\-env\-file\fR \fIstring\fR
+Load environment variables from the file.
+.br
+Default: ".env".
+.SH ENVIRONMENT
+The flags take precedence over the environment variables.
+The variables are also loaded from the .env file (or the file given by \-\-env\-file), without overriding the environment.
+.TP
+.B APP_LEVEL
+Sets \-\-level.
+.TP
+.B APP_COLOR
+Sets the value turned off by \-\-no\-color (true or false).
+.TP
+.B APP_TOKEN
+Sets \-\-token.
+.TP
+.B APP_TOKEN_FILE
+File to read the value of \-\-token from.
+`
+	if man != expected {
+		t.Errorf("man page mismatch:\n---EXPECTED---\n%s\n\n---ACTUAL---\n%s", expected, man)
+	}
+}
+
+func TestGenerateMan_Subcommands(t *testing.T) {
+	cmdMeta := &metadata.CommandMetadata{
+		Name:          "app",
+		Description:   "Manage the server.",
+		GlobalOptions: []*metadata.OptionMetadata{{Name: "Debug", CliName: "debug", TypeName: "bool", HelpText: "Debug mode."}},
+		Subcommands: []*metadata.CommandMetadata{
+			{Name: "serve", Description: "Start the server.", Options: []*metadata.OptionMetadata{{Name: "Port", CliName: "port", TypeName: "int", HelpText: "Port.", EnvVar: "PORT", IsRequired: true, DefaultValue: 8080}}},
+			{Name: "migrate", Description: "Run the migrations."},
+		},
+	}
+
+	man := GenerateMan(cmdMeta)
+
+	for _, want := range []string{
+		".B app\n[\\fIglobal flags\\fR] \\fIcommand\\fR [\\fIflags\\fR]\n",
+		".SH OPTIONS\n.TP\n\\fB\\-\\-debug\\fR\nDebug mode.\n",
+		".SH COMMANDS\n.SS \"app serve\"\n.B app serve\n[\\fIflags\\fR]\n.PP\nStart the server.\n.TP\n\\fB\\-\\-port\\fR \\fIint\\fR\nPort.\n.br\nDefault: 8080. Environment variable: PORT.\n",
+		".SS \"app migrate\"\n",
+		".TP\n.B PORT\nSets \\-\\-port of serve.\n",
+	} {
+		if !strings.Contains(man, want) {
+			t.Errorf("man page does not contain %q:\n%s", want, man)
+		}
+	}
+}
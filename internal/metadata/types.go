@@ -1,6 +1,13 @@
 package metadata
 
-import "go/token"
+import (
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"strings"
+
+	"github.com/podhmo/goat/internal/utils/stringutils"
+)
 
 // CommandMetadata holds all extracted information about a CLI command
 // that goat needs to generate code or help messages.
@@ -9,7 +16,16 @@ type CommandMetadata struct {
 	Description      string // Overall help description for the command (from run func doc)
 	RunFunc          *RunFuncInfo
 	Options          []*OptionMetadata
-	MainFuncPosition *token.Position // TODO: For knowing where to replace main func content
+	GlobalOptions    []*OptionMetadata `json:",omitempty"` // Options shared by the command and its subcommands (from the global options parameter)
+	MainFuncPosition *token.Position   // TODO: For knowing where to replace main func content
+
+	OptionGroups       []*OptionGroup `json:",omitempty"` // Constraints on the options (from goat.OneOf and goat.Together in the initializer)
+	GlobalOptionGroups []*OptionGroup `json:",omitempty"` // Constraints on the global options (from the global options initializer)
+
+	Subcommands []*CommandMetadata `json:",omitempty"` // Git-style subcommands, dispatched on os.Args[1] (RunFunc is nil when present)
+
+	DotEnv      bool `json:",omitempty"` // Load the .env file (or the file given by --env-file) into the environment before reading it (from `goat emit -dotenv`)
+	PrintConfig bool `json:",omitempty"` // Add the --print-config flag, printing the effective options and their sources (from `goat emit -print-config`)
 }
 
 // RunFuncInfo describes the target 'run' function.
@@ -23,6 +39,15 @@ type RunFuncInfo struct {
 	ContextArgName             string // Name of the context.Context parameter (if present)
 	ContextArgType             string // Type name of the context.Context parameter (if present)
 	InitializerFunc            string // Name of the function that initializes the options struct (e.g., NewOptions)
+	OptionsHasValidate         bool   `json:",omitempty"` // True if the options struct has a Validate() error method
+
+	// Global options, e.g. GlobalOptions in run(ctx, global GlobalOptions, opts ServeOptions)
+	GlobalOptionsArgName             string `json:",omitempty"` // Name of the global options struct parameter (e.g., "global")
+	GlobalOptionsArgType             string `json:",omitempty"` // Type name of the global options struct (e.g., "GlobalOptions")
+	GlobalOptionsArgTypeNameStripped string `json:",omitempty"` // Base type name of the global options struct
+	GlobalOptionsArgIsPointer        bool   `json:",omitempty"` // True if GlobalOptionsArgType is a pointer
+	GlobalInitializerFunc            string `json:",omitempty"` // Name of the function that initializes the global options struct (e.g., NewGlobalOptions)
+	GlobalOptionsHasValidate         bool   `json:",omitempty"` // True if the global options struct has a Validate() error method
 }
 
 // OptionMetadata holds information about a single command-line option.
@@ -35,14 +60,131 @@ type OptionMetadata struct {
 	IsRequired        bool   // True if the option must be provided
 	EnvVar            string // Environment variable name to read from (from `env` tag)
 	DefaultValue      any    // Default value (from goat.Default or struct tag)
+	DefaultFromTag    bool   `json:",omitempty"` // True if DefaultValue is from the `default` tag, and the initializer does not set the field
 	EnumValues        []any  // Allowed enum values (from goat.Enum or struct tag)
 	IsTextUnmarshaler bool   // True if the field's type implements encoding.TextUnmarshaler
 	IsTextMarshaler   bool   // True if the field's type implements encoding.TextMarshaler
 	UnderlyingKind    string // Stores the underlying kind if the type is a named basic type (e.g., "string", "int")
 
+	// Slice-specific options
+	ElemIsTextUnmarshaler bool `json:",omitempty"` // True if the option is a slice whose element type implements encoding.TextUnmarshaler
+
+	// Additional flag names (from goat.Short/goat.Alias or the `short`/`alias` struct tags)
+	ShortName string   `json:",omitempty"` // One-letter short name (e.g., "v" for -v)
+	Aliases   []string `json:",omitempty"` // Additional long names (e.g., "out" for --out)
+
+	// Value constraints (from goat.Min/goat.Max/goat.Range, goat.MinLen/goat.MaxLen and goat.Pattern)
+	MinValue any    `json:",omitempty"` // Minimum value (inclusive) of a numeric option, int64 or float64
+	MaxValue any    `json:",omitempty"` // Maximum value (inclusive) of a numeric option, int64 or float64
+	MinLen   *int   `json:",omitempty"` // Minimum length of a string or slice option
+	MaxLen   *int   `json:",omitempty"` // Maximum length of a string or slice option
+	Pattern  string `json:",omitempty"` // Regular expression that a string option must match (from goat.Pattern)
+
 	// File-specific options
+	IsFile          bool `json:"isFile,omitempty"` // True if the option is a file path (goat.File)
 	FileMustExist   bool `json:"fileMustExist,omitempty"`
 	FileGlobPattern bool `json:"fileGlobPattern,omitempty"`
+
+	// Directory-specific options (from goat.Dir)
+	IsDir              bool   `json:",omitempty"` // True if the option is a directory path
+	DirMustExist       bool   `json:",omitempty"` // The directory must exist (goat.MustExist)
+	DirCreateIfMissing bool   `json:",omitempty"` // The directory is created if missing (goat.CreateIfMissing)
+	DirPerm            uint32 `json:",omitempty"` // Permission bits of the created directory (e.g. 0o755)
+
+	// Nested struct options (e.g. the Host field of `DB DBOptions` is named "DB.Host")
+	Section string `json:",omitempty"` // Heading of the options of the nested struct in the help message (from the doc comment of the field)
+
+	// Secret options (from goat.Secret or the `secret:"true"` struct tag)
+	IsSecret bool `json:",omitempty"` // True if the value must not be shown; it can also be read from the file given by --<flag>-file or <ENV>_FILE
+
+	// Derived environment variables (from `goat emit -env-prefix`)
+	NoEnvVar bool `json:",omitempty"` // True if the option opts out of the derived environment variable (`env:"-"`)
+
+	// Config file options (from the `goat:"config"` and `json` struct tags)
+	IsConfigFile bool   `json:",omitempty"` // True if the option is the path of the JSON config file, applied before the environment variables and flags
+	ConfigKey    string `json:",omitempty"` // Key of the option in the config file, from the `json` tag (CliName if empty, "-" if not read from the config file)
+
+	Positional *PositionalArg `json:",omitempty"` // Set if the field receives positional arguments instead of a flag
+
+	// Source positions (nil if unknown)
+	FieldPosition       *token.Position `json:",omitempty"` // Position of the field in the options struct
+	InitializerPosition *token.Position `json:",omitempty"` // Position of the value of the field in the options initializer (e.g. goat.Default("info"))
+	EnvTagPosition      *token.Position `json:",omitempty"` // Position of the env key in the struct tag of the field
+}
+
+// PositionalArg describes how an option receives positional arguments (from `arg` or `args` struct tags).
+type PositionalArg struct {
+	Index int    // Position of the (first) argument
+	Arity string `json:",omitempty"` // For the remaining arguments: "+" (at least one), "*" (any number), or "N" (exactly N). Empty for a single argument
+}
+
+// Kinds of OptionGroup.
+const (
+	OptionGroupOneOf    = "oneOf"    // At most one of the options may be given (goat.OneOf)
+	OptionGroupTogether = "together" // The options must be given together, or not at all (goat.Together)
+)
+
+// OptionGroup is a constraint on a set of options, declared with goat.OneOf or goat.Together.
+type OptionGroup struct {
+	Kind    string   // OptionGroupOneOf or OptionGroupTogether
+	Options []string // Field names of the options in the group (e.g. "JSON", "YAML")
+}
+
+// Description describes the constraint of the group for help messages (e.g. "mutually exclusive").
+func (g *OptionGroup) Description() string {
+	switch g.Kind {
+	case OptionGroupOneOf:
+		return "mutually exclusive"
+	case OptionGroupTogether:
+		return "must be given together"
+	}
+	return g.Kind
+}
+
+// RedactedValue is shown instead of the value of a secret option.
+const RedactedValue = "[redacted]"
+
+// MarshalJSON marshals the option, with the default value of a secret option redacted.
+func (om *OptionMetadata) MarshalJSON() ([]byte, error) {
+	type optionMetadata OptionMetadata // Without the MarshalJSON method
+	if om.IsSecret && om.DefaultValue != nil && om.DefaultValue != "" {
+		redacted := *om
+		redacted.DefaultValue = RedactedValue
+		return json.Marshal((*optionMetadata)(&redacted))
+	}
+	return json.Marshal((*optionMetadata)(om))
+}
+
+// SecretFileFlag returns the name of the flag giving the file to read the value of a secret option from (e.g. "password-file").
+func (om *OptionMetadata) SecretFileFlag() string {
+	if om.CliName == "" {
+		return stringutils.ToKebabCase(om.Name) + "-file"
+	}
+	return om.CliName + "-file"
+}
+
+// SecretFileEnvVar returns the environment variable giving the file to read the value of a secret option from (e.g. "DB_PASSWORD_FILE"),
+// or an empty string if the option has no environment variable.
+func (om *OptionMetadata) SecretFileEnvVar() string {
+	if om.EnvVar == "" {
+		return ""
+	}
+	return om.EnvVar + "_FILE"
+}
+
+// ConfigFileKey returns the key of the option in the config file (e.g. "user-name"), falling back to its flag name,
+// or an empty string if the option is not read from the config file.
+func (om *OptionMetadata) ConfigFileKey() string {
+	if om.Positional != nil || om.IsConfigFile || om.ConfigKey == "-" {
+		return ""
+	}
+	if om.ConfigKey != "" {
+		return om.ConfigKey
+	}
+	if om.CliName == "" {
+		return stringutils.ToKebabCase(om.Name)
+	}
+	return om.CliName
 }
 
 // DefaultValueAsBool checks if the DefaultValue is a boolean and true.
@@ -52,3 +194,66 @@ func (om *OptionMetadata) DefaultValueAsBool() bool {
 	}
 	return false
 }
+
+// ValueRange returns the allowed range of a numeric option as "min..max" (either side may be omitted),
+// or an empty string if the option has no range constraint.
+func (om *OptionMetadata) ValueRange() string {
+	return formatRange(om.MinValue, om.MaxValue)
+}
+
+// LengthRange returns the allowed length of a string or slice option as "min..max" (either side may be omitted),
+// or an empty string if the option has no length constraint.
+func (om *OptionMetadata) LengthRange() string {
+	var minLen, maxLen any
+	if om.MinLen != nil {
+		minLen = *om.MinLen
+	}
+	if om.MaxLen != nil {
+		maxLen = *om.MaxLen
+	}
+	return formatRange(minLen, maxLen)
+}
+
+// LengthInCharacters reports whether the length of the option is the number of characters (runes) of a string,
+// rather than the number of items of a slice or map.
+func (om *OptionMetadata) LengthInCharacters() bool {
+	return strings.TrimPrefix(om.TypeName, "*") == "string" || om.UnderlyingKind == "string"
+}
+
+func formatRange(minValue, maxValue any) string {
+	if minValue == nil && maxValue == nil {
+		return ""
+	}
+	var lower, upper string
+	if minValue != nil {
+		lower = fmt.Sprintf("%v", minValue)
+	}
+	if maxValue != nil {
+		upper = fmt.Sprintf("%v", maxValue)
+	}
+	return lower + ".." + upper
+}
+
+// DirPolicy describes how a directory option is checked before calling the run function
+// (e.g. "must exist", "created if missing"), or returns an empty string if there is no policy.
+func (om *OptionMetadata) DirPolicy() string {
+	switch {
+	case om.DirMustExist:
+		return "must exist"
+	case om.DirCreateIfMissing:
+		return "created if missing"
+	}
+	return ""
+}
+
+// IsOptionalPositional checks if the option receives positional arguments that may be omitted
+// (a pointer field for a single argument, or `args:"*"` for the remaining arguments).
+func (om *OptionMetadata) IsOptionalPositional() bool {
+	if om.Positional == nil {
+		return false
+	}
+	if om.Positional.Arity != "" {
+		return om.Positional.Arity == "*"
+	}
+	return om.IsPointer
+}
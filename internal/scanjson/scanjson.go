@@ -0,0 +1,240 @@
+// Package scanjson defines the JSON output of `goat scan`, a stable format of the command metadata for other tools.
+//
+// Unlike metadata.CommandMetadata, which follows the needs of the generators, the format only changes with SchemaVersion:
+// fields may be added within a version, but they are not renamed or removed, and their meaning does not change.
+package scanjson
+
+import (
+	"go/token"
+	"path/filepath"
+	"strings"
+
+	"github.com/podhmo/goat/internal/metadata"
+)
+
+// SchemaVersion is the version of the format, incremented on incompatible changes.
+const SchemaVersion = 1
+
+// Command is a command, or a subcommand of a git-style CLI.
+type Command struct {
+	SchemaVersion int    `json:"schemaVersion,omitempty"` // Set for the top-level command only
+	Name          string `json:"name"`
+	Description   string `json:"description,omitempty"`
+
+	RunFunc            *RunFunc       `json:"runFunc,omitempty"` // Nil for a command with subcommands
+	Options            []*Option      `json:"options"`
+	GlobalOptions      []*Option      `json:"globalOptions,omitempty"`
+	OptionGroups       []*OptionGroup `json:"optionGroups,omitempty"`
+	GlobalOptionGroups []*OptionGroup `json:"globalOptionGroups,omitempty"`
+	Subcommands        []*Command     `json:"subcommands,omitempty"`
+
+	DotEnv           bool      `json:"dotEnv,omitempty"`
+	PrintConfig      bool      `json:"printConfig,omitempty"`
+	MainFuncPosition *Position `json:"mainFuncPosition,omitempty"`
+}
+
+// RunFunc is the run function of a command.
+type RunFunc struct {
+	Name                  string `json:"name"`
+	PackageName           string `json:"packageName"`
+	OptionsArgName        string `json:"optionsArgName,omitempty"`
+	OptionsArgType        string `json:"optionsArgType,omitempty"`
+	OptionsArgIsPointer   bool   `json:"optionsArgIsPointer,omitempty"`
+	OptionsHasValidate    bool   `json:"optionsHasValidate,omitempty"`
+	ContextArgName        string `json:"contextArgName,omitempty"`
+	InitializerFunc       string `json:"initializerFunc,omitempty"`
+	GlobalOptionsArgName  string `json:"globalOptionsArgName,omitempty"`
+	GlobalOptionsArgType  string `json:"globalOptionsArgType,omitempty"`
+	GlobalInitializerFunc string `json:"globalInitializerFunc,omitempty"`
+}
+
+// Option is an option of a command, given by a flag (and an environment variable) or by positional arguments.
+type Option struct {
+	Name           string `json:"name"`    // Field name in the options struct (e.g. "UserName", or "DB.Host" for a nested struct)
+	CliName        string `json:"cliName"` // Flag name (e.g. "user-name")
+	TypeName       string `json:"typeName"`
+	UnderlyingKind string `json:"underlyingKind,omitempty"`
+	HelpText       string `json:"helpText,omitempty"`
+	Section        string `json:"section,omitempty"`
+	IsPointer      bool   `json:"isPointer"`
+	IsRequired     bool   `json:"isRequired"`
+
+	DefaultValue any      `json:"defaultValue,omitempty"` // "[redacted]" for a secret option
+	EnumValues   []any    `json:"enumValues,omitempty"`
+	EnvVar       string   `json:"envVar,omitempty"`
+	NoEnvVar     bool     `json:"noEnvVar,omitempty"`
+	ShortName    string   `json:"shortName,omitempty"`
+	Aliases      []string `json:"aliases,omitempty"`
+
+	MinValue any    `json:"minValue,omitempty"`
+	MaxValue any    `json:"maxValue,omitempty"`
+	MinLen   *int   `json:"minLen,omitempty"`
+	MaxLen   *int   `json:"maxLen,omitempty"`
+	Pattern  string `json:"pattern,omitempty"`
+
+	IsTextUnmarshaler     bool `json:"isTextUnmarshaler,omitempty"`
+	IsTextMarshaler       bool `json:"isTextMarshaler,omitempty"`
+	ElemIsTextUnmarshaler bool `json:"elemIsTextUnmarshaler,omitempty"`
+
+	IsFile             bool   `json:"isFile,omitempty"`
+	FileMustExist      bool   `json:"fileMustExist,omitempty"`
+	FileGlobPattern    bool   `json:"fileGlobPattern,omitempty"`
+	IsDir              bool   `json:"isDir,omitempty"`
+	DirMustExist       bool   `json:"dirMustExist,omitempty"`
+	DirCreateIfMissing bool   `json:"dirCreateIfMissing,omitempty"`
+	DirPerm            uint32 `json:"dirPerm,omitempty"`
+	IsSecret           bool   `json:"isSecret,omitempty"`
+	IsConfigFile       bool   `json:"isConfigFile,omitempty"`
+	ConfigKey          string `json:"configKey,omitempty"` // Key in the config file, empty if not read from the config file
+
+	Positional *PositionalArg `json:"positional,omitempty"`
+	Source     Source         `json:"source"`
+}
+
+// PositionalArg describes how an option receives positional arguments.
+type PositionalArg struct {
+	Index int    `json:"index"`
+	Arity string `json:"arity,omitempty"` // "+", "*" or "N" for the remaining arguments, empty for a single argument
+}
+
+// OptionGroup is a constraint on a set of options.
+type OptionGroup struct {
+	Kind    string   `json:"kind"`    // "oneOf" or "together"
+	Options []string `json:"options"` // Names of the options
+}
+
+// Source is the place in the source code where an option is declared.
+type Source struct {
+	Field       *Position `json:"field,omitempty"`       // The field in the options struct
+	Initializer *Position `json:"initializer,omitempty"` // The value of the field in the options initializer (e.g. goat.Default("info"))
+	EnvTag      *Position `json:"envTag,omitempty"`      // The env key in the struct tag
+}
+
+// Position is a position in a source file, with 1-based line and column (in bytes).
+type Position struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+// FromMetadata converts the metadata of the command to the format.
+// The file names of the positions are made relative to baseDir (e.g. the working directory) if they are under it,
+// and kept as is if baseDir is empty.
+func FromMetadata(cmdMeta *metadata.CommandMetadata, baseDir string) *Command {
+	if cmdMeta == nil {
+		return nil
+	}
+	cmd := fromCommand(cmdMeta, baseDir)
+	cmd.SchemaVersion = SchemaVersion
+	return cmd
+}
+
+func fromCommand(cmdMeta *metadata.CommandMetadata, baseDir string) *Command {
+	cmd := &Command{
+		Name:               cmdMeta.Name,
+		Description:        cmdMeta.Description,
+		Options:            fromOptions(cmdMeta.Options, baseDir),
+		GlobalOptions:      fromOptions(cmdMeta.GlobalOptions, baseDir),
+		OptionGroups:       fromOptionGroups(cmdMeta.OptionGroups),
+		GlobalOptionGroups: fromOptionGroups(cmdMeta.GlobalOptionGroups),
+		DotEnv:             cmdMeta.DotEnv,
+		PrintConfig:        cmdMeta.PrintConfig,
+		MainFuncPosition:   fromPosition(cmdMeta.MainFuncPosition, baseDir),
+	}
+	if cmd.Options == nil {
+		cmd.Options = []*Option{}
+	}
+	if rf := cmdMeta.RunFunc; rf != nil {
+		cmd.RunFunc = &RunFunc{
+			Name:                  rf.Name,
+			PackageName:           rf.PackageName,
+			OptionsArgName:        rf.OptionsArgName,
+			OptionsArgType:        rf.OptionsArgType,
+			OptionsArgIsPointer:   rf.OptionsArgIsPointer,
+			OptionsHasValidate:    rf.OptionsHasValidate,
+			ContextArgName:        rf.ContextArgName,
+			InitializerFunc:       rf.InitializerFunc,
+			GlobalOptionsArgName:  rf.GlobalOptionsArgName,
+			GlobalOptionsArgType:  rf.GlobalOptionsArgType,
+			GlobalInitializerFunc: rf.GlobalInitializerFunc,
+		}
+	}
+	for _, sub := range cmdMeta.Subcommands {
+		cmd.Subcommands = append(cmd.Subcommands, fromCommand(sub, baseDir))
+	}
+	return cmd
+}
+
+func fromOptions(options []*metadata.OptionMetadata, baseDir string) []*Option {
+	var result []*Option
+	for _, om := range options {
+		opt := &Option{
+			Name:                  om.Name,
+			CliName:               om.CliName,
+			TypeName:              om.TypeName,
+			UnderlyingKind:        om.UnderlyingKind,
+			HelpText:              om.HelpText,
+			Section:               om.Section,
+			IsPointer:             om.IsPointer,
+			IsRequired:            om.IsRequired,
+			DefaultValue:          om.DefaultValue,
+			EnumValues:            om.EnumValues,
+			EnvVar:                om.EnvVar,
+			NoEnvVar:              om.NoEnvVar,
+			ShortName:             om.ShortName,
+			Aliases:               om.Aliases,
+			MinValue:              om.MinValue,
+			MaxValue:              om.MaxValue,
+			MinLen:                om.MinLen,
+			MaxLen:                om.MaxLen,
+			Pattern:               om.Pattern,
+			IsTextUnmarshaler:     om.IsTextUnmarshaler,
+			IsTextMarshaler:       om.IsTextMarshaler,
+			ElemIsTextUnmarshaler: om.ElemIsTextUnmarshaler,
+			IsFile:                om.IsFile,
+			FileMustExist:         om.FileMustExist,
+			FileGlobPattern:       om.FileGlobPattern,
+			IsDir:                 om.IsDir,
+			DirMustExist:          om.DirMustExist,
+			DirCreateIfMissing:    om.DirCreateIfMissing,
+			DirPerm:               om.DirPerm,
+			IsSecret:              om.IsSecret,
+			IsConfigFile:          om.IsConfigFile,
+			ConfigKey:             om.ConfigFileKey(),
+			Source: Source{
+				Field:       fromPosition(om.FieldPosition, baseDir),
+				Initializer: fromPosition(om.InitializerPosition, baseDir),
+				EnvTag:      fromPosition(om.EnvTagPosition, baseDir),
+			},
+		}
+		if om.IsSecret && om.DefaultValue != nil && om.DefaultValue != "" {
+			opt.DefaultValue = metadata.RedactedValue
+		}
+		if om.Positional != nil {
+			opt.Positional = &PositionalArg{Index: om.Positional.Index, Arity: om.Positional.Arity}
+		}
+		result = append(result, opt)
+	}
+	return result
+}
+
+func fromOptionGroups(groups []*metadata.OptionGroup) []*OptionGroup {
+	var result []*OptionGroup
+	for _, g := range groups {
+		result = append(result, &OptionGroup{Kind: g.Kind, Options: g.Options})
+	}
+	return result
+}
+
+func fromPosition(pos *token.Position, baseDir string) *Position {
+	if pos == nil || !pos.IsValid() {
+		return nil
+	}
+	file := pos.Filename
+	if baseDir != "" && filepath.IsAbs(file) {
+		if rel, err := filepath.Rel(baseDir, file); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			file = filepath.ToSlash(rel)
+		}
+	}
+	return &Position{File: file, Line: pos.Line, Column: pos.Column}
+}
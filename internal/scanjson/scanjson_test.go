@@ -0,0 +1,135 @@
+package scanjson
+
+import (
+	"encoding/json"
+	"go/token"
+	"testing"
+
+	"github.com/podhmo/goat/internal/metadata"
+)
+
+func TestFromMetadata(t *testing.T) {
+	cmdMeta := &metadata.CommandMetadata{
+		Name:        "example.com/app",
+		Description: "Copy the files.",
+		RunFunc:     &metadata.RunFuncInfo{Name: "run", PackageName: "main", OptionsArgName: "opts", OptionsArgType: "Options", OptionsArgTypeNameStripped: "Options"},
+		Options: []*metadata.OptionMetadata{
+			{
+				Name: "Port", CliName: "port", TypeName: "int", IsRequired: true, DefaultValue: 8080, EnvVar: "PORT",
+				FieldPosition:       &token.Position{Filename: "/src/app/main.go", Offset: 40, Line: 4, Column: 2},
+				InitializerPosition: &token.Position{Filename: "/src/app/main.go", Offset: 120, Line: 10, Column: 9},
+				EnvTagPosition:      &token.Position{Filename: "/src/app/main.go", Offset: 52, Line: 4, Column: 14},
+			},
+			{
+				Name: "Token", CliName: "token", TypeName: "string", IsSecret: true, DefaultValue: "xxx", ConfigKey: "-",
+				FieldPosition: &token.Position{Filename: "/other/options.go", Line: 7, Column: 2},
+			},
+		},
+		OptionGroups:     []*metadata.OptionGroup{{Kind: metadata.OptionGroupOneOf, Options: []string{"Port", "Token"}}},
+		MainFuncPosition: &token.Position{Filename: "/src/app/main.go", Line: 20, Column: 1},
+	}
+
+	b, err := json.MarshalIndent(FromMetadata(cmdMeta, "/src"), "", "  ")
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+
+	expected := `{
+  "schemaVersion": 1,
+  "name": "example.com/app",
+  "description": "Copy the files.",
+  "runFunc": {
+    "name": "run",
+    "packageName": "main",
+    "optionsArgName": "opts",
+    "optionsArgType": "Options"
+  },
+  "options": [
+    {
+      "name": "Port",
+      "cliName": "port",
+      "typeName": "int",
+      "isPointer": false,
+      "isRequired": true,
+      "defaultValue": 8080,
+      "envVar": "PORT",
+      "configKey": "port",
+      "source": {
+        "field": {
+          "file": "app/main.go",
+          "line": 4,
+          "column": 2
+        },
+        "initializer": {
+          "file": "app/main.go",
+          "line": 10,
+          "column": 9
+        },
+        "envTag": {
+          "file": "app/main.go",
+          "line": 4,
+          "column": 14
+        }
+      }
+    },
+    {
+      "name": "Token",
+      "cliName": "token",
+      "typeName": "string",
+      "isPointer": false,
+      "isRequired": false,
+      "defaultValue": "[redacted]",
+      "isSecret": true,
+      "source": {
+        "field": {
+          "file": "/other/options.go",
+          "line": 7,
+          "column": 2
+        }
+      }
+    }
+  ],
+  "optionGroups": [
+    {
+      "kind": "oneOf",
+      "options": [
+        "Port",
+        "Token"
+      ]
+    }
+  ],
+  "mainFuncPosition": {
+    "file": "app/main.go",
+    "line": 20,
+    "column": 1
+  }
+}`
+	if got := string(b); got != expected {
+		t.Errorf("JSON mismatch.\nExpected:\n%s\nGot:\n%s", expected, got)
+	}
+}
+
+func TestFromMetadata_Subcommands(t *testing.T) {
+	cmdMeta := &metadata.CommandMetadata{
+		Name:          "example.com/app",
+		GlobalOptions: []*metadata.OptionMetadata{{Name: "Verbose", CliName: "verbose", TypeName: "bool"}},
+		Subcommands: []*metadata.CommandMetadata{
+			{Name: "serve", RunFunc: &metadata.RunFuncInfo{Name: "runServe"}},
+		},
+	}
+
+	cmd := FromMetadata(cmdMeta, "")
+	if cmd.SchemaVersion != SchemaVersion || cmd.RunFunc != nil || len(cmd.GlobalOptions) != 1 {
+		t.Errorf("Unexpected top-level command: %+v", cmd)
+	}
+	if len(cmd.Subcommands) != 1 {
+		t.Fatalf("Expected 1 subcommand, got %d", len(cmd.Subcommands))
+	}
+	sub := cmd.Subcommands[0]
+	if sub.SchemaVersion != 0 || sub.Name != "serve" || sub.RunFunc.Name != "runServe" {
+		t.Errorf("Unexpected subcommand: %+v", sub)
+	}
+	if sub.Options == nil {
+		t.Errorf("Expected an empty options list, not null")
+	}
+}
@@ -0,0 +1,152 @@
+package schemagen
+
+import (
+	"sort"
+	"strings"
+
+	helpgen "github.com/podhmo/goat/internal/helpgen"
+	"github.com/podhmo/goat/internal/metadata"
+)
+
+// Draft is the URI of the JSON Schema dialect of the generated schemas.
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is a JSON Schema (draft 2020-12), with the keywords used for the options of a command.
+type Schema struct {
+	Schema      string `json:"$schema,omitempty"`
+	Ref         string `json:"$ref,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+
+	Type                 string             `json:"type,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties any                `json:"additionalProperties,omitempty"` // false, or the *Schema of the values of a map
+	Items                *Schema            `json:"items,omitempty"`
+
+	Enum      []any  `json:"enum,omitempty"`
+	Default   any    `json:"default,omitempty"`
+	Minimum   any    `json:"minimum,omitempty"`
+	Maximum   any    `json:"maximum,omitempty"`
+	MinLength *int   `json:"minLength,omitempty"`
+	MaxLength *int   `json:"maxLength,omitempty"`
+	MinItems  *int   `json:"minItems,omitempty"`
+	MaxItems  *int   `json:"maxItems,omitempty"`
+	Pattern   string `json:"pattern,omitempty"`
+	WriteOnly bool   `json:"writeOnly,omitempty"` // Set for secret options, whose values are not shown
+
+	AnyOf []*Schema          `json:"anyOf,omitempty"`
+	Defs  map[string]*Schema `json:"$defs,omitempty"`
+}
+
+// GenerateJSONSchema returns the JSON Schema of the options of the command, describing the JSON config file
+// (see the `goat:"config"` tag): a property for each option, keyed by its config key, with the type,
+// allowed values, default value, constraints and help text of the option.
+// Positional arguments and the options not read from the config file are left out.
+// For a command with subcommands, the schema of each subcommand (with the global options) is in $defs,
+// and the config file must match one of them.
+func GenerateJSONSchema(cmdMeta *metadata.CommandMetadata) *Schema {
+	if cmdMeta == nil {
+		return nil
+	}
+
+	var schema *Schema
+	if len(cmdMeta.Subcommands) == 0 {
+		schema = objectSchema(cmdMeta.GlobalOptions, cmdMeta.Options)
+	} else {
+		schema = &Schema{Defs: map[string]*Schema{}}
+		for _, sub := range cmdMeta.Subcommands {
+			def := objectSchema(cmdMeta.GlobalOptions, sub.Options)
+			def.Description = strings.TrimSpace(sub.Description)
+			schema.Defs[sub.Name] = def
+			schema.AnyOf = append(schema.AnyOf, &Schema{Ref: "#/$defs/" + sub.Name})
+		}
+	}
+	schema.Schema = Draft
+	schema.Title = helpgen.CommandName(cmdMeta)
+	schema.Description = strings.TrimSpace(cmdMeta.Description)
+	return schema
+}
+
+// objectSchema returns the schema of an object with the properties of the options read from the config file.
+// Unknown keys are rejected, like in the generated main().
+func objectSchema(optionLists ...[]*metadata.OptionMetadata) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}, AdditionalProperties: false}
+	for _, options := range optionLists {
+		for _, opt := range options {
+			key := opt.ConfigFileKey()
+			if key == "" {
+				continue
+			}
+			schema.Properties[key] = optionSchema(opt)
+			if helpgen.IsRequiredFlag(opt) {
+				schema.Required = append(schema.Required, key)
+			}
+		}
+	}
+	sort.Strings(schema.Required)
+	return schema
+}
+
+// optionSchema returns the schema of the value of opt.
+func optionSchema(opt *metadata.OptionMetadata) *Schema {
+	var schema, valueSchema *Schema // The allowed values and constraints apply to the elements of a slice, except for the length
+	if elem, ok := strings.CutPrefix(strings.TrimPrefix(opt.TypeName, "*"), "[]"); ok {
+		valueSchema = typeSchema(elem, "", opt.ElemIsTextUnmarshaler)
+		schema = &Schema{Type: "array", Items: valueSchema, MinItems: opt.MinLen, MaxItems: opt.MaxLen}
+	} else {
+		schema = typeSchema(opt.TypeName, opt.UnderlyingKind, opt.IsTextUnmarshaler)
+		schema.MinLength, schema.MaxLength = opt.MinLen, opt.MaxLen
+		valueSchema = schema
+	}
+	schema.Description = strings.TrimSpace(opt.HelpText)
+
+	if len(opt.EnumValues) > 0 {
+		valueSchema.Enum = opt.EnumValues
+	}
+	if opt.MinValue != nil {
+		valueSchema.Minimum = opt.MinValue
+	}
+	if opt.MaxValue != nil {
+		valueSchema.Maximum = opt.MaxValue
+	}
+	valueSchema.Pattern = opt.Pattern
+
+	if opt.IsSecret {
+		schema.WriteOnly = true // The default value of a secret option is not shown
+	} else if opt.DefaultValue != nil {
+		schema.Default = opt.DefaultValue
+	}
+	return schema
+}
+
+// typeSchema returns the schema of a Go type (e.g. "*int", "map[string]int", "time.Duration"),
+// using underlyingKind for named types. An empty schema (any value) is returned for the types it does not know.
+func typeSchema(typeName string, underlyingKind string, isTextUnmarshaler bool) *Schema {
+	typeName = strings.TrimPrefix(typeName, "*")
+	kind := typeName
+	if typeName != "time.Duration" && underlyingKind != "" {
+		kind = underlyingKind
+	}
+	switch {
+	case isTextUnmarshaler:
+		return &Schema{Type: "string"}
+	case strings.HasPrefix(kind, "map["):
+		if _, elem, ok := strings.Cut(kind, "]"); ok {
+			return &Schema{Type: "object", AdditionalProperties: typeSchema(elem, "", false)}
+		}
+	}
+	switch kind {
+	case "string", "time.Duration": // Durations are written like "1m30s"
+		return &Schema{Type: "string"}
+	case "bool":
+		return &Schema{Type: "boolean"}
+	case "int", "int8", "int16", "int32", "int64", "rune":
+		return &Schema{Type: "integer"}
+	case "uint", "uint8", "uint16", "uint32", "uint64", "uintptr", "byte":
+		return &Schema{Type: "integer", Minimum: 0}
+	case "float32", "float64":
+		return &Schema{Type: "number"}
+	}
+	return &Schema{}
+}
@@ -0,0 +1,151 @@
+package schemagen
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/podhmo/goat/internal/metadata"
+)
+
+func intPtr(v int) *int { return &v }
+
+func TestGenerateJSONSchema(t *testing.T) {
+	cmdMeta := &metadata.CommandMetadata{
+		Name:        "app",
+		Description: "Copy the files.",
+		Options: []*metadata.OptionMetadata{
+			{Name: "Level", CliName: "level", TypeName: "string", HelpText: "Log level.", DefaultValue: "info", EnumValues: []any{"debug", "info"}, IsRequired: true},
+			{Name: "Port", CliName: "port", TypeName: "int", HelpText: "Port.", MinValue: int64(1), MaxValue: int64(65535), IsRequired: true},
+			{Name: "Workers", CliName: "workers", TypeName: "*uint", UnderlyingKind: "uint", IsPointer: true},
+			{Name: "Ratio", CliName: "ratio", TypeName: "Ratio", UnderlyingKind: "float64", IsRequired: true, DefaultValue: 0.5},
+			{Name: "Timeout", CliName: "timeout", TypeName: "time.Duration", UnderlyingKind: "time.Duration", DefaultValue: "5s", IsRequired: true},
+			{Name: "Tags", CliName: "tags", TypeName: "[]string", MinLen: intPtr(1), Pattern: "^[a-z]+$", IsRequired: true},
+			{Name: "Labels", CliName: "labels", TypeName: "map[string]int", UnderlyingKind: "map[string]int"},
+			{Name: "Name", CliName: "name", TypeName: "string", MaxLen: intPtr(8), ConfigKey: "userName", IsRequired: true},
+			{Name: "Token", CliName: "token", TypeName: "string", IsSecret: true, DefaultValue: "xxx"},
+			{Name: "Addr", CliName: "addr", TypeName: "netip.Addr", IsTextUnmarshaler: true},
+			{Name: "Config", CliName: "config", TypeName: "string", IsConfigFile: true, DefaultValue: "app.json"},
+			{Name: "Debug", CliName: "debug", TypeName: "bool", ConfigKey: "-"},
+			{Name: "Files", CliName: "files", TypeName: "[]string", Positional: &metadata.PositionalArg{Index: 0, Arity: "+"}},
+		},
+	}
+
+	b, err := json.MarshalIndent(GenerateJSONSchema(cmdMeta), "", "  ")
+	if err != nil {
+		t.Fatalf("Failed to marshal the schema: %v", err)
+	}
+
+	expected := `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "title": "app",
+  "description": "Copy the files.",
+  "type": "object",
+  "properties": {
+    "addr": {
+      "type": "string"
+    },
+    "labels": {
+      "type": "object",
+      "additionalProperties": {
+        "type": "integer"
+      }
+    },
+    "level": {
+      "description": "Log level.",
+      "type": "string",
+      "enum": [
+        "debug",
+        "info"
+      ],
+      "default": "info"
+    },
+    "port": {
+      "description": "Port.",
+      "type": "integer",
+      "minimum": 1,
+      "maximum": 65535
+    },
+    "ratio": {
+      "type": "number",
+      "default": 0.5
+    },
+    "tags": {
+      "type": "array",
+      "items": {
+        "type": "string",
+        "pattern": "^[a-z]+$"
+      },
+      "minItems": 1
+    },
+    "timeout": {
+      "type": "string",
+      "default": "5s"
+    },
+    "token": {
+      "type": "string",
+      "writeOnly": true
+    },
+    "userName": {
+      "type": "string",
+      "maxLength": 8
+    },
+    "workers": {
+      "type": "integer",
+      "minimum": 0
+    }
+  },
+  "required": [
+    "port",
+    "tags",
+    "userName"
+  ],
+  "additionalProperties": false
+}`
+	if got := string(b); got != expected {
+		t.Errorf("Generated schema mismatch.\nExpected:\n%s\nGot:\n%s", expected, got)
+	}
+}
+
+func TestGenerateJSONSchema_Subcommands(t *testing.T) {
+	cmdMeta := &metadata.CommandMetadata{
+		Name: "app",
+		GlobalOptions: []*metadata.OptionMetadata{
+			{Name: "Verbose", CliName: "verbose", TypeName: "bool", IsRequired: true},
+		},
+		Subcommands: []*metadata.CommandMetadata{
+			{Name: "serve", Description: "Serve the files.", Options: []*metadata.OptionMetadata{
+				{Name: "Port", CliName: "port", TypeName: "int", IsRequired: true},
+			}},
+			{Name: "migrate", Options: []*metadata.OptionMetadata{
+				{Name: "DryRun", CliName: "dry-run", TypeName: "*bool", IsPointer: true},
+			}},
+		},
+	}
+
+	schema := GenerateJSONSchema(cmdMeta)
+	if len(schema.AnyOf) != 2 || schema.AnyOf[0].Ref != "#/$defs/serve" || schema.AnyOf[1].Ref != "#/$defs/migrate" {
+		t.Errorf("Expected anyOf with the refs of the subcommands, got %+v", schema.AnyOf)
+	}
+	if schema.Type != "" || schema.Properties != nil {
+		t.Errorf("Expected no properties at the top level, got type %q and %v", schema.Type, schema.Properties)
+	}
+
+	serve := schema.Defs["serve"]
+	if serve == nil || serve.Description != "Serve the files." {
+		t.Fatalf("Expected the schema of serve with its description, got %+v", serve)
+	}
+	for _, key := range []string{"verbose", "port"} {
+		if _, ok := serve.Properties[key]; !ok {
+			t.Errorf("Expected property %q in the schema of serve", key)
+		}
+	}
+	if strings.Join(serve.Required, ",") != "port" {
+		t.Errorf("Expected port to be required in serve, got %v", serve.Required)
+	}
+
+	migrate := schema.Defs["migrate"]
+	if migrate == nil || len(migrate.Properties) != 2 || migrate.Properties["dry-run"].Type != "boolean" {
+		t.Errorf("Expected verbose and dry-run in the schema of migrate, got %+v", migrate)
+	}
+}
@@ -4,10 +4,12 @@ import (
 	"context"
 	"fmt"
 	"go/ast"
+	"go/constant"
 	"go/token"
 	"log/slog"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // EvalResult holds the result of an evaluation.
@@ -108,7 +110,8 @@ func lastPathPart(path string) string {
 // EvaluateArg evaluates the argument of a function call.
 // It returns an EvalResult containing the evaluated argument or identifier information.
 // It currently supports basic literals (strings, integers, floats, chars),
-// identifiers like true, false, nil, unary expressions for negative numbers, and selector expressions.
+// identifiers like true, false, nil, unary expressions for negative numbers, binary expressions of constants
+// (e.g. `int64(1) << 40`), and selector expressions.
 func EvaluateArg(ctx context.Context, arg ast.Expr) EvalResult {
 	switch v := arg.(type) {
 	case *ast.BasicLit:
@@ -189,12 +192,165 @@ func EvaluateArg(ctx context.Context, arg ast.Expr) EvalResult {
 		}
 		slog.DebugContext(ctx, fmt.Sprintf("EvaluateArg: unsupported selector expression, expected X to be *ast.Ident but got %T for X in X.Sel", v.X))
 		return EvalResult{}
+	case *ast.ParenExpr: // e.g. (1024)
+		return EvaluateArg(ctx, v.X)
+	case *ast.BinaryExpr: // e.g. int64(1) << 40 or 64 * 1024, a constant expression of literals
+		x, y := EvaluateArg(ctx, v.X), EvaluateArg(ctx, v.Y)
+		if value, ok := evaluateConstantBinaryOp(x.Value, v.Op, y.Value); ok {
+			return EvalResult{Value: value}
+		}
+		slog.DebugContext(ctx, fmt.Sprintf("EvaluateArg: unsupported binary expression %s %s %s", ExprToTypeName(v.X), v.Op, ExprToTypeName(v.Y)))
+		return EvalResult{}
+	case *ast.CallExpr: // e.g. int64(1024) or float32(0.5), a conversion to a predeclared numeric type
+		if ident, ok := v.Fun.(*ast.Ident); ok && len(v.Args) == 1 && isNumericTypeName(ident.Name) {
+			evalRes := EvaluateArg(ctx, v.Args[0])
+			if evalRes.IdentifierName == "" {
+				return evalRes
+			}
+		}
+		slog.DebugContext(ctx, fmt.Sprintf("EvaluateArg: unsupported call expression %s", ExprToTypeName(v.Fun)))
+		return EvalResult{}
 	default:
 		slog.DebugContext(ctx, fmt.Sprintf("EvaluateArg: argument is not a basic literal, identifier, unary or selector expression, got %T", arg))
 		return EvalResult{}
 	}
 }
 
+// evaluateConstantBinaryOp applies op to the evaluated operands x and y (int64, float64 or string), like the Go compiler
+// does for constants (e.g. integer division for integers). It reports false for unsupported operands
+// and for results that are not representable (e.g. a division by zero or an overflow of int64).
+func evaluateConstantBinaryOp(x any, op token.Token, y any) (any, bool) {
+	toConstant := func(v any) (constant.Value, bool) {
+		switch v := v.(type) {
+		case int64:
+			return constant.MakeInt64(v), true
+		case float64:
+			return constant.MakeFloat64(v), true
+		case string:
+			return constant.MakeString(v), true
+		}
+		return nil, false
+	}
+	cx, okX := toConstant(x)
+	cy, okY := toConstant(y)
+	if !okX || !okY {
+		return nil, false
+	}
+
+	var result constant.Value
+	switch op {
+	case token.SHL, token.SHR:
+		shift, exact := constant.Uint64Val(cy)
+		if cx.Kind() != constant.Int || cy.Kind() != constant.Int || !exact || shift >= 64 {
+			return nil, false
+		}
+		result = constant.Shift(cx, op, uint(shift))
+	case token.ADD, token.SUB, token.MUL, token.QUO, token.REM, token.AND, token.OR, token.XOR, token.AND_NOT:
+		if (cx.Kind() == constant.String) != (cy.Kind() == constant.String) || (cx.Kind() == constant.String && op != token.ADD) {
+			return nil, false
+		}
+		if (op == token.QUO || op == token.REM) && constant.Sign(cy) == 0 {
+			return nil, false
+		}
+		isInt := cx.Kind() == constant.Int && cy.Kind() == constant.Int
+		if (op == token.REM || op == token.AND || op == token.OR || op == token.XOR || op == token.AND_NOT) && !isInt {
+			return nil, false
+		}
+		if op == token.QUO && isInt {
+			op = token.QUO_ASSIGN // Integer division
+		}
+		result = constant.BinaryOp(cx, op, cy)
+	default:
+		return nil, false
+	}
+
+	switch result.Kind() {
+	case constant.Int:
+		if i, exact := constant.Int64Val(result); exact {
+			return i, true
+		}
+	case constant.Float:
+		f, _ := constant.Float64Val(result)
+		return f, true
+	case constant.String:
+		return constant.StringVal(result), true
+	}
+	return nil, false
+}
+
+// isNumericTypeName checks if name is a predeclared numeric type (e.g. "int64", "float32").
+func isNumericTypeName(name string) bool {
+	switch name {
+	case "int", "int8", "int16", "int32", "int64",
+		"uint", "uint8", "uint16", "uint32", "uint64", "uintptr",
+		"float32", "float64", "byte", "rune":
+		return true
+	}
+	return false
+}
+
+// EvaluateDurationArg evaluates a constant expression of time.Duration,
+// e.g. `5 * time.Second`, `time.Minute + 30*time.Second` or `time.Duration(100)`.
+// timePkgAlias is the name under which the "time" package is imported in the file (usually "time").
+func EvaluateDurationArg(ctx context.Context, arg ast.Expr, timePkgAlias string) (time.Duration, bool) {
+	switch v := arg.(type) {
+	case *ast.BasicLit:
+		if v.Kind == token.INT {
+			if i, err := strconv.ParseInt(v.Value, 0, 64); err == nil {
+				return time.Duration(i), true
+			}
+		}
+	case *ast.ParenExpr:
+		return EvaluateDurationArg(ctx, v.X, timePkgAlias)
+	case *ast.UnaryExpr:
+		if d, ok := EvaluateDurationArg(ctx, v.X, timePkgAlias); ok && v.Op == token.SUB {
+			return -d, true
+		}
+	case *ast.SelectorExpr:
+		if xIdent, ok := v.X.(*ast.Ident); ok && xIdent.Name == timePkgAlias {
+			switch v.Sel.Name {
+			case "Nanosecond":
+				return time.Nanosecond, true
+			case "Microsecond":
+				return time.Microsecond, true
+			case "Millisecond":
+				return time.Millisecond, true
+			case "Second":
+				return time.Second, true
+			case "Minute":
+				return time.Minute, true
+			case "Hour":
+				return time.Hour, true
+			}
+		}
+	case *ast.CallExpr: // time.Duration(n)
+		if sel, ok := v.Fun.(*ast.SelectorExpr); ok && len(v.Args) == 1 && sel.Sel.Name == "Duration" {
+			if xIdent, ok := sel.X.(*ast.Ident); ok && xIdent.Name == timePkgAlias {
+				return EvaluateDurationArg(ctx, v.Args[0], timePkgAlias)
+			}
+		}
+	case *ast.BinaryExpr:
+		x, okX := EvaluateDurationArg(ctx, v.X, timePkgAlias)
+		y, okY := EvaluateDurationArg(ctx, v.Y, timePkgAlias)
+		if okX && okY {
+			switch v.Op {
+			case token.MUL:
+				return x * y, true
+			case token.ADD:
+				return x + y, true
+			case token.SUB:
+				return x - y, true
+			case token.QUO:
+				if y != 0 {
+					return x / y, true
+				}
+			}
+		}
+	}
+	slog.DebugContext(ctx, fmt.Sprintf("EvaluateDurationArg: unsupported expression %T", arg))
+	return 0, false
+}
+
 // EvaluateSliceArg evaluates a slice argument of a function call.
 // It returns an EvalResult. If the argument is a literal slice of simple values,
 // EvalResult.Value will contain []any. If it's an identifier (qualified or not)
@@ -237,3 +393,29 @@ func EvaluateSliceArg(ctx context.Context, arg ast.Expr) EvalResult {
 		return EvalResult{}
 	}
 }
+
+// EvaluateMapArg evaluates a map argument of a function call.
+// If the argument is a literal map with string keys and simple values, like map[string]int{"a": 1},
+// EvalResult.Value will contain map[string]any. Otherwise, an empty EvalResult is returned.
+func EvaluateMapArg(ctx context.Context, arg ast.Expr) EvalResult {
+	compLit, ok := arg.(*ast.CompositeLit)
+	if !ok {
+		slog.DebugContext(ctx, fmt.Sprintf("EvaluateMapArg: argument is not a composite literal, got %T", arg))
+		return EvalResult{}
+	}
+	results := make(map[string]any, len(compLit.Elts))
+	for _, elt := range compLit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			return EvalResult{}
+		}
+		key, isString := EvaluateArg(ctx, kv.Key).Value.(string)
+		value := EvaluateArg(ctx, kv.Value).Value
+		if !isString || value == nil {
+			slog.DebugContext(ctx, fmt.Sprintf("EvaluateMapArg: failed to evaluate the entry %s of composite literal to a value.", ExprToTypeName(kv.Key)))
+			return EvalResult{}
+		}
+		results[key] = value
+	}
+	return EvalResult{Value: results}
+}
@@ -7,6 +7,7 @@ import (
 	"go/token"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func parseAndFindFirstFuncArgType(t *testing.T, code string, funcName string) ast.Expr {
@@ -190,6 +191,17 @@ func TestEvaluateArg(t *testing.T) {
 		{"True", "true", true},
 		{"False", "false", false},
 		{"Nil", "nil", nil},
+		{"Conversion", "int64(1024)", int64(1024)},
+		{"FloatConversion", "float32(0.5)", 0.5},
+		{"Paren", "(42)", int64(42)},
+		{"Shift", "int64(1) << 40", int64(1) << 40},
+		{"Arithmetic", "64 * 1024 + 1", int64(64*1024 + 1)},
+		{"IntegerDivision", "7 / 2", int64(3)},
+		{"FloatArithmetic", "1.5 * 2", 3.0},
+		{"Concatenation", `"a" + "b"`, "ab"},
+		{"DivisionByZero", "1 / 0", nil},
+		{"Overflow", "1 << 63", nil},
+		{"Unresolved", "1 << n", nil},
 		// TODO: Add char, negative numbers, etc.
 	}
 	for _, tc := range testCases {
@@ -210,6 +222,59 @@ func TestEvaluateArg(t *testing.T) {
 	}
 }
 
+func TestEvaluateDurationArg(t *testing.T) {
+	testCases := []struct {
+		exprStr  string
+		alias    string
+		expected time.Duration
+		ok       bool
+	}{
+		{exprStr: "5 * time.Second", alias: "time", expected: 5 * time.Second, ok: true},
+		{exprStr: "time.Minute + 30*time.Second", alias: "time", expected: 90 * time.Second, ok: true},
+		{exprStr: "-(2 * time.Hour)", alias: "time", expected: -2 * time.Hour, ok: true},
+		{exprStr: "time.Duration(100)", alias: "time", expected: 100, ok: true},
+		{exprStr: "500 * t.Millisecond", alias: "t", expected: 500 * time.Millisecond, ok: true},
+		{exprStr: "5 * other.Second", alias: "time", ok: false},
+		{exprStr: "timeout", alias: "time", ok: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.exprStr, func(t *testing.T) {
+			got, ok := EvaluateDurationArg(context.Background(), parseExpr(t, tc.exprStr), tc.alias)
+			if ok != tc.ok || got != tc.expected {
+				t.Errorf("EvaluateDurationArg(%q) = (%v, %v), want (%v, %v)", tc.exprStr, got, ok, tc.expected, tc.ok)
+			}
+		})
+	}
+}
+
+func TestEvaluateMapArg(t *testing.T) {
+	testCases := []struct {
+		exprStr  string
+		expected any
+	}{
+		{exprStr: `map[string]string{"env": "dev", "app": "x"}`, expected: map[string]any{"env": "dev", "app": "x"}},
+		{exprStr: `Limits{"cpu": 2}`, expected: map[string]any{"cpu": int64(2)}},
+		{exprStr: `map[string]string{}`, expected: map[string]any{}},
+		{exprStr: `map[string]string{"env": other}`, expected: nil},
+		{exprStr: `map[int]string{1: "a"}`, expected: nil},
+		{exprStr: `labels`, expected: nil},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.exprStr, func(t *testing.T) {
+			got := EvaluateMapArg(context.Background(), parseExpr(t, tc.exprStr))
+			if tc.expected == nil {
+				if got.Value != nil {
+					t.Errorf("EvaluateMapArg(%q) = %v, want nil", tc.exprStr, got.Value)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got.Value, tc.expected) {
+				t.Errorf("EvaluateMapArg(%q) = %v, want %v", tc.exprStr, got.Value, tc.expected)
+			}
+		})
+	}
+}
+
 func TestEvaluateSliceArg(t *testing.T) {
 	testCases := []struct {
 		name     string
@@ -20,3 +20,9 @@ func ToKebabCase(str string) string {
 	snake = matchAllCap.ReplaceAllString(snake, "${1}-${2}")
 	return strings.ToLower(snake)
 }
+
+// ToUpperSnakeCase converts a string from CamelCase or PascalCase to UPPER_SNAKE_CASE, e.g. for environment variable names.
+// Example: "UserName" -> "USER_NAME", "DB" -> "DB"
+func ToUpperSnakeCase(str string) string {
+	return strings.ToUpper(strings.ReplaceAll(ToKebabCase(str), "-", "_"))
+}
@@ -28,3 +28,23 @@ func TestToKebabCase(t *testing.T) {
 		})
 	}
 }
+
+func TestToUpperSnakeCase(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"", ""},
+		{"UserName", "USER_NAME"},
+		{"DB", "DB"},
+		{"TestHTTPResponse", "TEST_HTTP_RESPONSE"},
+		{"test_string", "TEST_STRING"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := ToUpperSnakeCase(tt.input); got != tt.want {
+				t.Errorf("ToUpperSnakeCase(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
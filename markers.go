@@ -8,6 +8,8 @@
 // just returning their input, as their primary purpose is static analysis.
 package goat
 
+import "os"
+
 // Enum marks a field as having a set of allowed values.
 // The `goat` tool's interpreter will extract these `values`.
 // It is used for analysis purposes only and returns the passed `values` as is at runtime.
@@ -24,9 +26,138 @@ func Enum[T any](values []T) []T {
 // (which should be a slice of allowed values from `Enum()`) will be used for enum validation.
 // It is used for analysis purposes only and returns the passed `defaultValue` as is at runtime.
 // Type parameter T can be any type suitable for a default value.
+// The other markers (e.g. Short, Alias) are passed to With.
 func Default[T any](defaultValue T, enumConstraint ...[]T) T {
 	// The enumConstraint argument is primarily for the static analyzer.
 	// The analyzer will look for calls to goat.Enum() passed here.
 	// At runtime, this function simply returns the defaultValue.
 	return defaultValue
 }
+
+// With attaches option markers such as Short, Alias and Range to the value of a field.
+// The value is the default value of the field, given as is or by Default
+// (e.g. `goat.With(goat.Default("info", goat.Enum(levels)), goat.Short("l"))`).
+// It is used for analysis purposes only and returns the passed `value` as is at runtime.
+func With[T any](value T, options ...Option) T {
+	return value
+}
+
+// Option is the result of the option markers passed to With (e.g. Short, Alias).
+// It carries no information at runtime.
+type Option struct{}
+
+// Short registers a one-letter short name for the flag of a field (e.g. `-v` for `--verbose`).
+// It is used for analysis purposes only, e.g. `Verbose: goat.With(false, goat.Short("v"))`.
+// The struct tag equivalent is `short:"v"`.
+func Short(name string) Option {
+	return Option{}
+}
+
+// Alias registers additional long names for the flag of a field (e.g. `--out` for `--output-dir`).
+// It is used for analysis purposes only, e.g. `OutputDir: goat.With(".", goat.Alias("out"))`.
+// The struct tag equivalent is `alias:"out"` (comma-separated for several names).
+func Alias(names ...string) Option {
+	return Option{}
+}
+
+// Number is the constraint of the numeric value markers (Min, Max and Range).
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 | ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~float32 | ~float64
+}
+
+// Min sets the minimum value (inclusive) of a numeric field, validated by the generated main().
+// It is used for analysis purposes only, e.g. `Workers: goat.With(4, goat.Min(1))`.
+func Min[T Number](value T) Option {
+	return Option{}
+}
+
+// Max sets the maximum value (inclusive) of a numeric field, validated by the generated main().
+// It is used for analysis purposes only, e.g. `Ratio: goat.With(0.5, goat.Max(1.0))`.
+func Max[T Number](value T) Option {
+	return Option{}
+}
+
+// Range sets both the minimum and maximum values (inclusive) of a numeric field.
+// It is used for analysis purposes only, e.g. `Port: goat.With(8080, goat.Range(1, 65535))`.
+func Range[T Number](min, max T) Option {
+	return Option{}
+}
+
+// MinLen sets the minimum length of a string or slice field, validated by the generated main().
+// It is used for analysis purposes only, e.g. `Name: goat.With("", goat.MinLen(3))`.
+func MinLen(n int) Option {
+	return Option{}
+}
+
+// MaxLen sets the maximum length of a string or slice field, validated by the generated main().
+// It is used for analysis purposes only, e.g. `Tags: goat.With([]string{}, goat.MaxLen(5))`.
+func MaxLen(n int) Option {
+	return Option{}
+}
+
+// Pattern sets a regular expression that the value of a string field must match, validated by the generated main().
+// The pattern is checked to compile when the code is generated.
+// It is used for analysis purposes only, e.g. `Name: goat.With("app", goat.Pattern(`^[a-z0-9-]+$`))`.
+func Pattern(regexp string) Option {
+	return Option{}
+}
+
+// Secret marks a string field as a secret (e.g. a password or a token): its value is never shown in the help message,
+// the output of `goat scan` and the logs of the generated main(). The value can also be read from a file,
+// with the `--<flag>-file` flag or the `<ENV>_FILE` environment variable.
+// It is used for analysis purposes only, e.g. `Password: goat.With("", goat.Secret())`.
+// The struct tag equivalent is `secret:"true"`.
+func Secret() Option {
+	return Option{}
+}
+
+// FileOption is an option of the File and Dir markers (e.g. MustExist, GlobPattern, CreateIfMissing).
+type FileOption struct{}
+
+// File marks a field as a file path, with `defaultPath` as its default value.
+// The field is a string (a single path) or a []string (several paths, e.g. with GlobPattern).
+// "-" is accepted as is, meaning stdin or stdout; the run function is expected to handle it.
+// It is used for analysis purposes only and returns the passed `defaultPath` as is at runtime.
+func File[T string | []string](defaultPath T, options ...FileOption) T {
+	return defaultPath
+}
+
+// MustExist makes the generated main() check that the file (or directory, with Dir) exists before calling the run function.
+// It is used for analysis purposes only, e.g. `Config: goat.File("config.json", goat.MustExist())`.
+func MustExist() FileOption {
+	return FileOption{}
+}
+
+// GlobPattern makes the generated main() expand the glob patterns given to a []string field
+// (patterns without matches are kept as is). For a string field, the value is checked to be a valid pattern
+// (matching at least one file with MustExist) and passed to the run function unexpanded.
+// It is used for analysis purposes only, e.g. `Inputs: goat.File([]string{"*.txt"}, goat.GlobPattern())`.
+func GlobPattern() FileOption {
+	return FileOption{}
+}
+
+// Dir marks a string field as a directory path. It is passed to With, with an optional policy:
+// MustExist (the directory must exist) or CreateIfMissing (the directory is created with its parents).
+// The policy is applied by the generated main() before calling the run function.
+// It is used for analysis purposes only, e.g. `OutputDir: goat.With("output", goat.Dir(goat.CreateIfMissing(0o755)))`.
+func Dir(options ...FileOption) Option {
+	return Option{}
+}
+
+// CreateIfMissing makes the generated main() create the directory of a Dir field (like os.MkdirAll) with the permission `perm`.
+// It is used for analysis purposes only.
+func CreateIfMissing(perm os.FileMode) FileOption {
+	return FileOption{}
+}
+
+// OneOf declares that the options of the given fields are mutually exclusive:
+// the generated main() fails if more than one of them is given (by flag or environment variable).
+// It is called as a statement in the initializer, with pointers to the fields of the options struct,
+// e.g. `goat.OneOf(&o.JSON, &o.YAML)`.
+// It is used for analysis purposes only and does nothing at runtime.
+func OneOf(fields ...any) {}
+
+// Together declares that the options of the given fields must be given together (all or none),
+// e.g. `goat.Together(&o.TLSCert, &o.TLSKey)`.
+// It is used for analysis purposes only and does nothing at runtime.
+func Together(fields ...any) {}